@@ -129,6 +129,8 @@ type Vout struct {
 	Addresses   []string                 `json:"addresses"`
 	IsAddress   bool                     `json:"isAddress"`
 	Type        string                   `json:"type,omitempty"`
+	Dust        bool                     `json:"dust,omitempty"`
+	NonStandard bool                     `json:"nonStandard,omitempty"`
 }
 
 // TokenType specifies type of token
@@ -182,29 +184,133 @@ type Tx struct {
 	Version          int32             `json:"version,omitempty"`
 	Locktime         uint32            `json:"lockTime,omitempty"`
 	Vin              []Vin             `json:"vin"`
+	VinPaging        *Paging           `json:"vinPaging,omitempty"`
 	Vout             []Vout            `json:"vout"`
 	Blockhash        string            `json:"blockHash,omitempty"`
 	Blockheight      int               `json:"blockHeight"`
 	Confirmations    uint32            `json:"confirmations"`
 	Blocktime        int64             `json:"blockTime"`
+	FirstSeen        int64             `json:"firstSeen,omitempty"`
 	Size             int               `json:"size,omitempty"`
+	VSize            int               `json:"vsize,omitempty"`
 	ValueOutSat      *Amount           `json:"value"`
 	ValueInSat       *Amount           `json:"valueIn,omitempty"`
 	FeesSat          *Amount           `json:"fees,omitempty"`
 	Hex              string            `json:"hex,omitempty"`
 	Rbf              bool              `json:"rbf,omitempty"`
+	Final            bool              `json:"final,omitempty"`
+	Comment          string            `json:"comment,omitempty"`
+	UnlockHeight     uint32            `json:"unlockHeight,omitempty"`
+	Timelocked       bool              `json:"timelocked,omitempty"`
+	NonFinal         bool              `json:"nonFinal,omitempty"`
+	Type             string            `json:"type,omitempty"`
+	Coinstake        *Coinstake        `json:"coinstake,omitempty"`
+	SelfSpend        bool              `json:"selfSpend,omitempty"`
 	CoinSpecificData interface{}       `json:"-"`
 	CoinSpecificJSON json.RawMessage   `json:"-"`
 	TokenTransfers   []TokenTransfer   `json:"tokenTransfers,omitempty"`
 	EthereumSpecific *EthereumSpecific `json:"ethereumSpecific,omitempty"`
 }
 
-// FeeStats contains detailed block fee statistics
+// Coinstake contains the breakdown of the proof-of-stake coinstake transaction to the staked value
+// (the value of the spent outputs) and the reward (the value created by the transaction)
+type Coinstake struct {
+	StakeSat  *Amount `json:"stake"`
+	RewardSat *Amount `json:"reward"`
+}
+
+// FeeStats contains detailed block fee statistics, AverageFeeRate is the total fees divided by the total size
+// of the counted transactions in satoshis per vbyte
 type FeeStats struct {
-	TxCount         int       `json:"txCount"`
-	TotalFeesSat    *Amount   `json:"totalFeesSat"`
-	AverageFeePerKb int64     `json:"averageFeePerKb"`
-	DecilesFeePerKb [11]int64 `json:"decilesFeePerKb"`
+	TxCount            int       `json:"txCount"`
+	TotalFeesSat       *Amount   `json:"totalFeesSat"`
+	AverageFeePerKb    int64     `json:"averageFeePerKb"`
+	AverageFeeRate     float64   `json:"averageFeeRate"`
+	DecilesFeePerKb    [11]int64 `json:"decilesFeePerKb"`
+	ExcludedPrivacyTxs int       `json:"excludedPrivacyTxs,omitempty"`
+}
+
+// ShieldedSupply contains cumulative values moved to and from the shielded pool up to the height
+type ShieldedSupply struct {
+	Height    uint32  `json:"height"`
+	MintedSat *Amount `json:"minted"`
+	SpentSat  *Amount `json:"spent"`
+	SupplySat *Amount `json:"supply"`
+}
+
+// Mints contains a page of the privacy mints of a denomination in a range of blocks, ordered from the newest block
+type Mints struct {
+	Paging
+	DenominationSat *Amount `json:"denomination"`
+	FromHeight      uint32  `json:"fromHeight"`
+	ToHeight        uint32  `json:"toHeight"`
+	Mints           []Mint  `json:"mints"`
+}
+
+// Transaction types of the parsed block
+const (
+	ParsedTxTypeCoinbase    = "coinbase"
+	ParsedTxTypeCoinstake   = "coinstake"
+	ParsedTxTypePrivacy     = "privacy"
+	ParsedTxTypeTransparent = "transparent"
+)
+
+// ParsedBlock contains the block parsed from the raw data supplied by the user, for debugging
+type ParsedBlock struct {
+	// Size is the size of the raw block in bytes
+	Size int `json:"size"`
+	// TxTypes contains the types of the transactions, in the order of the transactions in the block
+	TxTypes []string      `json:"txTypes"`
+	Block   *bchain.Block `json:"block"`
+}
+
+// TxFlags contains the privacy operations of a confirmed transaction, Flags is the stored flags byte
+type TxFlags struct {
+	Txid         string `json:"txid"`
+	Flags        byte   `json:"flags"`
+	PrivacyMint  bool   `json:"privacyMint,omitempty"`
+	PrivacySpend bool   `json:"privacySpend,omitempty"`
+	Remint       bool   `json:"remint,omitempty"`
+}
+
+// AddressCluster contains the common-input-ownership cluster of the address, the cluster is identified by its root address,
+// Height is the last block height processed by the computation of the clusters
+type AddressCluster struct {
+	Address string `json:"address"`
+	Cluster string `json:"cluster"`
+	Height  uint32 `json:"height"`
+}
+
+// PrivacySerial contains information about a privacy spend serial
+type PrivacySerial struct {
+	Serial string `json:"serial"`
+	// MempoolTime is the time when the spend with the serial was first seen in the mempool
+	MempoolTime int64 `json:"mempoolTime,omitempty"`
+}
+
+// MempoolPrivacy contains the number and the value of the privacy mints and spends in the mempool
+type MempoolPrivacy struct {
+	Mints     int     `json:"mints"`
+	MintedSat *Amount `json:"minted"`
+	Spends    int     `json:"spends"`
+	SpentSat  *Amount `json:"spent"`
+}
+
+// BlockPrivacy contains the number and the value of the privacy mints and spends in the block
+type BlockPrivacy struct {
+	Hash      string  `json:"hash"`
+	Height    uint32  `json:"height"`
+	Mints     int     `json:"mints"`
+	MintedSat *Amount `json:"minted"`
+	Spends    int     `json:"spends"`
+	SpentSat  *Amount `json:"spent"`
+}
+
+// OrphanStats contains statistics of blocks disconnected from the main chain
+type OrphanStats struct {
+	Count   int              `json:"count"`
+	Stakers map[string]int   `json:"stakers,omitempty"`
+	Orphans []db.OrphanBlock `json:"orphans"`
 }
 
 // Paging contains information about paging for address, blocks and block
@@ -242,6 +348,8 @@ type AddressFilter struct {
 	TokensToReturn TokensToReturn
 	// OnlyConfirmed set to true will ignore mempool transactions; mempool is also ignored if FromHeight/ToHeight filter is specified
 	OnlyConfirmed bool
+	// Mints set to true returns privacy mints funded by the xpub addresses
+	Mints bool
 }
 
 // Address holds information about address and its transactions
@@ -261,11 +369,25 @@ type Address struct {
 	UsedTokens            int                   `json:"usedTokens,omitempty"`
 	Tokens                []Token               `json:"tokens,omitempty"`
 	Erc20Contract         *bchain.Erc20Contract `json:"erc20Contract,omitempty"`
+	Mints                 []Mint                `json:"mints,omitempty"`
+	// Searchable is set to false for the pseudo addresses, whose transactions cannot be looked up,
+	// Type is then the type of the privacy operation of the pseudo address
+	Searchable *bool  `json:"searchable,omitempty"`
+	Type       string `json:"type,omitempty"`
 	// helpers for explorer
 	Filter        string              `json:"-"`
 	XPubAddresses map[string]struct{} `json:"-"`
 }
 
+// Mint is a privacy mint output funded by the inputs of an xpub
+type Mint struct {
+	Txid      string  `json:"txid"`
+	Vout      int32   `json:"vout"`
+	AmountSat *Amount `json:"value"`
+	Height    int     `json:"height"`
+	Type      string  `json:"type,omitempty"`
+}
+
 // Utxo is one unspent transaction output
 type Utxo struct {
 	Txid          string  `json:"txid"`
@@ -277,6 +399,9 @@ type Utxo struct {
 	Path          string  `json:"path,omitempty"`
 	Locktime      uint32  `json:"lockTime,omitempty"`
 	Coinbase      bool    `json:"coinbase,omitempty"`
+	Stake         bool    `json:"stake,omitempty"`
+	Immature      bool    `json:"immature,omitempty"`
+	Final         bool    `json:"final,omitempty"`
 }
 
 // Utxos is array of Utxo
@@ -387,8 +512,31 @@ type BlockInfo struct {
 type Block struct {
 	Paging
 	BlockInfo
-	TxCount      int   `json:"txCount"`
-	Transactions []*Tx `json:"txs,omitempty"`
+	TxCount        int     `json:"txCount"`
+	StakeRewardSat *Amount `json:"stakeReward,omitempty"`
+	FeeRewardSat   *Amount `json:"feeReward,omitempty"`
+	// StakeInputAge is set for the proof-of-stake blocks if the output spent by the coinstake is found
+	StakeInputAge *StakeInputAge `json:"stakeInputAge,omitempty"`
+	// MedianTime is the median time past of the block (BIP113)
+	MedianTime int64 `json:"medianTime,omitempty"`
+	// BlockType distinguishes the proof-of-work and proof-of-stake blocks of Bitcoin-type coins
+	BlockType string `json:"blockType,omitempty"`
+	// DecodedDifficulty is the difficulty decoded by the parser from the bits of the block of the BlockType
+	DecodedDifficulty float64 `json:"decodedDifficulty,omitempty"`
+	Transactions      []*Tx   `json:"txs,omitempty"`
+}
+
+// Block types of Bitcoin-type coins
+const (
+	BlockTypeProofOfWork  = "pow"
+	BlockTypeProofOfStake = "pos"
+)
+
+// StakeInputAge is the age of the output spent by the coinstake transaction of a proof-of-stake block,
+// in blocks and in seconds between the times of the blocks
+type StakeInputAge struct {
+	Blocks  uint32 `json:"blocks"`
+	Seconds int64  `json:"seconds"`
 }
 
 // BlockbookInfo contains information about the running blockbook instance
@@ -6,6 +6,7 @@ import (
 	"blockbook/common"
 	"blockbook/db"
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -14,6 +15,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
@@ -29,6 +31,10 @@ type Worker struct {
 	chainType   bchain.ChainType
 	mempool     bchain.Mempool
 	is          *common.InternalState
+	// median time past of the best block, cached by the best block hash
+	mtpMux  sync.Mutex
+	mtpHash string
+	mtp     int64
 }
 
 // NewWorker creates new api worker
@@ -165,7 +171,15 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 				if err != nil {
 					return nil, errors.Annotatef(err, "GetTxAddresses %v", bchainVin.Txid)
 				}
-				if tas == nil {
+				if tas == nil && bchainVin.ValueSat != nil {
+					// the value of the spent output was provided by the parser, do not load the spent transaction
+					vin.ValueSat = (*Amount)(bchainVin.ValueSat)
+					vin.AddrDesc = w.chainParser.GetAddrDescForUnknownInput(bchainTx, i)
+					vin.Addresses, vin.IsAddress, err = w.chainParser.GetAddressesFromAddrDesc(vin.AddrDesc)
+					if err != nil {
+						glog.Warning("GetAddressesFromAddrDesc tx ", bchainVin.Txid, ", addrDesc ", vin.AddrDesc, ": ", err)
+					}
+				} else if tas == nil {
 					// try to load from backend
 					otx, _, err := w.txCache.GetTransaction(bchainVin.Txid)
 					if err != nil {
@@ -231,6 +245,8 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 		if err != nil {
 			glog.V(2).Infof("getAddressesFromVout error %v, %v, output %v", err, bchainTx.Txid, bchainVout.N)
 		}
+		vout.Dust = w.isDust(&bchainVout.ValueSat, vout.IsAddress)
+		vout.NonStandard = w.chainParser.IsNonStandardOutput(vout.AddrDesc)
 		if ta != nil {
 			vout.Spent = ta.Outputs[i].Spent
 			if spendingTxs && vout.Spent {
@@ -242,10 +258,20 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 		}
 	}
 	if w.chainType == bchain.ChainBitcoinType {
-		// for coinbase transactions valIn is 0
-		feesSat.Sub(&valInSat, &valOutSat)
-		if feesSat.Sign() == -1 {
-			feesSat.SetUint64(0)
+		prevouts := make([]*big.Int, len(vins))
+		for i := range vins {
+			prevouts[i] = (*big.Int)(vins[i].ValueSat)
+		}
+		// the parser accounts also for the value spent from and minted to the shielded pool
+		if fee, err := w.chainParser.ComputeFee(bchainTx, prevouts); err == nil {
+			feesSat.Set(fee)
+		} else {
+			glog.V(2).Infof("ComputeFee error %v, %v", err, bchainTx.Txid)
+			// for coinbase transactions valIn is 0
+			feesSat.Sub(&valInSat, &valOutSat)
+			if feesSat.Sign() == -1 {
+				feesSat.SetUint64(0)
+			}
 		}
 		pValInSat = &valInSat
 	} else if w.chainType == bchain.ChainEthereumType {
@@ -295,8 +321,11 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 			Status:   ethTxData.Status,
 		}
 	}
-	// for now do not return size, we would have to compute vsize of segwit transactions
-	// size:=len(bchainTx.Hex) / 2
+	// the size is returned only together with the vsize of segwit transactions, if provided by the parser
+	var size int
+	if bchainTx.VSize > 0 {
+		size = bchainTx.BaseSize + bchainTx.WitnessSize
+	}
 	var sj json.RawMessage
 	if specificJSON {
 		sj, err = w.chain.GetTransactionSpecific(bchainTx)
@@ -305,13 +334,49 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 		}
 	}
 	// for mempool transaction get first seen time
+	var firstSeen int64
 	if bchainTx.Confirmations == 0 {
 		bchainTx.Blocktime = int64(w.mempool.GetTransactionTime(bchainTx.Txid))
+		firstSeen = bchainTx.Blocktime
+	}
+	// privacy spends timelocked by height cannot be mined before the unlock height
+	unlockHeight := w.chainParser.PrivacySpendUnlockHeight(bchainTx)
+	timelocked := false
+	if unlockHeight > 0 && bchainTx.Confirmations == 0 {
+		bestheight, _, err := w.db.GetBestBlock()
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetBestBlock")
+		}
+		timelocked = isTimelocked(unlockHeight, bestheight)
+	}
+	// transactions timelocked by time cannot be mined before the median time past of the best block reaches the lock time,
+	// if the median time past is not available (for example during a reorg), the lock times are not evaluated
+	nonFinal := false
+	if w.chainType == bchain.ChainBitcoinType && bchainTx.Confirmations == 0 {
+		bestheight, mtp, err := w.getBestMedianTimePast()
+		if err != nil {
+			glog.Warning("txid ", bchainTx.Txid, ": lock time not evaluated, ", err)
+		} else {
+			if isTimeLockTx(w.chainParser, bchainTx) {
+				timelocked = timelocked || isTimelockedByTime(bchainTx.LockTime, mtp)
+			}
+			if nonFinal, err = w.isNonFinalMempoolTx(bchainTx, bestheight, mtp); err != nil {
+				glog.Warning("txid ", bchainTx.Txid, ": finality not evaluated, ", err)
+				nonFinal = false
+			}
+		}
+	}
+	var txType string
+	var coinstake *Coinstake
+	if w.chainType == bchain.ChainBitcoinType && bchain.IsCoinstakeTx(bchainTx) {
+		txType = ParsedTxTypeCoinstake
+		coinstake = newCoinstake(&valInSat, &valOutSat)
 	}
 	r := &Tx{
 		Blockhash:        blockhash,
 		Blockheight:      height,
 		Blocktime:        bchainTx.Blocktime,
+		FirstSeen:        firstSeen,
 		Confirmations:    bchainTx.Confirmations,
 		FeesSat:          (*Amount)(&feesSat),
 		Locktime:         bchainTx.LockTime,
@@ -321,6 +386,15 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 		Version:          bchainTx.Version,
 		Hex:              bchainTx.Hex,
 		Rbf:              rbf,
+		Final:            w.isFinal(int(bchainTx.Confirmations)),
+		Comment:          bchainTx.Comment,
+		UnlockHeight:     unlockHeight,
+		Timelocked:       timelocked,
+		NonFinal:         nonFinal,
+		Type:             txType,
+		Coinstake:        coinstake,
+		Size:             size,
+		VSize:            bchainTx.VSize,
 		Vin:              vins,
 		Vout:             vouts,
 		CoinSpecificData: bchainTx.CoinSpecificData,
@@ -328,6 +402,9 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 		TokenTransfers:   tokens,
 		EthereumSpecific: ethSpecific,
 	}
+	if w.chainType == bchain.ChainBitcoinType {
+		r.SelfSpend = w.isSelfSpend(r, nil)
+	}
 	return r, nil
 }
 
@@ -391,24 +468,69 @@ func (w *Worker) getAddressTxids(addrDesc bchain.AddressDescriptor, mempool bool
 	return txids, nil
 }
 
-func (t *Tx) getAddrVoutValue(addrDesc bchain.AddressDescriptor) *big.Int {
+// countsToBalance returns false for the OP_RETURN data outputs and the privacy mints, which never count
+// toward the spendable balance, regardless of how their addresses are classified
+func (w *Worker) countsToBalance(addrDesc bchain.AddressDescriptor) bool {
+	return w.chainParser.IsAddrDescIndexable(addrDesc) && !w.chainParser.IsPrivacyMintAddrDesc(addrDesc)
+}
+
+// getAddrBalanceChange returns the change of the spendable balance of the address by the transaction,
+// the value of the outputs to the address minus the value of the inputs from the address
+func (w *Worker) getAddrBalanceChange(t *Tx, addrDesc bchain.AddressDescriptor) *big.Int {
 	var val big.Int
 	for _, vout := range t.Vout {
-		if bytes.Equal(vout.AddrDesc, addrDesc) && vout.ValueSat != nil {
+		if bytes.Equal(vout.AddrDesc, addrDesc) && vout.ValueSat != nil && w.countsToBalance(vout.AddrDesc) {
 			val.Add(&val, (*big.Int)(vout.ValueSat))
 		}
 	}
+	for _, vin := range t.Vin {
+		if bytes.Equal(vin.AddrDesc, addrDesc) && vin.ValueSat != nil && w.countsToBalance(vin.AddrDesc) {
+			val.Sub(&val, (*big.Int)(vin.ValueSat))
+		}
+	}
 	return &val
 }
 
-func (t *Tx) getAddrVinValue(addrDesc bchain.AddressDescriptor) *big.Int {
-	var val big.Int
-	for _, vin := range t.Vin {
-		if bytes.Equal(vin.AddrDesc, addrDesc) && vin.ValueSat != nil {
-			val.Add(&val, (*big.Int)(vin.ValueSat))
+// isSelfSpend returns true if the transaction looks like a self-spend (a change only transaction), i.e. all its outputs
+// except OP_RETURN pay to the addresses controlled by the owner of its inputs. Without the cluster, the controlled
+// addresses are the input addresses. The cluster (for example the addresses of an xpub) extends them, if all inputs
+// belong to the cluster. It is only a best-effort heuristic, the addresses controlled by the same owner are not known
+// in general. The coinbase transactions and the transactions with privacy operations are never tagged, the owner
+// of their inputs cannot be determined.
+func (w *Worker) isSelfSpend(t *Tx, cluster map[string]struct{}) bool {
+	if len(t.Vin) == 0 {
+		return false
+	}
+	controlled := cluster
+	if controlled == nil {
+		controlled = make(map[string]struct{}, len(t.Vin))
+	}
+	for i := range t.Vin {
+		vin := &t.Vin[i]
+		if vin.Coinbase != "" || len(vin.AddrDesc) == 0 || w.chainParser.GetPseudoAddressType(vin.AddrDesc) != "" {
+			return false
+		}
+		if cluster == nil {
+			controlled[string(vin.AddrDesc)] = struct{}{}
+		} else if _, found := cluster[string(vin.AddrDesc)]; !found {
+			return false
+		}
+	}
+	outputs := 0
+	for i := range t.Vout {
+		vout := &t.Vout[i]
+		if w.chainParser.IsPrivacyMintAddrDesc(vout.AddrDesc) || w.chainParser.GetPseudoAddressType(vout.AddrDesc) != "" {
+			return false
+		}
+		if !w.chainParser.IsAddrDescIndexable(vout.AddrDesc) {
+			continue
 		}
+		if _, found := controlled[string(vout.AddrDesc)]; !found {
+			return false
+		}
+		outputs++
 	}
-	return &val
+	return outputs > 0
 }
 
 // GetUniqueTxids removes duplicate transactions
@@ -427,6 +549,185 @@ func GetUniqueTxids(txids []string) []string {
 	return ut[0:i]
 }
 
+// isFinal returns true if the transaction with the given number of confirmations is considered final by the coin
+func (w *Worker) isFinal(confirmations int) bool {
+	return confirmations > 0 && confirmations >= w.chainParser.FinalityConfirmations()
+}
+
+// isTimelocked returns true if the transaction with the unlock height cannot be included in the next block
+func isTimelocked(unlockHeight, bestheight uint32) bool {
+	return unlockHeight > bestheight+1
+}
+
+// lockTimeThreshold is the lock time from which the lock time is interpreted as unix time instead of block height
+const lockTimeThreshold = 500000000
+
+// medianTimeBlocks is the number of blocks from which the median time past is computed (BIP113)
+const medianTimeBlocks = 11
+
+// isLockTimeEnforced returns true if the transaction has an input with non-final sequence, which enforces its lock time,
+// the privacy spend inputs do not enforce the lock time given as unix time, their sequence is kept by the parser
+// from the original spend (for example the accumulator id of the Zerocoin spend), the lock times of the privacy spends
+// are handled only as block heights (PrivacySpendUnlockHeight)
+func isLockTimeEnforced(parser bchain.BlockChainParser, tx *bchain.Tx) bool {
+	byTime := tx.LockTime >= lockTimeThreshold
+	for i := range tx.Vin {
+		vin := &tx.Vin[i]
+		if vin.Sequence == math.MaxUint32 || byTime && parser.GetPrivacySpendAddrDesc(vin) != nil {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// isTimeLockTx returns true if the transaction is locked by time, i.e. its lock time is a unix time
+// and the lock time is enforced because it has an input with non-final sequence
+func isTimeLockTx(parser bchain.BlockChainParser, tx *bchain.Tx) bool {
+	return tx.LockTime >= lockTimeThreshold && isLockTimeEnforced(parser, tx)
+}
+
+// isTimelockedByTime returns true if the transaction with the time lock cannot be included in the next block,
+// the lock time must be lower than the median time past of the best block (BIP113)
+func isTimelockedByTime(lockTime uint32, medianTimePast int64) bool {
+	return int64(lockTime) >= medianTimePast
+}
+
+// isFinalTx returns true if the lock time of the transaction allows its inclusion in the block of the given height,
+// whose predecessor has the median time past mtp (IsFinalTx of Bitcoin Core), the lock time is not enforced
+// if all inputs have the final sequence
+func isFinalTx(parser bchain.BlockChainParser, tx *bchain.Tx, height uint32, mtp int64) bool {
+	if tx.LockTime == 0 {
+		return true
+	}
+	if tx.LockTime < lockTimeThreshold {
+		if tx.LockTime < height {
+			return true
+		}
+	} else if int64(tx.LockTime) < mtp {
+		return true
+	}
+	return !isLockTimeEnforced(parser, tx)
+}
+
+// BIP68 relative lock time encoded in the sequence of the input
+const (
+	sequenceLockTimeDisabled    = 1 << 31
+	sequenceLockTimeTypeFlag    = 1 << 22
+	sequenceLockTimeMask        = 0x0000ffff
+	sequenceLockTimeGranularity = 9
+)
+
+// hasSequenceLock returns true if the input of the transaction is locked by the relative lock time (BIP68),
+// which applies to the transactions of version 2 and higher
+func hasSequenceLock(tx *bchain.Tx, vin *bchain.Vin) bool {
+	return tx.Version >= 2 && vin.Txid != "" && vin.Sequence&sequenceLockTimeDisabled == 0
+}
+
+// isSequenceLocked returns true if the relative lock time of the input (BIP68) does not allow the inclusion
+// of the transaction in the block of the given height, whose predecessor has the median time past mtp,
+// the spent output is in the block of coinHeight, whose predecessor has the median time past coinMTP
+func isSequenceLocked(sequence uint32, coinHeight uint32, coinMTP int64, height uint32, mtp int64) bool {
+	v := sequence & sequenceLockTimeMask
+	if sequence&sequenceLockTimeTypeFlag != 0 {
+		return coinMTP+int64(v)<<sequenceLockTimeGranularity-1 >= mtp
+	}
+	return int64(coinHeight)+int64(v)-1 >= int64(height)
+}
+
+// isNonFinalMempoolTx returns true if the mempool transaction cannot be included in the next block because of its
+// lock time or the relative lock time of its inputs (BIP68), mtp is the median time past of the best block,
+// the outputs spent from the mempool are expected to be included in the next block
+func (w *Worker) isNonFinalMempoolTx(tx *bchain.Tx, bestheight uint32, mtp int64) (bool, error) {
+	height := bestheight + 1
+	if !isFinalTx(w.chainParser, tx, height, mtp) {
+		return true, nil
+	}
+	for i := range tx.Vin {
+		vin := &tx.Vin[i]
+		if !hasSequenceLock(tx, vin) || w.chainParser.GetPrivacySpendAddrDesc(vin) != nil {
+			continue
+		}
+		coinHeight := height
+		ta, err := w.db.GetTxAddresses(vin.Txid)
+		if err != nil {
+			return false, errors.Annotatef(err, "GetTxAddresses %v", vin.Txid)
+		}
+		if ta != nil {
+			coinHeight = ta.Height
+		}
+		coinMTP := mtp
+		if coinHeight <= bestheight && coinHeight > 0 {
+			if coinMTP, err = w.getMedianTimePast(coinHeight - 1); err != nil {
+				return false, errors.Annotatef(err, "getMedianTimePast %v", coinHeight-1)
+			}
+		}
+		if isSequenceLocked(vin.Sequence, coinHeight, coinMTP, height, mtp) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// medianTime returns the median of the block times, the upper one for an even number of times
+func medianTime(times []int64) int64 {
+	if len(times) == 0 {
+		return 0
+	}
+	sorted := make([]int64, len(times))
+	copy(sorted, times)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+// getBestMedianTimePast returns the height and the median time past of the best block, the median time past
+// is computed only once for each best block
+func (w *Worker) getBestMedianTimePast() (uint32, int64, error) {
+	bestheight, besthash, err := w.db.GetBestBlock()
+	if err != nil {
+		return 0, 0, errors.Annotatef(err, "GetBestBlock")
+	}
+	w.mtpMux.Lock()
+	defer w.mtpMux.Unlock()
+	if besthash != w.mtpHash {
+		mtp, err := w.getMedianTimePast(bestheight)
+		if err != nil {
+			return 0, 0, errors.Annotatef(err, "getMedianTimePast %v", bestheight)
+		}
+		w.mtpHash, w.mtp = besthash, mtp
+	}
+	return bestheight, w.mtp, nil
+}
+
+// getMedianTimePast returns the median time past of the block at the height (BIP113), the median of the times
+// of the block and its 10 predecessors, at the start of the chain the available blocks are used
+func (w *Worker) getMedianTimePast(height uint32) (int64, error) {
+	times := make([]int64, 0, medianTimeBlocks)
+	for i := uint32(0); i < medianTimeBlocks && i <= height; i++ {
+		bi, err := w.db.GetBlockInfo(height - i)
+		if err != nil {
+			return 0, err
+		}
+		if bi == nil {
+			return 0, errors.Errorf("Block %v not found", height-i)
+		}
+		times = append(times, bi.Time)
+	}
+	return medianTime(times), nil
+}
+
+// isDust returns true if the output to an address has value below the dust threshold of the coin,
+// the outputs without address (OP_RETURN, privacy mints) are never dust
+func (w *Worker) isDust(value *big.Int, isAddress bool) bool {
+	threshold := w.chainParser.DustThresholdSat()
+	return isAddress && threshold > 0 && value.Cmp(big.NewInt(threshold)) < 0
+}
+
+// isCoinbaseTxAddresses returns true if the transaction has only one input without address and value
+func isCoinbaseTxAddresses(ta *db.TxAddresses) bool {
+	return ta != nil && len(ta.Inputs) == 1 && len(ta.Inputs[0].AddrDesc) == 0 && IsZeroBigInt(&ta.Inputs[0].ValueSat)
+}
+
 func (w *Worker) txFromTxAddress(txid string, ta *db.TxAddresses, bi *db.BlockInfo, bestheight uint32) *Tx {
 	var err error
 	var valInSat, valOutSat, feesSat big.Int
@@ -437,6 +738,7 @@ func (w *Worker) txFromTxAddress(txid string, ta *db.TxAddresses, bi *db.BlockIn
 		vin.N = i
 		vin.ValueSat = (*Amount)(&tai.ValueSat)
 		valInSat.Add(&valInSat, &tai.ValueSat)
+		vin.AddrDesc = tai.AddrDesc
 		vin.Addresses, vin.IsAddress, err = tai.Addresses(w.chainParser)
 		if err != nil {
 			glog.Errorf("tai.Addresses error %v, tx %v, input %v, tai %+v", err, txid, i, tai)
@@ -449,10 +751,13 @@ func (w *Worker) txFromTxAddress(txid string, ta *db.TxAddresses, bi *db.BlockIn
 		vout.N = i
 		vout.ValueSat = (*Amount)(&tao.ValueSat)
 		valOutSat.Add(&valOutSat, &tao.ValueSat)
+		vout.AddrDesc = tao.AddrDesc
 		vout.Addresses, vout.IsAddress, err = tao.Addresses(w.chainParser)
 		if err != nil {
 			glog.Errorf("tai.Addresses error %v, tx %v, output %v, tao %+v", err, txid, i, tao)
 		}
+		vout.Dust = w.isDust(&tao.ValueSat, vout.IsAddress)
+		vout.NonStandard = w.chainParser.IsNonStandardOutput(tao.AddrDesc)
 		vout.Spent = tao.Spent
 	}
 	// for coinbase transactions valIn is 0
@@ -460,18 +765,29 @@ func (w *Worker) txFromTxAddress(txid string, ta *db.TxAddresses, bi *db.BlockIn
 	if feesSat.Sign() == -1 {
 		feesSat.SetUint64(0)
 	}
+	confirmations := w.chainParser.Confirmations(ta.Height, bestheight)
+	var txType string
+	var coinstake *Coinstake
+	if isCoinstakeTxAddresses(ta) {
+		txType = ParsedTxTypeCoinstake
+		coinstake = newCoinstake(&valInSat, &valOutSat)
+	}
 	r := &Tx{
 		Blockhash:     bi.Hash,
 		Blockheight:   int(ta.Height),
 		Blocktime:     bi.Time,
-		Confirmations: bestheight - ta.Height + 1,
+		Confirmations: uint32(confirmations),
+		Final:         w.isFinal(confirmations),
 		FeesSat:       (*Amount)(&feesSat),
 		Txid:          txid,
 		ValueInSat:    (*Amount)(&valInSat),
 		ValueOutSat:   (*Amount)(&valOutSat),
 		Vin:           vins,
 		Vout:          vouts,
+		Type:          txType,
+		Coinstake:     coinstake,
 	}
+	r.SelfSpend = w.isSelfSpend(r, nil)
 	return r
 }
 
@@ -496,6 +812,21 @@ func computePaging(count, page, itemsOnPage int) (Paging, int, int, int) {
 	}, from, to, page
 }
 
+// PageTxInputs limits the inputs of the transaction to the given page (numbered from 1) of itemsOnPage inputs,
+// the transaction with at most itemsOnPage inputs is not paged, the values of the transaction are not affected
+func PageTxInputs(tx *Tx, page, itemsOnPage int) {
+	if itemsOnPage <= 0 || len(tx.Vin) <= itemsOnPage {
+		return
+	}
+	page--
+	if page < 0 {
+		page = 0
+	}
+	pg, from, to, _ := computePaging(len(tx.Vin), page, itemsOnPage)
+	tx.Vin = tx.Vin[from:to]
+	tx.VinPaging = &pg
+}
+
 func (w *Worker) getEthereumTypeAddressBalances(addrDesc bchain.AddressDescriptor, details AccountDetails, filter *AddressFilter) (*db.AddrBalance, []Token, *bchain.Erc20Contract, uint64, int, int, error) {
 	var (
 		ba             *db.AddrBalance
@@ -667,6 +998,20 @@ func (w *Worker) getAddrDescAndNormalizeAddress(address string) (bchain.AddressD
 	return addrDesc, address, nil
 }
 
+// pseudoAddress returns the result for the non-searchable pseudo address (for example a privacy mint),
+// which has no balance and transactions, nil if the address descriptor is not a pseudo address
+func pseudoAddress(parser bchain.BlockChainParser, addrDesc bchain.AddressDescriptor, address string) *Address {
+	t := parser.GetPseudoAddressType(addrDesc)
+	if t == "" {
+		return nil
+	}
+	if _, searchable, _ := parser.GetAddressesFromAddrDesc(addrDesc); searchable {
+		return nil
+	}
+	searchable := false
+	return &Address{AddrStr: address, Searchable: &searchable, Type: t}
+}
+
 // GetAddress computes address value and gets transactions for given address
 func (w *Worker) GetAddress(address string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter) (*Address, error) {
 	start := time.Now()
@@ -693,6 +1038,9 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 	if err != nil {
 		return nil, err
 	}
+	if r := pseudoAddress(w.chainParser, addrDesc, address); r != nil {
+		return r, nil
+	}
 	if w.chainType == bchain.ChainEthereumType {
 		var n uint64
 		ba, tokens, erc20c, n, nonTokenTxs, totalResults, err = w.getEthereumTypeAddressBalances(addrDesc, option, filter)
@@ -707,6 +1055,9 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 			return nil, NewAPIError(fmt.Sprintf("Address not found, %v", err), true)
 		}
 		if ba != nil {
+			if !w.countsToBalance(addrDesc) {
+				ba.BalanceSat.SetInt64(0)
+			}
 			// totalResults is known only if there is no filter
 			if filter.Vout == AddressFilterVoutOff && filter.FromHeight == 0 && filter.ToHeight == 0 {
 				totalResults = int(ba.Txs)
@@ -735,8 +1086,7 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 				// skip already confirmed txs, mempool may be out of sync
 				if tx.Confirmations == 0 {
 					unconfirmedTxs++
-					uBalSat.Add(&uBalSat, tx.getAddrVoutValue(addrDesc))
-					uBalSat.Sub(&uBalSat, tx.getAddrVinValue(addrDesc))
+					uBalSat.Add(&uBalSat, w.getAddrBalanceChange(tx, addrDesc))
 					if page == 0 {
 						if option == AccountDetailsTxidHistory {
 							txids = append(txids, tx.Txid)
@@ -1065,6 +1415,10 @@ func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrB
 				return nil, err
 			}
 			bestheight := int(b)
+			maturity := w.chainParser.RequiredConfirmations(0, true, false)
+			if m := w.chainParser.RequiredConfirmations(0, false, true); m > maturity {
+				maturity = m
+			}
 			var checksum big.Int
 			checksum.Set(&ba.BalanceSat)
 			// go backwards to get the newest first
@@ -1076,18 +1430,17 @@ func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrB
 				}
 				_, e := spentInMempool[txid+strconv.Itoa(int(utxo.Vout))]
 				if !e {
-					confirmations := bestheight - int(utxo.Height) + 1
-					coinbase := false
-					// for performance reasons, check coinbase transactions only in minimum confirmantion range
-					if confirmations < w.chainParser.MinimumCoinbaseConfirmations() {
+					coinbase, stake := false, false
+					// for performance reasons, check coinbase and coinstake transactions only in maturity range
+					if bestheight-int(utxo.Height)+1 < maturity {
 						ta, err := w.db.GetTxAddresses(txid)
 						if err != nil {
 							return nil, err
 						}
-						if len(ta.Inputs) == 1 && len(ta.Inputs[0].AddrDesc) == 0 && IsZeroBigInt(&ta.Inputs[0].ValueSat) {
-							coinbase = true
-						}
+						coinbase = isCoinbaseTxAddresses(ta)
+						stake = ta != nil && isCoinstakeTxAddresses(ta)
 					}
+					confirmations := w.chainParser.Confirmations(utxo.Height, b)
 					_, e = inMempool[txid]
 					if !e {
 						utxos = append(utxos, Utxo{
@@ -1097,6 +1450,9 @@ func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrB
 							Height:        int(utxo.Height),
 							Confirmations: confirmations,
 							Coinbase:      coinbase,
+							Stake:         stake,
+							Immature:      confirmations < w.chainParser.RequiredConfirmations(uint32(utxo.Vout), coinbase, stake),
+							Final:         w.isFinal(confirmations),
 						})
 					}
 				}
@@ -1320,8 +1676,16 @@ func (w *Worker) getBlockInfoFromBlockID(bid string) (*bchain.BlockInfo, error)
 	return bi, err
 }
 
-// GetFeeStats returns statistics about block fees
-func (w *Worker) GetFeeStats(bid string) (*FeeStats, error) {
+// feeStatsTx contains the data of a transaction of a block needed for the fee statistics
+type feeStatsTx struct {
+	tx   *bchain.Tx
+	ta   *db.TxAddresses
+	size int
+}
+
+// GetFeeStats returns statistics about block fees, the transactions with privacy operations are included
+// only if includePrivacy is set, their fees are computed by the parser from the privacy spend denominations
+func (w *Worker) GetFeeStats(bid string, includePrivacy bool) (*FeeStats, error) {
 	// txSpecific extends Tx with an additional Size and Vsize info
 	type txSpecific struct {
 		*bchain.Tx
@@ -1338,10 +1702,7 @@ func (w *Worker) GetFeeStats(bid string) (*FeeStats, error) {
 		return nil, NewAPIError(fmt.Sprintf("Block not found, %v", err), true)
 	}
 
-	feesPerKb := make([]int64, 0, len(bi.Txids))
-	totalFeesSat := big.NewInt(0)
-	averageFeePerKb := int64(0)
-
+	txs := make([]feeStatsTx, 0, len(bi.Txids))
 	for _, txid := range bi.Txids {
 		// Get a raw JSON with transaction details, including size, vsize, hex
 		txSpecificJSON, err := w.chain.GetTransactionSpecific(&bchain.Tx{Txid: txid})
@@ -1374,34 +1735,98 @@ func (w *Worker) GetFeeStats(bid string) (*FeeStats, error) {
 		if err != nil {
 			return nil, errors.Annotatef(err, "GetTxAddresses")
 		}
-
-		// Calculate total fees in Satoshis
-		feeSat := big.NewInt(0)
-		for _, input := range txAddresses.Inputs {
-			feeSat = feeSat.Add(&input.ValueSat, feeSat)
+		if txSpec.Tx == nil {
+			txSpec.Tx = &bchain.Tx{Txid: txid}
 		}
+		txs = append(txs, feeStatsTx{tx: txSpec.Tx, ta: txAddresses, size: txSize})
+	}
+
+	fs, err := w.feeStats(txs, includePrivacy)
+	if err != nil {
+		return nil, err
+	}
+	glog.Info("GetFeeStats ", bid, " (", fs.TxCount, " txs) finished in ", time.Since(start))
+	return fs, nil
+}
+
+// feeStats computes the fee statistics of the transactions of a block, the coinbase transactions are skipped,
+// the transactions with privacy operations are skipped unless includePrivacy is set, as the values of their
+// inputs do not reflect the fee, their fee is computed by the parser (ComputeFee)
+func (w *Worker) feeStats(txs []feeStatsTx, includePrivacy bool) (*FeeStats, error) {
+	feesPerKb := make([]int64, 0, len(txs))
+	totalFeesSat := big.NewInt(0)
+	averageFeePerKb := int64(0)
+	totalSize := 0
+	excludedPrivacyTxs := 0
 
-		// Zero inputs means it's a Coinbase TX - skip it
-		if feeSat.Cmp(big.NewInt(0)) == 0 {
+	for i := range txs {
+		ftx := &txs[i]
+		if ftx.ta == nil || ftx.size <= 0 {
 			continue
 		}
+		var feeSat *big.Int
+		if w.chainParser.IsPrivacyTx(ftx.tx) {
+			if !includePrivacy {
+				excludedPrivacyTxs++
+				continue
+			}
+			if isCoinbaseTxAddresses(ftx.ta) {
+				continue
+			}
+			// the parser computes the fee from the values of the outputs and of the spent transparent outputs
+			tx := *ftx.tx
+			if len(tx.Vout) != len(ftx.ta.Outputs) {
+				return nil, errors.Errorf("Outputs of tx %v do not match the index", tx.Txid)
+			}
+			tx.Vout = make([]bchain.Vout, len(ftx.tx.Vout))
+			for j := range tx.Vout {
+				tx.Vout[j] = ftx.tx.Vout[j]
+				tx.Vout[j].ValueSat = ftx.ta.Outputs[j].ValueSat
+			}
+			prevouts := make([]*big.Int, len(tx.Vin))
+			for j := range ftx.ta.Inputs {
+				if j < len(prevouts) && len(ftx.ta.Inputs[j].AddrDesc) > 0 && w.chainParser.GetPseudoAddressType(ftx.ta.Inputs[j].AddrDesc) == "" {
+					prevouts[j] = &ftx.ta.Inputs[j].ValueSat
+				}
+			}
+			var err error
+			if feeSat, err = w.chainParser.ComputeFee(&tx, prevouts); err != nil {
+				glog.Warning("ComputeFee tx ", tx.Txid, ": ", err)
+				continue
+			}
+		} else {
+			// Calculate total fees in Satoshis
+			feeSat = big.NewInt(0)
+			for _, input := range ftx.ta.Inputs {
+				feeSat = feeSat.Add(&input.ValueSat, feeSat)
+			}
+
+			// Zero inputs means it's a Coinbase TX - skip it
+			if feeSat.Cmp(big.NewInt(0)) == 0 {
+				continue
+			}
 
-		for _, output := range txAddresses.Outputs {
-			feeSat = feeSat.Sub(feeSat, &output.ValueSat)
+			for _, output := range ftx.ta.Outputs {
+				feeSat = feeSat.Sub(feeSat, &output.ValueSat)
+			}
 		}
 		totalFeesSat.Add(totalFeesSat, feeSat)
+		totalSize += ftx.size
 
 		// Convert feeSat to fee per kilobyte and add to an array for decile calculation
-		feePerKb := int64(float64(feeSat.Int64()) / float64(txSize) * 1000)
+		feePerKb := int64(float64(feeSat.Int64()) / float64(ftx.size) * 1000)
 		averageFeePerKb += feePerKb
 		feesPerKb = append(feesPerKb, feePerKb)
 	}
 
 	var deciles [11]int64
+	var averageFeeRate float64
 	n := len(feesPerKb)
 
 	if n > 0 {
 		averageFeePerKb /= int64(n)
+		// the average fee rate of the block in satoshis per vbyte, rounded to 3 decimal places
+		averageFeeRate = math.Round(float64(totalFeesSat.Int64())/float64(totalSize)*1000) / 1000
 
 		// Sort fees and calculate the deciles
 		sort.Slice(feesPerKb, func(i, j int) bool { return feesPerKb[i] < feesPerKb[j] })
@@ -1416,16 +1841,265 @@ func (w *Worker) GetFeeStats(bid string) (*FeeStats, error) {
 		}
 	}
 
-	glog.Info("GetFeeStats ", bid, " (", len(feesPerKb), " txs) finished in ", time.Since(start))
-
 	return &FeeStats{
-		TxCount:         len(feesPerKb),
-		AverageFeePerKb: averageFeePerKb,
-		TotalFeesSat:    (*Amount)(totalFeesSat),
-		DecilesFeePerKb: deciles,
+		TxCount:            n,
+		AverageFeePerKb:    averageFeePerKb,
+		AverageFeeRate:     averageFeeRate,
+		TotalFeesSat:       (*Amount)(totalFeesSat),
+		DecilesFeePerKb:    deciles,
+		ExcludedPrivacyTxs: excludedPrivacyTxs,
+	}, nil
+}
+
+// GetShieldedSupply returns net shielded supply (mints minus spends) up to the given height
+func (w *Worker) GetShieldedSupply(height string) (*ShieldedSupply, error) {
+	h, err := strconv.ParseUint(height, 10, 32)
+	if err != nil {
+		return nil, NewAPIError("Invalid height", true)
+	}
+	bestheight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	if uint32(h) > bestheight {
+		return nil, NewAPIError(fmt.Sprintf("Height %v is not yet indexed, best height %v", h, bestheight), true)
+	}
+	s, err := w.db.GetShieldedSupply(uint32(h))
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetShieldedSupply %v", h)
+	}
+	return &ShieldedSupply{
+		Height:    uint32(h),
+		MintedSat: (*Amount)(&s.Minted),
+		SpentSat:  (*Amount)(&s.Spent),
+		SupplySat: (*Amount)(s.Supply()),
+	}, nil
+}
+
+// GetMints returns the page (numbered from 1) of the privacy mints of the denomination in satoshis in the blocks
+// in the range from-to, to equal to 0 means the best block, the mints are paged in db and are not counted,
+// the total number of pages is -1 if there are more mints after the returned page
+func (w *Worker) GetMints(denomination string, from, to uint32, page, itemsOnPage int) (*Mints, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	var d big.Int
+	if _, ok := d.SetString(denomination, 10); !ok || d.Sign() <= 0 {
+		return nil, NewAPIError("Invalid denomination", true)
+	}
+	bestheight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	if to == 0 || to > bestheight {
+		to = bestheight
+	}
+	if from > to {
+		return nil, NewAPIError("Invalid height range", true)
+	}
+	if page < 1 {
+		page = 1
+	}
+	// one more mint than the page size is read to find out if there is a next page
+	mints := make([]Mint, 0)
+	err = w.db.GetMints(&d, from, to, (page-1)*itemsOnPage, func(txid string, vout int32, height uint32) error {
+		mints = append(mints, Mint{Txid: txid, Vout: vout, AmountSat: (*Amount)(&d), Height: int(height)})
+		if len(mints) > itemsOnPage {
+			return &db.StopIteration{}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetMints %v", denomination)
+	}
+	pg := Paging{Page: page, TotalPages: page, ItemsOnPage: itemsOnPage}
+	if len(mints) > itemsOnPage {
+		mints = mints[:itemsOnPage]
+		pg.TotalPages = -1
+	}
+	return &Mints{
+		Paging:          pg,
+		DenominationSat: (*Amount)(&d),
+		FromHeight:      from,
+		ToHeight:        to,
+		Mints:           mints,
+	}, nil
+}
+
+func newTxFlags(txid string, f bchain.TxFlags) *TxFlags {
+	return &TxFlags{
+		Txid:         txid,
+		Flags:        byte(f),
+		PrivacyMint:  f&bchain.TxFlagPrivacyMint != 0,
+		PrivacySpend: f&bchain.TxFlagPrivacySpend != 0,
+		Remint:       f&bchain.TxFlagRemint != 0,
+	}
+}
+
+// GetTxFlags returns the privacy flags of the confirmed transaction stored in db, without loading the transaction
+func (w *Worker) GetTxFlags(txid string) (*TxFlags, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	f, err := w.db.GetTxFlags(txid)
+	if err != nil {
+		return nil, NewAPIError(fmt.Sprintf("Invalid txid, %v", err), true)
+	}
+	// the transparent transactions are not stored, check that the transaction is known
+	if f == 0 {
+		ta, err := w.db.GetTxAddresses(txid)
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetTxAddresses %v", txid)
+		}
+		if ta == nil {
+			return nil, NewAPIError(fmt.Sprintf("Transaction %v not found", txid), true)
+		}
+	}
+	return newTxFlags(txid, f), nil
+}
+
+// GetAddressCluster returns the common-input-ownership cluster of the address computed by the background job,
+// the address which was never spent together with another address is the only address of its cluster
+func (w *Worker) GetAddressCluster(address string) (*AddressCluster, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	height := w.is.GetAddressClustersHeight()
+	if height == 0 {
+		return nil, NewAPIError("Address clusters are not computed", true)
+	}
+	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	root, err := w.db.GetAddressCluster(addrDesc)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetAddressCluster %v", addrDesc)
+	}
+	cluster := root.String()
+	addresses, _, err := w.chainParser.GetAddressesFromAddrDesc(root)
+	if err != nil {
+		glog.V(2).Infof("GetAddressesFromAddrDesc error %v, %v", err, root)
+	}
+	if len(addresses) == 1 {
+		cluster = addresses[0]
+	}
+	return &AddressCluster{
+		Address: address,
+		Cluster: cluster,
+		Height:  height,
 	}, nil
 }
 
+// GetPrivacySerial returns the first seen time in the mempool of the privacy spend with the serial
+func (w *Worker) GetPrivacySerial(serial string) (*PrivacySerial, error) {
+	serial = strings.ToLower(serial)
+	if _, err := hex.DecodeString(serial); err != nil || len(serial) == 0 {
+		return nil, NewAPIError("Invalid serial", true)
+	}
+	t := w.mempool.GetPrivacySerialTime(serial)
+	if t == 0 {
+		return nil, NewAPIError("Serial not seen in mempool", true)
+	}
+	return &PrivacySerial{
+		Serial:      serial,
+		MempoolTime: int64(t),
+	}, nil
+}
+
+// GetMempoolPrivacy returns the number and the value of the privacy mints and spends in the mempool
+func (w *Worker) GetMempoolPrivacy() *MempoolPrivacy {
+	s := w.mempool.GetPrivacySummary()
+	return &MempoolPrivacy{
+		Mints:     s.Mints,
+		MintedSat: (*Amount)(&s.MintedSat),
+		Spends:    s.Spends,
+		SpentSat:  (*Amount)(&s.SpentSat),
+	}
+}
+
+// GetBlockPrivacy returns the number and the value of the privacy mints and spends in the block given by height or hash
+func (w *Worker) GetBlockPrivacy(bid string) (*BlockPrivacy, error) {
+	bi, err := w.getBlockInfoFromBlockID(bid)
+	if err != nil {
+		if err == bchain.ErrBlockNotFound {
+			return nil, NewAPIError("Block not found", true)
+		}
+		return nil, NewAPIError(fmt.Sprintf("Block not found, %v", err), true)
+	}
+	block, err := w.chain.GetBlock(bi.Hash, bi.Height)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlock %v", bi.Hash)
+	}
+	return w.blockPrivacy(block), nil
+}
+
+func (w *Worker) blockPrivacy(block *bchain.Block) *BlockPrivacy {
+	s := bchain.BlockPrivacySummary(w.chainParser, block)
+	return &BlockPrivacy{
+		Hash:      block.Hash,
+		Height:    block.Height,
+		Mints:     s.Mints,
+		MintedSat: (*Amount)(&s.MintedSat),
+		Spends:    s.Spends,
+		SpentSat:  (*Amount)(&s.SpentSat),
+	}
+}
+
+// ParseRawBlock parses the hex encoded raw block by the chain parser and classifies its transactions, for debugging
+func (w *Worker) ParseRawBlock(hexBlock string) (*ParsedBlock, error) {
+	b, err := hex.DecodeString(strings.TrimSpace(hexBlock))
+	if err != nil || len(b) == 0 {
+		return nil, NewAPIError("Invalid block hex", true)
+	}
+	block, err := w.chainParser.ParseBlock(b)
+	if err != nil {
+		return nil, NewAPIError(fmt.Sprintf("ParseBlock error: %v", err), true)
+	}
+	types := make([]string, len(block.Txs))
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		switch {
+		case w.chainParser.IsPrivacyTx(tx):
+			types[i] = ParsedTxTypePrivacy
+		case len(tx.Vin) > 0 && tx.Vin[0].Coinbase != "":
+			types[i] = ParsedTxTypeCoinbase
+		case bchain.IsCoinstakeTx(tx):
+			types[i] = ParsedTxTypeCoinstake
+		default:
+			types[i] = ParsedTxTypeTransparent
+		}
+	}
+	return &ParsedBlock{
+		Size:    len(b),
+		TxTypes: types,
+		Block:   block,
+	}, nil
+}
+
+// GetOrphanStats returns statistics of stored orphan blocks, at most limit of the latest orphans is listed
+func (w *Worker) GetOrphanStats(limit int) (*OrphanStats, error) {
+	obs, err := w.db.GetOrphanBlocks()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetOrphanBlocks")
+	}
+	stakers := make(map[string]int)
+	for i := range obs {
+		if obs[i].Staker != "" {
+			stakers[obs[i].Staker]++
+		}
+	}
+	stats := &OrphanStats{
+		Count:   len(obs),
+		Stakers: stakers,
+		Orphans: obs,
+	}
+	if len(stats.Orphans) > limit {
+		stats.Orphans = stats.Orphans[:limit]
+	}
+	return stats, nil
+}
+
 // GetBlock returns paged data about block
 func (w *Worker) GetBlock(bid string, page int, txsOnPage int) (*Block, error) {
 	start := time.Now()
@@ -1467,6 +2141,35 @@ func (w *Worker) GetBlock(bid string, page int, txsOnPage int) (*Block, error) {
 		bi.Next, _ = w.db.GetBlockHash(bi.Height + 1)
 	}
 	txs = txs[:txi]
+	var stakeReward, feeReward *Amount
+	var stakeAge *StakeInputAge
+	var medianTime int64
+	var blockType string
+	var difficulty float64
+	if w.chainType == bchain.ChainBitcoinType {
+		tas := make([]*db.TxAddresses, len(bi.Txids))
+		for i, txid := range bi.Txids {
+			if tas[i], err = w.db.GetTxAddresses(txid); err != nil {
+				return nil, err
+			}
+		}
+		s, f := blockRewards(tas)
+		stakeReward, feeReward = (*Amount)(&s), (*Amount)(&f)
+		proofOfStake := len(tas) > 1 && tas[1] != nil && isCoinstakeTxAddresses(tas[1])
+		if proofOfStake {
+			stakeAge = w.getStakeInputAge(bi.Txids[1], bi.Height, bi.Time)
+			blockType = BlockTypeProofOfStake
+		} else {
+			blockType = BlockTypeProofOfWork
+		}
+		if bits, err := strconv.ParseUint(bi.Bits, 16, 32); err == nil {
+			difficulty = w.chainParser.Difficulty(uint32(bits), proofOfStake)
+		}
+		if medianTime, err = w.getMedianTimePast(bi.Height); err != nil {
+			glog.Warning("getMedianTimePast ", bi.Height, ": ", err)
+			medianTime = 0
+		}
+	}
 	bi.Txids = nil
 	glog.Info("GetBlock ", bid, ", page ", page, " finished in ", time.Since(start))
 	return &Block{
@@ -1486,11 +2189,91 @@ func (w *Worker) GetBlock(bid string, page int, txsOnPage int) (*Block, error) {
 			Txids:         bi.Txids,
 			Version:       bi.Version,
 		},
-		TxCount:      txCount,
-		Transactions: txs,
+		TxCount:           txCount,
+		StakeRewardSat:    stakeReward,
+		FeeRewardSat:      feeReward,
+		StakeInputAge:     stakeAge,
+		MedianTime:        medianTime,
+		BlockType:         blockType,
+		DecodedDifficulty: difficulty,
+		Transactions:      txs,
 	}, nil
 }
 
+// getStakeInputAge returns the age of the output spent by the first input of the coinstake transaction
+// in the block of the given height and time, nil if the spent output or its block is not found
+func (w *Worker) getStakeInputAge(coinstakeTxid string, height uint32, blockTime int64) *StakeInputAge {
+	tx, _, err := w.txCache.GetTransaction(coinstakeTxid)
+	if err != nil || len(tx.Vin) == 0 || tx.Vin[0].Txid == "" {
+		return nil
+	}
+	_, inputHeight, err := w.txCache.GetTransaction(tx.Vin[0].Txid)
+	if err != nil || inputHeight <= 0 {
+		return nil
+	}
+	ibi, err := w.db.GetBlockInfo(uint32(inputHeight))
+	if err != nil || ibi == nil {
+		return nil
+	}
+	return stakeInputAge(height, blockTime, uint32(inputHeight), ibi.Time)
+}
+
+// stakeInputAge computes the age of the stake input from the heights and times of the blocks,
+// nil if the input block is not older than the staking block
+func stakeInputAge(height uint32, blockTime int64, inputHeight uint32, inputTime int64) *StakeInputAge {
+	if inputHeight == 0 || inputHeight >= height {
+		return nil
+	}
+	return &StakeInputAge{Blocks: height - inputHeight, Seconds: blockTime - inputTime}
+}
+
+// blockRewards splits the reward of the block given by the stored transactions of the block (in the block order)
+// to the subsidy (stakeReward) and the fees (feeReward), the reward is the value created by the coinbase
+// and by the coinstake of proof-of-stake blocks (outputs minus inputs), the fees are the sum of the fees
+// of the other transactions and the subsidy is the rest of the reward (it can be negative if the block does not claim
+// all the fees), the fees of the transactions with privacy
+// spends, whose input values are unknown, are counted as zero, the transactions not found in db are skipped
+func blockRewards(tas []*db.TxAddresses) (stakeReward, feeReward big.Int) {
+	var reward big.Int
+	for i, ta := range tas {
+		if ta == nil {
+			continue
+		}
+		var in, out, v big.Int
+		for j := range ta.Inputs {
+			in.Add(&in, &ta.Inputs[j].ValueSat)
+		}
+		for j := range ta.Outputs {
+			out.Add(&out, &ta.Outputs[j].ValueSat)
+		}
+		if i == 0 || (i == 1 && isCoinstakeTxAddresses(ta)) {
+			reward.Add(&reward, v.Sub(&out, &in))
+		} else if v.Sub(&in, &out); v.Sign() > 0 {
+			feeReward.Add(&feeReward, &v)
+		}
+	}
+	stakeReward.Sub(&reward, &feeReward)
+	return
+}
+
+// newCoinstake returns the breakdown of the coinstake transaction with the given value of inputs and outputs,
+// nil if the value of the inputs is not known
+func newCoinstake(valIn, valOut *big.Int) *Coinstake {
+	if valIn.Sign() == 0 {
+		return nil
+	}
+	var stake, reward big.Int
+	stake.Set(valIn)
+	reward.Sub(valOut, valIn)
+	return &Coinstake{StakeSat: (*Amount)(&stake), RewardSat: (*Amount)(&reward)}
+}
+
+// isCoinstakeTxAddresses returns true if the transaction is a proof-of-stake coinstake,
+// it has inputs, at least two outputs and the first output is empty
+func isCoinstakeTxAddresses(ta *db.TxAddresses) bool {
+	return len(ta.Inputs) > 0 && len(ta.Outputs) >= 2 && len(ta.Outputs[0].AddrDesc) == 0 && IsZeroBigInt(&ta.Outputs[0].ValueSat)
+}
+
 // ComputeFeeStats computes fee distribution in defined blocks and logs them to log
 func (w *Worker) ComputeFeeStats(blockFrom, blockTo int, stopCompute chan os.Signal) error {
 	bestheight, _, err := w.db.GetBestBlock()
@@ -0,0 +1,560 @@
+// +build unittest
+
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/bchain/coins/btc"
+	"blockbook/bchain/coins/xzc"
+	"blockbook/db"
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWorker_isFinal(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        btc.Configuration
+		confirmations int
+		want          bool
+	}{
+		{name: "mempool", confirmations: 0, want: false},
+		{name: "default below threshold", confirmations: 5, want: false},
+		{name: "default at threshold", confirmations: 6, want: true},
+		{name: "configured below threshold", config: btc.Configuration{FinalityConfirmations: 20}, confirmations: 19, want: false},
+		{name: "configured at threshold", config: btc.Configuration{FinalityConfirmations: 20}, confirmations: 20, want: true},
+		{name: "configured above threshold", config: btc.Configuration{FinalityConfirmations: 20}, confirmations: 21, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &Worker{chainParser: btc.NewBitcoinParser(btc.GetChainParams("main"), &tt.config)}
+			if got := w.isFinal(tt.confirmations); got != tt.want {
+				t.Errorf("isFinal(%d) = %v, want %v", tt.confirmations, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorker_isDust(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    btc.Configuration
+		value     int64
+		isAddress bool
+		want      bool
+	}{
+		{name: "default below threshold", value: 545, isAddress: true, want: true},
+		{name: "default at threshold", value: 546, isAddress: true, want: false},
+		{name: "no address", value: 0, isAddress: false, want: false},
+		{name: "configured below threshold", config: btc.Configuration{DustRelayFee: 1000}, value: 181, isAddress: true, want: true},
+		{name: "configured at threshold", config: btc.Configuration{DustRelayFee: 1000}, value: 182, isAddress: true, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &Worker{chainParser: btc.NewBitcoinParser(btc.GetChainParams("main"), &tt.config)}
+			if got := w.isDust(big.NewInt(tt.value), tt.isAddress); got != tt.want {
+				t.Errorf("isDust(%d, %v) = %v, want %v", tt.value, tt.isAddress, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_blockRewards(t *testing.T) {
+	input := func(v int64) db.TxInput { return db.TxInput{AddrDesc: []byte{1}, ValueSat: *big.NewInt(v)} }
+	output := func(v int64) db.TxOutput { return db.TxOutput{AddrDesc: []byte{2}, ValueSat: *big.NewInt(v)} }
+	coinbase := &db.TxAddresses{Inputs: []db.TxInput{{}}, Outputs: []db.TxOutput{output(5000000300)}}
+	coinstake := &db.TxAddresses{
+		Inputs:  []db.TxInput{input(100000000000)},
+		Outputs: []db.TxOutput{{}, output(100400000050)},
+	}
+	tx1 := &db.TxAddresses{Inputs: []db.TxInput{input(1000)}, Outputs: []db.TxOutput{output(900)}}
+	tx2 := &db.TxAddresses{Inputs: []db.TxInput{input(500), input(500)}, Outputs: []db.TxOutput{output(400), output(350)}}
+	// privacy spend without input values
+	spend := &db.TxAddresses{Inputs: []db.TxInput{{}}, Outputs: []db.TxOutput{output(2500000000)}}
+	tests := []struct {
+		name      string
+		tas       []*db.TxAddresses
+		wantStake int64
+		wantFee   int64
+		wantTotal int64
+	}{
+		{name: "pow", tas: []*db.TxAddresses{coinbase, tx1, tx2, spend}, wantStake: 4999999950, wantFee: 350, wantTotal: 5000000300},
+		{name: "pos", tas: []*db.TxAddresses{{Inputs: []db.TxInput{{}}, Outputs: []db.TxOutput{{}}}, coinstake, tx1, tx2}, wantStake: 399999700, wantFee: 350, wantTotal: 400000050},
+		{name: "coinbase only", tas: []*db.TxAddresses{coinbase}, wantStake: 5000000300, wantFee: 0, wantTotal: 5000000300},
+		{name: "missing tx", tas: []*db.TxAddresses{coinbase, nil, tx1}, wantStake: 5000000200, wantFee: 100, wantTotal: 5000000300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stake, fee := blockRewards(tt.tas)
+			if stake.Int64() != tt.wantStake || fee.Int64() != tt.wantFee {
+				t.Errorf("blockRewards() = %v, %v, want %v, %v", stake.String(), fee.String(), tt.wantStake, tt.wantFee)
+			}
+			var total big.Int
+			if total.Add(&stake, &fee); total.Int64() != tt.wantTotal {
+				t.Errorf("blockRewards() sum %v, want total reward %v", total.String(), tt.wantTotal)
+			}
+		})
+	}
+}
+
+func Test_newCoinstake(t *testing.T) {
+	got := newCoinstake(big.NewInt(107300000000), big.NewInt(107550000000))
+	if got == nil {
+		t.Fatal("newCoinstake() = nil")
+	}
+	if (*big.Int)(got.StakeSat).Int64() != 107300000000 || (*big.Int)(got.RewardSat).Int64() != 250000000 {
+		t.Errorf("newCoinstake() = %v, %v, want 107300000000, 250000000", got.StakeSat, got.RewardSat)
+	}
+	// the values of the spent outputs are not known
+	if got := newCoinstake(big.NewInt(0), big.NewInt(107550000000)); got != nil {
+		t.Errorf("newCoinstake() = %+v, want nil", got)
+	}
+}
+
+func Test_isTimelocked(t *testing.T) {
+	tests := []struct {
+		unlockHeight, bestheight uint32
+		want                     bool
+	}{
+		{unlockHeight: 250001, bestheight: 249999, want: true},
+		{unlockHeight: 250001, bestheight: 250000, want: false},
+		{unlockHeight: 250001, bestheight: 260000, want: false},
+	}
+	for _, tt := range tests {
+		if got := isTimelocked(tt.unlockHeight, tt.bestheight); got != tt.want {
+			t.Errorf("isTimelocked(%v, %v) = %v, want %v", tt.unlockHeight, tt.bestheight, got, tt.want)
+		}
+	}
+}
+
+func Test_stakeInputAge(t *testing.T) {
+	tests := []struct {
+		name                 string
+		height, inputHeight  uint32
+		blockTime, inputTime int64
+		want                 *StakeInputAge
+	}{
+		{name: "mature input", height: 300500, blockTime: 1600090000, inputHeight: 300000, inputTime: 1600000000, want: &StakeInputAge{Blocks: 500, Seconds: 90000}},
+		{name: "unknown input height", height: 300500, blockTime: 1600090000},
+		{name: "input in the same block", height: 300500, blockTime: 1600090000, inputHeight: 300500, inputTime: 1600090000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stakeInputAge(tt.height, tt.blockTime, tt.inputHeight, tt.inputTime); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stakeInputAge() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_pseudoAddress(t *testing.T) {
+	params, err := xzc.GetChainParams("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := xzc.NewZcoinParser(params, &btc.Configuration{})
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{address: "Zeromint", want: "zerocoinmint"},
+		{address: "Zerospend", want: "zerocoinspend"},
+		{address: "Sigmamint", want: "sigmamint"},
+		{address: "Sigmaspend", want: "sigmaspend"},
+		{address: "aHfKwzFZMiSxDuNL4jts819nh57t2yJG1h"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			addrDesc, err := parser.GetAddrDescFromAddress(tt.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := pseudoAddress(parser, addrDesc, tt.address)
+			if tt.want == "" {
+				if got != nil {
+					t.Errorf("pseudoAddress() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.Searchable == nil || *got.Searchable || got.Type != tt.want || got.AddrStr != tt.address {
+				t.Errorf("pseudoAddress() = %+v, want not searchable %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageTxInputs(t *testing.T) {
+	tests := []struct {
+		name       string
+		inputs     int
+		page       int
+		wantFirst  int
+		wantInputs int
+		wantPaging *Paging
+	}{
+		{name: "not paged", inputs: 100, page: 1, wantFirst: 0, wantInputs: 100},
+		{name: "first page", inputs: 500, page: 1, wantFirst: 0, wantInputs: 100, wantPaging: &Paging{Page: 1, TotalPages: 5, ItemsOnPage: 100}},
+		{name: "missing page", inputs: 500, page: 0, wantFirst: 0, wantInputs: 100, wantPaging: &Paging{Page: 1, TotalPages: 5, ItemsOnPage: 100}},
+		{name: "last page", inputs: 450, page: 5, wantFirst: 400, wantInputs: 50, wantPaging: &Paging{Page: 5, TotalPages: 5, ItemsOnPage: 100}},
+		{name: "page after last", inputs: 450, page: 9, wantFirst: 400, wantInputs: 50, wantPaging: &Paging{Page: 5, TotalPages: 5, ItemsOnPage: 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &Tx{Vin: make([]Vin, tt.inputs)}
+			for i := range tx.Vin {
+				tx.Vin[i].N = i
+			}
+			PageTxInputs(tx, tt.page, 100)
+			if len(tx.Vin) != tt.wantInputs || tx.Vin[0].N != tt.wantFirst {
+				t.Errorf("PageTxInputs() = %v inputs from %v, want %v inputs from %v", len(tx.Vin), tx.Vin[0].N, tt.wantInputs, tt.wantFirst)
+			}
+			if !reflect.DeepEqual(tx.VinPaging, tt.wantPaging) {
+				t.Errorf("PageTxInputs() paging = %+v, want %+v", tx.VinPaging, tt.wantPaging)
+			}
+		})
+	}
+}
+
+func Test_medianTime(t *testing.T) {
+	tests := []struct {
+		name  string
+		times []int64
+		want  int64
+	}{
+		{name: "11 blocks", times: []int64{1600000600, 1600000000, 1600001200, 1600000300, 1600000900, 1600000100, 1600001000, 1600000500, 1600000200, 1600000800, 1600000400}, want: 1600000500},
+		{name: "11 blocks with time going back", times: []int64{1600001000, 1600000950, 1600000100, 1600000200, 1600000300, 1600000400, 1600000500, 1600000600, 1600000700, 1600000800, 1600000900}, want: 1600000600},
+		{name: "start of the chain", times: []int64{1600000300, 1600000100, 1600000200, 1600000000}, want: 1600000200},
+		{name: "genesis", times: []int64{1600000000}, want: 1600000000},
+		{name: "no blocks", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			times := append([]int64(nil), tt.times...)
+			if got := medianTime(tt.times); got != tt.want {
+				t.Errorf("medianTime() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(times, tt.times) {
+				t.Errorf("medianTime() modified the times %v", tt.times)
+			}
+		})
+	}
+}
+
+// testSigmaSpendVin returns the Sigma spend input converted by the parser to the input without prevout
+func testSigmaSpendVin(sequence uint32) bchain.Vin {
+	spend := "c4010a00ca9a3b00000000" + strings.Repeat("ef", 32)
+	return bchain.Vin{Coinbase: spend, ScriptSig: bchain.ScriptSig{Hex: spend}, Sequence: sequence}
+}
+
+func Test_isTimelockedByTime(t *testing.T) {
+	params, err := xzc.GetChainParams("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := xzc.NewZcoinParser(params, &btc.Configuration{})
+	tests := []struct {
+		name string
+		tx   bchain.Tx
+		mtp  int64
+		want bool
+	}{
+		{name: "locked", tx: bchain.Tx{LockTime: 1600000600, Vin: []bchain.Vin{{Sequence: 0xfffffffe}}}, mtp: 1600000500, want: true},
+		{name: "lock time equal to median time", tx: bchain.Tx{LockTime: 1600000500, Vin: []bchain.Vin{{Sequence: 0xfffffffe}}}, mtp: 1600000500, want: true},
+		{name: "unlocked", tx: bchain.Tx{LockTime: 1600000499, Vin: []bchain.Vin{{Sequence: 0xfffffffe}}}, mtp: 1600000500, want: false},
+		{name: "final sequences", tx: bchain.Tx{LockTime: 1600000600, Vin: []bchain.Vin{{Sequence: 0xffffffff}}}, mtp: 1600000500, want: false},
+		{name: "lock by height", tx: bchain.Tx{LockTime: 250000, Vin: []bchain.Vin{{Sequence: 0xfffffffe}}}, mtp: 1600000500, want: false},
+		// the sequence of the privacy spend does not enforce the lock time given as time
+		{name: "privacy spend", tx: bchain.Tx{LockTime: 1600000600, Vin: []bchain.Vin{testSigmaSpendVin(0)}}, mtp: 1600000500, want: false},
+		{name: "privacy spend and transparent input", tx: bchain.Tx{LockTime: 1600000600, Vin: []bchain.Vin{testSigmaSpendVin(0), {Txid: "a", Sequence: 0xfffffffe}}}, mtp: 1600000500, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTimeLockTx(parser, &tt.tx) && isTimelockedByTime(tt.tx.LockTime, tt.mtp); got != tt.want {
+				t.Errorf("isTimelockedByTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isFinalTx(t *testing.T) {
+	params, err := xzc.GetChainParams("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := xzc.NewZcoinParser(params, &btc.Configuration{})
+	heightLocked := bchain.Tx{LockTime: 250000, Vin: []bchain.Vin{{Txid: "a", Sequence: 0xfffffffe}}}
+	timeLocked := bchain.Tx{LockTime: 1600000600, Vin: []bchain.Vin{{Txid: "a", Sequence: 0xfffffffe}}}
+	tests := []struct {
+		name   string
+		tx     bchain.Tx
+		height uint32
+		mtp    int64
+		want   bool
+	}{
+		{name: "no lock time", tx: bchain.Tx{Vin: []bchain.Vin{{Txid: "a", Sequence: 0}}}, height: 1, want: true},
+		{name: "future height", tx: heightLocked, height: 249999, want: false},
+		{name: "lock height", tx: heightLocked, height: 250000, want: false},
+		{name: "height after lock height", tx: heightLocked, height: 250001, want: true},
+		{name: "future time", tx: timeLocked, height: 250001, mtp: 1600000500, want: false},
+		{name: "median time equal to lock time", tx: timeLocked, height: 250001, mtp: 1600000600, want: false},
+		{name: "median time after lock time", tx: timeLocked, height: 250001, mtp: 1600000601, want: true},
+		{name: "final sequences", tx: bchain.Tx{LockTime: 250000, Vin: []bchain.Vin{{Txid: "a", Sequence: 0xffffffff}}}, height: 1, want: true},
+		// the privacy spends are locked only by height
+		{name: "privacy spend locked by time", tx: bchain.Tx{LockTime: 1600000600, Vin: []bchain.Vin{testSigmaSpendVin(0)}}, height: 250001, mtp: 1600000500, want: true},
+		{name: "privacy spend locked by height", tx: bchain.Tx{LockTime: 250000, Vin: []bchain.Vin{testSigmaSpendVin(0)}}, height: 250000, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isFinalTx(parser, &tt.tx, tt.height, tt.mtp); got != tt.want {
+				t.Errorf("isFinalTx() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isSequenceLocked(t *testing.T) {
+	tests := []struct {
+		name       string
+		sequence   uint32
+		coinHeight uint32
+		coinMTP    int64
+		height     uint32
+		mtp        int64
+		want       bool
+	}{
+		// 10 blocks relative lock of the output mined at height 1000, spendable from the height 1010
+		{name: "height locked", sequence: 10, coinHeight: 1000, height: 1009, want: true},
+		{name: "height unlocked", sequence: 10, coinHeight: 1000, height: 1010, want: false},
+		// 2*512 seconds relative lock
+		{name: "time locked", sequence: sequenceLockTimeTypeFlag | 2, coinMTP: 1600000000, height: 1010, mtp: 1600001023, want: true},
+		{name: "time unlocked", sequence: sequenceLockTimeTypeFlag | 2, coinMTP: 1600000000, height: 1010, mtp: 1600001024, want: false},
+		{name: "zero lock", sequence: 0, coinHeight: 1010, height: 1010, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSequenceLocked(tt.sequence, tt.coinHeight, tt.coinMTP, tt.height, tt.mtp); got != tt.want {
+				t.Errorf("isSequenceLocked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	tx := bchain.Tx{Version: 2, Vin: []bchain.Vin{{Txid: "a", Sequence: 10}, {Txid: "b", Sequence: sequenceLockTimeDisabled | 10}, {Sequence: 10}}}
+	for i, want := range []bool{true, false, false} {
+		if got := hasSequenceLock(&tx, &tx.Vin[i]); got != want {
+			t.Errorf("hasSequenceLock() of input %v = %v, want %v", i, got, want)
+		}
+	}
+	tx.Version = 1
+	if hasSequenceLock(&tx, &tx.Vin[0]) {
+		t.Error("hasSequenceLock() of version 1 tx = true, want false")
+	}
+}
+
+func TestWorker_getAddrBalanceChange(t *testing.T) {
+	params, err := xzc.GetChainParams("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &Worker{chainParser: xzc.NewZcoinParser(params, &btc.Configuration{})}
+	addrDesc, err := w.chainParser.GetAddrDescFromAddress("aHfKwzFZMiSxDuNL4jts819nh57t2yJG1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opReturn := bchain.AddressDescriptor{0x6a, 0x02, 0x01, 0x02}
+	mint := bchain.AddressDescriptor{xzc.OpSigmaMint, 0x01, 0x02}
+	amount := func(v int64) *Amount { return (*Amount)(big.NewInt(v)) }
+	tx := &Tx{
+		Vin: []Vin{
+			{AddrDesc: addrDesc, ValueSat: amount(1000000)},
+			{AddrDesc: addrDesc, ValueSat: amount(250000)},
+		},
+		Vout: []Vout{
+			{AddrDesc: mint, ValueSat: amount(1000000), IsAddress: true},
+			{AddrDesc: opReturn, ValueSat: amount(0), IsAddress: true},
+			{AddrDesc: addrDesc, ValueSat: amount(240000), IsAddress: true},
+		},
+	}
+	tests := []struct {
+		name     string
+		addrDesc bchain.AddressDescriptor
+		want     int64
+	}{
+		{name: "address", addrDesc: addrDesc, want: -1010000},
+		{name: "OP_RETURN", addrDesc: opReturn, want: 0},
+		{name: "mint", addrDesc: mint, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.getAddrBalanceChange(tx, tt.addrDesc); got.Int64() != tt.want {
+				t.Errorf("getAddrBalanceChange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorker_isSelfSpend(t *testing.T) {
+	params, err := xzc.GetChainParams("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &Worker{chainParser: xzc.NewZcoinParser(params, &btc.Configuration{})}
+	p2pkh := func(b string) bchain.AddressDescriptor {
+		ad, _ := hex.DecodeString("76a914" + strings.Repeat(b, 20) + "88ac")
+		return ad
+	}
+	input, change, foreign := p2pkh("01"), p2pkh("02"), p2pkh("03")
+	opReturn := bchain.AddressDescriptor{0x6a, 0x02, 0x01, 0x02}
+	mint := bchain.AddressDescriptor{xzc.OpSigmaMint, 0x01, 0x02}
+	spend := bchain.AddressDescriptor{xzc.OpSigmaSpend}
+	cluster := map[string]struct{}{string(input): {}, string(change): {}}
+	tx := func(vins []bchain.AddressDescriptor, vouts ...bchain.AddressDescriptor) *Tx {
+		t := &Tx{}
+		for _, ad := range vins {
+			t.Vin = append(t.Vin, Vin{AddrDesc: ad})
+		}
+		for _, ad := range vouts {
+			t.Vout = append(t.Vout, Vout{AddrDesc: ad})
+		}
+		return t
+	}
+	tests := []struct {
+		name    string
+		tx      *Tx
+		cluster map[string]struct{}
+		want    bool
+	}{
+		{name: "consolidation", tx: tx([]bchain.AddressDescriptor{input, input}, input), want: true},
+		{name: "consolidation with OP_RETURN", tx: tx([]bchain.AddressDescriptor{input}, opReturn, input), want: true},
+		{name: "payment", tx: tx([]bchain.AddressDescriptor{input}, foreign, input), want: false},
+		{name: "change address without cluster", tx: tx([]bchain.AddressDescriptor{input}, change), want: false},
+		{name: "change address in cluster", tx: tx([]bchain.AddressDescriptor{input}, change, input), cluster: cluster, want: true},
+		{name: "payment with cluster", tx: tx([]bchain.AddressDescriptor{input}, foreign, change), cluster: cluster, want: false},
+		{name: "received to cluster", tx: tx([]bchain.AddressDescriptor{foreign}, change), cluster: cluster, want: false},
+		{name: "only OP_RETURN", tx: tx([]bchain.AddressDescriptor{input}, opReturn), want: false},
+		{name: "mint", tx: tx([]bchain.AddressDescriptor{input}, mint, input), want: false},
+		{name: "spend", tx: tx([]bchain.AddressDescriptor{spend}, input), cluster: cluster, want: false},
+		{name: "unknown input", tx: tx([]bchain.AddressDescriptor{nil}, input), want: false},
+		{name: "coinbase", tx: &Tx{Vin: []Vin{{Coinbase: "03"}}, Vout: []Vout{{AddrDesc: input}}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.isSelfSpend(tt.tx, tt.cluster); got != tt.want {
+				t.Errorf("isSelfSpend() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorker_feeStats(t *testing.T) {
+	params, err := xzc.GetChainParams("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &Worker{chainParser: xzc.NewZcoinParser(params, &btc.Configuration{})}
+	p2pkh, _ := hex.DecodeString("76a914a7f3e9e1e9a1d0fc43d84ee5dbd1e6e7c2306b0b88ac")
+	mint := "c3" + strings.Repeat("ab", 34)
+	// Zerocoin spend of 10 XZC
+	spend := "c20100" + "0a000000"
+	output := func(v int64) db.TxOutput { return db.TxOutput{AddrDesc: p2pkh, ValueSat: *big.NewInt(v)} }
+	input := func(v int64) db.TxInput { return db.TxInput{AddrDesc: p2pkh, ValueSat: *big.NewInt(v)} }
+	// block with a coinbase, two transparent transactions, a mint and a spend
+	txs := []feeStatsTx{
+		{
+			tx:   &bchain.Tx{Txid: "01", Vin: []bchain.Vin{{Coinbase: "03"}}, Vout: []bchain.Vout{{}}},
+			ta:   &db.TxAddresses{Inputs: []db.TxInput{{}}, Outputs: []db.TxOutput{output(2800000000)}},
+			size: 150,
+		},
+		{
+			tx:   &bchain.Tx{Txid: "02", Vin: []bchain.Vin{{Txid: "aa"}}, Vout: []bchain.Vout{{}}},
+			ta:   &db.TxAddresses{Inputs: []db.TxInput{input(100000)}, Outputs: []db.TxOutput{output(90000)}},
+			size: 250,
+		},
+		{
+			tx:   &bchain.Tx{Txid: "03", Vin: []bchain.Vin{{Txid: "bb"}}, Vout: []bchain.Vout{{}}},
+			ta:   &db.TxAddresses{Inputs: []db.TxInput{input(50000)}, Outputs: []db.TxOutput{output(45000)}},
+			size: 200,
+		},
+		{
+			tx: &bchain.Tx{
+				Txid: "04",
+				Vin:  []bchain.Vin{{Txid: "cc"}},
+				Vout: []bchain.Vout{{ScriptPubKey: bchain.ScriptPubKey{Hex: mint}}, {}},
+			},
+			ta: &db.TxAddresses{
+				Inputs:  []db.TxInput{input(1000000000)},
+				Outputs: []db.TxOutput{{AddrDesc: bchain.AddressDescriptor{xzc.OpSigmaMint}, ValueSat: *big.NewInt(900000000)}, output(99000000)},
+			},
+			size: 300,
+		},
+		{
+			tx:   &bchain.Tx{Txid: "05", Vin: []bchain.Vin{{ScriptSig: bchain.ScriptSig{Hex: spend}}}, Vout: []bchain.Vout{{}}},
+			ta:   &db.TxAddresses{Inputs: []db.TxInput{{AddrDesc: bchain.AddressDescriptor{xzc.OpZeroCoinSpend}}}, Outputs: []db.TxOutput{output(999000000)}},
+			size: 500,
+		},
+	}
+	tests := []struct {
+		name           string
+		includePrivacy bool
+		want           FeeStats
+	}{
+		{
+			name: "transparent only",
+			want: FeeStats{
+				TxCount:            2,
+				TotalFeesSat:       (*Amount)(big.NewInt(15000)),
+				AverageFeePerKb:    32500,
+				AverageFeeRate:     33.333,
+				DecilesFeePerKb:    [11]int64{25000, 25000, 25000, 25000, 25000, 40000, 40000, 40000, 40000, 40000, 40000},
+				ExcludedPrivacyTxs: 2,
+			},
+		},
+		{
+			name:           "including privacy",
+			includePrivacy: true,
+			want: FeeStats{
+				TxCount:         4,
+				TotalFeesSat:    (*Amount)(big.NewInt(2015000)),
+				AverageFeePerKb: 1349583,
+				AverageFeeRate:  1612,
+				DecilesFeePerKb: [11]int64{25000, 25000, 25000, 40000, 40000, 2000000, 2000000, 3333333, 3333333, 3333333, 3333333},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := w.feeStats(txs, tt.includePrivacy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("feeStats() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_newTxFlags(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags bchain.TxFlags
+		want  TxFlags
+	}{
+		{name: "transparent", flags: 0, want: TxFlags{Txid: "tx"}},
+		{name: "mint", flags: bchain.TxFlagPrivacyMint, want: TxFlags{Txid: "tx", Flags: 1, PrivacyMint: true}},
+		{name: "spend", flags: bchain.TxFlagPrivacySpend, want: TxFlags{Txid: "tx", Flags: 2, PrivacySpend: true}},
+		{
+			name:  "remint",
+			flags: bchain.TxFlagPrivacyMint | bchain.TxFlagPrivacySpend | bchain.TxFlagRemint,
+			want:  TxFlags{Txid: "tx", Flags: 7, PrivacyMint: true, PrivacySpend: true, Remint: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newTxFlags("tx", tt.flags); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("newTxFlags() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
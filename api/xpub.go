@@ -279,8 +279,12 @@ func (w *Worker) getXpubData(xpub string, page int, txsOnPage int, option Accoun
 		besthash   string
 	)
 	if gap <= 0 {
-		gap = defaultAddressesGap
-	} else if gap > maxAddressesGap {
+		gap = w.chainParser.XPubGapLimit()
+		if gap <= 0 {
+			gap = defaultAddressesGap
+		}
+	}
+	if gap > maxAddressesGap {
 		// limit the maximum gap to protect against unreasonably big values that could cause high load of the server
 		gap = maxAddressesGap
 	}
@@ -333,7 +337,7 @@ func (w *Worker) getXpubData(xpub string, page int, txsOnPage int, option Accoun
 				return nil, 0, err
 			}
 		}
-		if option >= AccountDetailsTxidHistory {
+		if option >= AccountDetailsTxidHistory || filter.Mints {
 			for _, da := range [][]xpubAddress{data.addresses, data.changeAddresses} {
 				for i := range da {
 					if err = w.xpubCheckAndLoadTxids(&da[i], filter, bestheight, (page+1)*txsOnPage); err != nil {
@@ -429,8 +433,7 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 						if !foundTx {
 							unconfirmedTxs++
 						}
-						uBalSat.Add(&uBalSat, tx.getAddrVoutValue(ad.addrDesc))
-						uBalSat.Sub(&uBalSat, tx.getAddrVinValue(ad.addrDesc))
+						uBalSat.Add(&uBalSat, w.getAddrBalanceChange(tx, ad.addrDesc))
 						// mempool txs are returned only on the first page, uniquely and filtered
 						if page == 0 && !foundTx && (txidFilter == nil || txidFilter(&txid, ad)) {
 							mempoolEntries = append(mempoolEntries, bchain.MempoolTxidEntry{Txid: txid.txid, Time: uint32(tx.Blocktime)})
@@ -503,6 +506,21 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 	} else {
 		txCount = int(data.txCountEstimate)
 	}
+	// the transactions spending from and paying only to the addresses of the xpub are self-spends,
+	// even if they do not pay to their input addresses
+	if len(txs) > 0 {
+		cluster := make(map[string]struct{}, len(data.addresses)+len(data.changeAddresses))
+		for _, da := range [][]xpubAddress{data.addresses, data.changeAddresses} {
+			for i := range da {
+				cluster[string(da[i].addrDesc)] = struct{}{}
+			}
+		}
+		for _, tx := range txs {
+			if !tx.SelfSpend {
+				tx.SelfSpend = w.isSelfSpend(tx, cluster)
+			}
+		}
+	}
 	usedTokens := 0
 	var tokens []Token
 	var xpubAddresses map[string]struct{}
@@ -527,6 +545,12 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 			}
 		}
 	}
+	var mints []Mint
+	if filter.Mints {
+		if mints, err = w.xpubMints(data); err != nil {
+			return nil, err
+		}
+	}
 	var totalReceived big.Int
 	totalReceived.Add(&data.balanceSat, &data.sentSat)
 	addr := Address{
@@ -543,11 +567,61 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 		UsedTokens:            usedTokens,
 		Tokens:                tokens,
 		XPubAddresses:         xpubAddresses,
+		Mints:                 mints,
 	}
 	glog.Info("GetXpubAddress ", xpub[:16], ", ", len(data.addresses)+len(data.changeAddresses), " derived addresses, ", txCount, " confirmed txs, finished in ", time.Since(start))
 	return &addr, nil
 }
 
+// xpubMints returns confirmed privacy mints in the transactions spending from the xpub addresses
+// the mints are not attributable to an address but the funding inputs are, sorted by height descending
+func (w *Worker) xpubMints(data *xpubData) ([]Mint, error) {
+	mints := make([]Mint, 0)
+	processed := make(map[string]struct{})
+	for _, da := range [][]xpubAddress{data.addresses, data.changeAddresses} {
+		for i := range da {
+			for _, txid := range da[i].txids {
+				if txid.inputOutput&txInput == 0 {
+					continue
+				}
+				if _, found := processed[txid.txid]; found {
+					continue
+				}
+				processed[txid.txid] = struct{}{}
+				ta, err := w.db.GetTxAddresses(txid.txid)
+				if err != nil {
+					return nil, err
+				}
+				if ta == nil {
+					glog.Warning("DB inconsistency:  tx ", txid.txid, ": not found in txAddresses")
+					continue
+				}
+				for j := range ta.Outputs {
+					o := &ta.Outputs[j]
+					if !w.chainParser.IsPrivacyMintAddrDesc(o.AddrDesc) {
+						continue
+					}
+					var t string
+					if a, _, err := w.chainParser.GetAddressesFromAddrDesc(o.AddrDesc); err == nil && len(a) == 1 {
+						t = a[0]
+					}
+					mints = append(mints, Mint{
+						Txid:      txid.txid,
+						Vout:      int32(j),
+						AmountSat: (*Amount)(&o.ValueSat),
+						Height:    int(ta.Height),
+						Type:      t,
+					})
+				}
+			}
+		}
+	}
+	sort.SliceStable(mints, func(i, j int) bool {
+		return mints[i].Height > mints[j].Height
+	})
+	return mints, nil
+}
+
 // GetXpubUtxo returns unspent outputs for given xpub
 func (w *Worker) GetXpubUtxo(xpub string, onlyConfirmed bool, gap int) (Utxos, error) {
 	start := time.Now()
@@ -16,8 +16,10 @@ type txEntry struct {
 }
 
 type txidio struct {
-	txid string
-	io   []addrIndex
+	txid    string
+	io      []addrIndex
+	privacy *MempoolPrivacySummary
+	inputs  []Outpoint
 }
 
 // BaseMempool is mempool base handle
@@ -27,6 +29,12 @@ type BaseMempool struct {
 	txEntries    map[string]txEntry
 	addrDescToTx map[string][]Outpoint
 	OnNewTxAddr  OnNewTxAddrFunc
+	// serialTimes contains the first seen times of the privacy spend serials, kept also after the spends leave the mempool
+	serialTimes map[string]uint32
+	// privacyTxs contains the privacy mints and spends of the mempool transactions with privacy operations,
+	// privacy contains their running totals
+	privacyTxs map[string]*MempoolPrivacySummary
+	privacy    MempoolPrivacySummary
 }
 
 // GetTransactions returns slice of mempool transactions for given address
@@ -68,6 +76,7 @@ func (a MempoolTxidEntries) Less(i, j int) bool {
 // removeEntryFromMempool removes entry from mempool structs. The caller is responsible for locking!
 func (m *BaseMempool) removeEntryFromMempool(txid string, entry txEntry) {
 	delete(m.txEntries, txid)
+	m.removePrivacyTx(txid)
 	for _, si := range entry.addrIndexes {
 		outpoints, found := m.addrDescToTx[si.addrDesc]
 		if found {
@@ -103,6 +112,120 @@ func (m *BaseMempool) GetAllEntries() MempoolTxidEntries {
 	return entries
 }
 
+// GetPrivacySerialTime returns the first seen time of a privacy spend serial in the mempool, 0 if it was not seen
+func (m *BaseMempool) GetPrivacySerialTime(serial string) uint32 {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.serialTimes[serial]
+}
+
+// addPrivacySerials records the first seen time of the serials of the privacy spends of the transaction,
+// the earliest time is kept if a serial appears again, for example in a replacement transaction or after a reorg
+func (m *BaseMempool) addPrivacySerials(tx *Tx, time uint32) {
+	parser := m.chain.GetChainParser()
+	for i := range tx.Vin {
+		serial := parser.GetPrivacySpendSerial(&tx.Vin[i])
+		if serial == "" {
+			continue
+		}
+		m.mux.Lock()
+		if t, found := m.serialTimes[serial]; !found || time < t {
+			m.serialTimes[serial] = time
+		}
+		m.mux.Unlock()
+	}
+}
+
+// removeOldPrivacySerials removes the serials first seen before the time
+func (m *BaseMempool) removeOldPrivacySerials(time uint32) {
+	m.mux.Lock()
+	for serial, t := range m.serialTimes {
+		if t < time {
+			delete(m.serialTimes, serial)
+		}
+	}
+	m.mux.Unlock()
+}
+
+// txPrivacySummary returns the privacy mints and spends of the transaction classified by the parser,
+// nil if the transaction has no privacy operations
+func txPrivacySummary(parser BlockChainParser, tx *Tx) *MempoolPrivacySummary {
+	var s MempoolPrivacySummary
+	for i := range tx.Vin {
+		if parser.GetPrivacySpendAddrDesc(&tx.Vin[i]) != nil {
+			s.Spends++
+		}
+	}
+	for i := range tx.Vout {
+		addrDesc, err := parser.GetAddrDescFromVout(&tx.Vout[i])
+		if err == nil && parser.IsPrivacyMintAddrDesc(addrDesc) {
+			s.Mints++
+		}
+	}
+	minted, spent := parser.GetShieldedFlows(tx)
+	if s.Mints == 0 && s.Spends == 0 && minted.Sign() == 0 && spent.Sign() == 0 {
+		return nil
+	}
+	s.MintedSat.Set(minted)
+	s.SpentSat.Set(spent)
+	return &s
+}
+
+// BlockPrivacySummary returns the privacy mints and spends of the transactions of the block,
+// the transactions are accounted in the same way as the mempool transactions
+func BlockPrivacySummary(parser BlockChainParser, block *Block) *MempoolPrivacySummary {
+	var s MempoolPrivacySummary
+	for i := range block.Txs {
+		ts := txPrivacySummary(parser, &block.Txs[i])
+		if ts == nil {
+			continue
+		}
+		s.Mints += ts.Mints
+		s.MintedSat.Add(&s.MintedSat, &ts.MintedSat)
+		s.Spends += ts.Spends
+		s.SpentSat.Add(&s.SpentSat, &ts.SpentSat)
+	}
+	return &s
+}
+
+// addPrivacyTx adds the privacy mints and spends of the transaction to the totals. The caller is responsible for locking!
+func (m *BaseMempool) addPrivacyTx(txid string, s *MempoolPrivacySummary) {
+	if s == nil {
+		return
+	}
+	m.privacyTxs[txid] = s
+	m.privacy.Mints += s.Mints
+	m.privacy.MintedSat.Add(&m.privacy.MintedSat, &s.MintedSat)
+	m.privacy.Spends += s.Spends
+	m.privacy.SpentSat.Add(&m.privacy.SpentSat, &s.SpentSat)
+}
+
+// removePrivacyTx subtracts the privacy mints and spends of the transaction from the totals. The caller is responsible for locking!
+func (m *BaseMempool) removePrivacyTx(txid string) {
+	s, found := m.privacyTxs[txid]
+	if !found {
+		return
+	}
+	delete(m.privacyTxs, txid)
+	m.privacy.Mints -= s.Mints
+	m.privacy.MintedSat.Sub(&m.privacy.MintedSat, &s.MintedSat)
+	m.privacy.Spends -= s.Spends
+	m.privacy.SpentSat.Sub(&m.privacy.SpentSat, &s.SpentSat)
+}
+
+// GetPrivacySummary returns the number and the value of the privacy mints and spends in the mempool
+func (m *BaseMempool) GetPrivacySummary() MempoolPrivacySummary {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	s := MempoolPrivacySummary{
+		Mints:  m.privacy.Mints,
+		Spends: m.privacy.Spends,
+	}
+	s.MintedSat.Set(&m.privacy.MintedSat)
+	s.SpentSat.Set(&m.privacy.SpentSat)
+	return s
+}
+
 // GetTransactionTime returns first seen time of a transaction
 func (m *BaseMempool) GetTransactionTime(txid string) uint32 {
 	m.mux.Lock()
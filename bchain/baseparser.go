@@ -1,6 +1,7 @@
 package bchain
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"math/big"
@@ -15,6 +16,10 @@ import (
 type BaseParser struct {
 	BlockAddressesToKeep int
 	AmountDecimalPoint   int
+	Shortcut             string
+	// MaxMoney is the maximum money supply of the coin in satoshis, the money range of the parsed
+	// transactions is checked only if it is set
+	MaxMoney *big.Int
 }
 
 // ParseBlock parses raw block to our Block struct - currently not implemented
@@ -37,6 +42,13 @@ func (p *BaseParser) GetAddrDescForUnknownInput(tx *Tx, input int) AddressDescri
 	return nil
 }
 
+// DefaultFinalityConfirmations is the number of confirmations after which a transaction is considered final,
+// if the coin does not specify otherwise
+const DefaultFinalityConfirmations = 6
+
+// DefaultMaxMoneySat is the maximum money supply of Bitcoin, 21 million coins in satoshis
+var DefaultMaxMoneySat = big.NewInt(21000000 * 100000000)
+
 const zeros = "0000000000000000000000000000000000000000"
 
 // AmountToBigInt converts amount in json.Number (string) to big.Int
@@ -102,6 +114,79 @@ func (p *BaseParser) AmountDecimals() int {
 	return p.AmountDecimalPoint
 }
 
+// CoinShortcut returns the configured ticker of the coin
+func (p *BaseParser) CoinShortcut() string {
+	return p.Shortcut
+}
+
+// CanonicalTxJSON returns the transaction JSON with sorted object keys, without insignificant whitespace
+// and with numbers in the shortest exact decimal form, all fields and values are preserved
+func (p *BaseParser) CanonicalTxJSON(msg json.RawMessage) ([]byte, error) {
+	d := json.NewDecoder(bytes.NewReader(msg))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	v, err := canonicalJSONValue(v)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(false)
+	// the map keys are sorted by the encoder
+	if err = e.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(b.Bytes(), "\n"), nil
+}
+
+func canonicalJSONValue(v interface{}) (interface{}, error) {
+	var err error
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k := range t {
+			if t[k], err = canonicalJSONValue(t[k]); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		for i := range t {
+			if t[i], err = canonicalJSONValue(t[i]); err != nil {
+				return nil, err
+			}
+		}
+	case json.Number:
+		return canonicalJSONNumber(t)
+	}
+	return v, nil
+}
+
+// maxCanonicalDecimals limits the number of decimal places of the numbers in CanonicalTxJSON
+const maxCanonicalDecimals = 100
+
+// canonicalJSONNumber formats the number without exponent and without trailing zeros of the fraction
+func canonicalJSONNumber(n json.Number) (json.Number, error) {
+	var r big.Rat
+	if _, ok := r.SetString(string(n)); !ok {
+		return "", errors.Errorf("Invalid number %v", n)
+	}
+	// the exact value has as many decimal places as the smallest power of 10 divisible by the denominator
+	var m big.Int
+	pow := big.NewInt(1)
+	ten := big.NewInt(10)
+	decimals := 0
+	for m.Mod(pow, r.Denom()).Sign() != 0 {
+		if decimals == maxCanonicalDecimals {
+			return "", errors.Errorf("Unsupported number %v", n)
+		}
+		pow.Mul(pow, ten)
+		decimals++
+	}
+	return json.Number(r.FloatString(decimals)), nil
+}
+
 // ParseTxFromJson parses JSON message containing transaction and returns Tx struct
 func (p *BaseParser) ParseTxFromJson(msg json.RawMessage) (*Tx, error) {
 	var tx Tx
@@ -120,9 +205,41 @@ func (p *BaseParser) ParseTxFromJson(msg json.RawMessage) (*Tx, error) {
 		vout.JsonValue = ""
 	}
 
+	if p.MaxMoney != nil {
+		if err = CheckMoneyRange(&tx, p.MaxMoney); err != nil {
+			return nil, err
+		}
+	}
+
 	return &tx, nil
 }
 
+// MaxMoneySat returns the configured maximum money supply of the coin in satoshis, DefaultMaxMoneySat if it is not configured,
+// in which case the parsed values are not checked against it
+func (p *BaseParser) MaxMoneySat() *big.Int {
+	if p.MaxMoney != nil {
+		return p.MaxMoney
+	}
+	return DefaultMaxMoneySat
+}
+
+// CheckMoneyRange returns error if the value of an output of the transaction or the sum of the values
+// of its outputs is negative or exceeds maxMoney, as the MoneyRange checks of Bitcoin Core
+func CheckMoneyRange(tx *Tx, maxMoney *big.Int) error {
+	var sum big.Int
+	for i := range tx.Vout {
+		v := &tx.Vout[i].ValueSat
+		if v.Sign() < 0 || v.Cmp(maxMoney) > 0 {
+			return errors.Errorf("Value %v of output %v of tx %v out of range", v, i, tx.Txid)
+		}
+		sum.Add(&sum, v)
+		if sum.Cmp(maxMoney) > 0 {
+			return errors.Errorf("Value of outputs of tx %v out of range", tx.Txid)
+		}
+	}
+	return nil
+}
+
 // PackedTxidLen returns length in bytes of packed txid
 func (p *BaseParser) PackedTxidLen() int {
 	return 32
@@ -166,6 +283,54 @@ func (p *BaseParser) MinimumCoinbaseConfirmations() int {
 	return 0
 }
 
+// RequiredConfirmations returns 1, by default the coins do not define the maturity of the outputs
+func (p *BaseParser) RequiredConfirmations(vout uint32, isCoinbase, isStake bool) int {
+	return 1
+}
+
+// IsBIP30Exception returns false, by default coins do not have duplicate transactions
+func (p *BaseParser) IsBIP30Exception(height uint32, txid string) bool {
+	return false
+}
+
+// FinalityConfirmations returns DefaultFinalityConfirmations
+func (p *BaseParser) FinalityConfirmations() int {
+	return DefaultFinalityConfirmations
+}
+
+// DustThresholdSat returns 0, by default the outputs are not considered dust
+func (p *BaseParser) DustThresholdSat() int64 {
+	return 0
+}
+
+// IsNonStandardOutput returns false, by default all outputs are considered standard
+func (p *BaseParser) IsNonStandardOutput(addrDesc AddressDescriptor) bool {
+	return false
+}
+
+// Difficulty returns 0, by default the coins do not define the difficulty
+func (p *BaseParser) Difficulty(bits uint32, proofOfStake bool) float64 {
+	return 0
+}
+
+// TxWeight returns the weight of the transaction computed from its base and witness sizes,
+// 0 if the sizes are not set
+func (p *BaseParser) TxWeight(tx *Tx) int {
+	if tx.BaseSize == 0 {
+		return 0
+	}
+	return tx.BaseSize*4 + tx.WitnessSize
+}
+
+// Confirmations returns number of confirmations of a transaction in block txHeight given the best block tipHeight
+// the transactions in the genesis block (txHeight 0) are confirmed, 0 is returned if the tip is below txHeight
+func (p *BaseParser) Confirmations(txHeight, tipHeight uint32) int {
+	if txHeight > tipHeight {
+		return 0
+	}
+	return int(tipHeight-txHeight) + 1
+}
+
 // PackTx packs transaction to byte array using protobuf
 func (p *BaseParser) PackTx(tx *Tx, height uint32, blockTime int64) ([]byte, error) {
 	var err error
@@ -273,12 +438,93 @@ func (p *BaseParser) UnpackTx(buf []byte) (*Tx, uint32, error) {
 	return &tx, pt.Height, nil
 }
 
+// IsPrivacyTx returns true if the transaction contains privacy operations
+// by default coins do not have privacy operations
+func (p *BaseParser) IsPrivacyTx(tx *Tx) bool {
+	return false
+}
+
+// GetPrivacySpendAddrDesc returns nil, by default coins do not have privacy spends
+func (p *BaseParser) GetPrivacySpendAddrDesc(vin *Vin) AddressDescriptor {
+	return nil
+}
+
+// GetPrivacySpendSerial returns empty string, by default coins do not have privacy spends
+func (p *BaseParser) GetPrivacySpendSerial(vin *Vin) string {
+	return ""
+}
+
+// GetShieldedFlows returns the value moved to and from the shielded pool, by default zero
+func (p *BaseParser) GetShieldedFlows(tx *Tx) (*big.Int, *big.Int) {
+	return big.NewInt(0), big.NewInt(0)
+}
+
+// PrivacyScore returns 0, by default coins do not have privacy operations
+func (p *BaseParser) PrivacyScore(tx *Tx) int {
+	return 0
+}
+
+// ComputeFee returns the fee of the transaction as the value of the spent outputs minus the value of the outputs,
+// the fee of a coinbase transaction is 0
+func (p *BaseParser) ComputeFee(tx *Tx, prevouts []*big.Int) (*big.Int, error) {
+	if len(prevouts) != len(tx.Vin) {
+		return nil, errors.Errorf("%v values of spent outputs for %v inputs", len(prevouts), len(tx.Vin))
+	}
+	if len(tx.Vin) > 0 && tx.Vin[0].Coinbase != "" {
+		return big.NewInt(0), nil
+	}
+	fee := new(big.Int)
+	for i, v := range prevouts {
+		if v == nil {
+			return nil, errors.Errorf("Missing value of input %v", i)
+		}
+		fee.Add(fee, v)
+	}
+	for i := range tx.Vout {
+		fee.Sub(fee, &tx.Vout[i].ValueSat)
+	}
+	if fee.Sign() < 0 {
+		return nil, errors.Errorf("Negative fee %v", fee)
+	}
+	return fee, nil
+}
+
+// GetPseudoAddressType returns empty string, by default coins do not have pseudo addresses
+func (p *BaseParser) GetPseudoAddressType(addrDesc AddressDescriptor) string {
+	return ""
+}
+
+// TxPrivacyFlags returns 0, by default coins do not have privacy operations
+func (p *BaseParser) TxPrivacyFlags(tx *Tx) TxFlags {
+	return 0
+}
+
+// IsPrivacyMintAddrDesc returns false, by default coins do not have privacy mints
+func (p *BaseParser) IsPrivacyMintAddrDesc(addrDesc AddressDescriptor) bool {
+	return false
+}
+
+// PrivacySpendUnlockHeight returns 0, by default coins do not have privacy operations
+func (p *BaseParser) PrivacySpendUnlockHeight(tx *Tx) uint32 {
+	return 0
+}
+
+// GetPrivacyEvents returns nil, by default coins do not have privacy operations
+func (p *BaseParser) GetPrivacyEvents(block *Block) []PrivacyEvent {
+	return nil
+}
+
 // IsAddrDescIndexable returns true if AddressDescriptor should be added to index
 // by default all AddressDescriptors are indexable
 func (p *BaseParser) IsAddrDescIndexable(addrDesc AddressDescriptor) bool {
 	return true
 }
 
+// XPubGapLimit returns 0, the Blockbook default gap is used
+func (p *BaseParser) XPubGapLimit() int {
+	return 0
+}
+
 // DerivationBasePath is unsupported
 func (p *BaseParser) DerivationBasePath(xpub string) (string, error) {
 	return "", errors.New("Not supported")
@@ -55,3 +55,107 @@ func TestBaseParser_AmountToBigInt(t *testing.T) {
 		})
 	}
 }
+
+func TestBaseParser_Confirmations(t *testing.T) {
+	tests := []struct {
+		name      string
+		txHeight  uint32
+		tipHeight uint32
+		want      int
+	}{
+		{"genesis", 0, 100, 101},
+		{"tip", 100, 100, 1},
+		{"confirmed", 90, 100, 11},
+		{"above tip", 101, 100, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewBaseParser(8).Confirmations(tt.txHeight, tt.tipHeight); got != tt.want {
+				t.Errorf("BaseParser.Confirmations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBaseParser_CanonicalTxJSON(t *testing.T) {
+	want := `{"txid":"a1b2","vin":[{"sequence":4294967295,"txid":"c3d4","vout":0}],"vout":[{"n":0,"scriptPubKey":{"hex":"76a9<>"},"value":0.1},{"n":1,"value":50}]}`
+	tests := []struct {
+		name    string
+		msg     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "canonical",
+			msg:  want,
+			want: want,
+		},
+		{
+			name: "reordered keys and whitespace",
+			msg: `{
+				"vout": [{"value": 0.1, "scriptPubKey": {"hex": "76a9<>"}, "n": 0}, {"value": 50, "n": 1}],
+				"vin": [{"vout": 0, "txid": "c3d4", "sequence": 4294967295}],
+				"txid": "a1b2"
+			}`,
+			want: want,
+		},
+		{
+			name: "number formatting",
+			msg:  `{"txid":"a1b2","vin":[{"sequence":4294967295e0,"txid":"c3d4","vout":-0}],"vout":[{"n":0.0,"scriptPubKey":{"hex":"76a9<>"},"value":0.10000000},{"n":1,"value":5E1}]}`,
+			want: want,
+		},
+		{
+			name: "small value",
+			msg:  `{"value":1e-8,"fee":-0.00012300}`,
+			want: `{"fee":-0.000123,"value":0.00000001}`,
+		},
+		{
+			name:    "invalid json",
+			msg:     `{"txid":`,
+			wantErr: true,
+		},
+	}
+	p := NewBaseParser(8)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.CanonicalTxJSON(json.RawMessage(tt.msg))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BaseParser.CanonicalTxJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("BaseParser.CanonicalTxJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBaseParser_ParseTxFromJsonMaxMoney(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxMoney *big.Int
+		values   string
+		wantErr  bool
+	}{
+		// without configured max money the range is not checked, the supply of many coins exceeds the Bitcoin limit
+		{name: "not configured", values: `{"value":21000000.00000001,"n":0}`},
+		{name: "max money", maxMoney: DefaultMaxMoneySat, values: `{"value":21000000,"n":0}`},
+		{name: "above max money", maxMoney: DefaultMaxMoneySat, values: `{"value":21000000.00000001,"n":0}`, wantErr: true},
+		{name: "negative", maxMoney: DefaultMaxMoneySat, values: `{"value":-0.00000001,"n":0}`, wantErr: true},
+		{name: "sum above max money", maxMoney: DefaultMaxMoneySat, values: `{"value":20000000,"n":0},{"value":1000000.00000001,"n":1}`, wantErr: true},
+		{name: "configured max money", maxMoney: big.NewInt(2140000000000000), values: `{"value":21400000,"n":0}`},
+		{name: "above configured max money", maxMoney: big.NewInt(2140000000000000), values: `{"value":21400000.00000001,"n":0}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewBaseParser(8)
+			p.MaxMoney = tt.maxMoney
+			_, err := p.ParseTxFromJson(json.RawMessage(`{"txid":"a1b2","vout":[` + tt.values + `]}`))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTxFromJson() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if got := NewBaseParser(8).MaxMoneySat(); got.Cmp(big.NewInt(2100000000000000)) != 0 {
+		t.Errorf("MaxMoneySat() = %v, want 2100000000000000", got)
+	}
+}
@@ -353,3 +353,11 @@ func (c *mempoolWithMetrics) GetAllEntries() (v bchain.MempoolTxidEntries) {
 func (c *mempoolWithMetrics) GetTransactionTime(txid string) uint32 {
 	return c.mempool.GetTransactionTime(txid)
 }
+
+func (c *mempoolWithMetrics) GetPrivacySerialTime(serial string) uint32 {
+	return c.mempool.GetPrivacySerialTime(serial)
+}
+
+func (c *mempoolWithMetrics) GetPrivacySummary() bchain.MempoolPrivacySummary {
+	return c.mempool.GetPrivacySummary()
+}
@@ -9,6 +9,7 @@ import (
 	"strconv"
 
 	vlq "github.com/bsm/go-vlq"
+	"github.com/golang/glog"
 	"github.com/juju/errors"
 	"github.com/martinboehm/btcd/blockchain"
 	"github.com/martinboehm/btcd/wire"
@@ -18,6 +19,39 @@ import (
 	"github.com/martinboehm/btcutil/txscript"
 )
 
+const (
+	// DefaultMaxBlockSize is the maximum size of a block used for sanity checks if not configured,
+	// it is intentionally larger than the Bitcoin limit to keep the parsing lenient
+	DefaultMaxBlockSize = 32 * 1024 * 1024
+	// MinTxSize is the size of the smallest possible transaction with one input and one output
+	MinTxSize = 60
+	// MinTxOutSize is the size of the smallest possible transaction output, the value and the empty script
+	MinTxOutSize = 9
+	// DefaultDustRelayFee is the Bitcoin Core dust relay fee in satoshis per kB
+	DefaultDustRelayFee = 3000
+	// dustOutputSpendSize is the size of P2PKH output (34 bytes) and of the input spending it (148 bytes)
+	dustOutputSpendSize = 182
+	// DefaultMaxDataCarrierSize is the Bitcoin Core limit of the size of a standard OP_RETURN output script,
+	// the OP_RETURN opcode, the push opcodes and 80 bytes of data
+	DefaultMaxDataCarrierSize = 83
+	// Difficulty1Bits is the compact target of the difficulty 1, the proof-of-work limit of Bitcoin
+	Difficulty1Bits = 0x1d00ffff
+)
+
+// BIP30Exception is a coinbase transaction which duplicates the txid of an earlier transaction,
+// as allowed before BIP30 and BIP34
+type BIP30Exception struct {
+	Height uint32 `json:"height"`
+	Txid   string `json:"txid"`
+}
+
+// BitcoinMainnetBIP30Exceptions are the duplicate coinbase transactions of the Bitcoin main network,
+// the coinbases of the blocks 91812 and 91722 were overwritten by them
+var BitcoinMainnetBIP30Exceptions = []BIP30Exception{
+	{Height: 91842, Txid: "d5d27987d2a3dfc724e359870c6644b40e497bdc0589a033220fe15429d88599"},
+	{Height: 91880, Txid: "e3bf3d07d4b0375638d5f1db5255fe07ba2c4cb067cd81b84ee974b6585fb468"},
+}
+
 // OutputScriptToAddressesFunc converts ScriptPubKey to bitcoin addresses
 type OutputScriptToAddressesFunc func(script []byte) ([]string, bool, error)
 
@@ -31,6 +65,12 @@ type BitcoinParser struct {
 	XPubMagicSegwitNative        uint32
 	Slip44                       uint32
 	minimumCoinbaseConfirmations int
+	finalityConfirmations        int
+	dustRelayFee                 int64
+	maxDataCarrierSize           int
+	maxBlockSize                 int
+	xpubGapLimit                 int
+	bip30Exceptions              map[uint32]string
 }
 
 // NewBitcoinParser returns new BitcoinParser instance
@@ -39,6 +79,7 @@ func NewBitcoinParser(params *chaincfg.Params, c *Configuration) *BitcoinParser
 		BaseParser: &bchain.BaseParser{
 			BlockAddressesToKeep: c.BlockAddressesToKeep,
 			AmountDecimalPoint:   8,
+			Shortcut:             c.CoinShortcut,
 		},
 		Params:                       params,
 		XPubMagic:                    c.XPubMagic,
@@ -46,6 +87,35 @@ func NewBitcoinParser(params *chaincfg.Params, c *Configuration) *BitcoinParser
 		XPubMagicSegwitNative:        c.XPubMagicSegwitNative,
 		Slip44:                       c.Slip44,
 		minimumCoinbaseConfirmations: c.MinimumCoinbaseConfirmations,
+		finalityConfirmations:        c.FinalityConfirmations,
+		dustRelayFee:                 c.DustRelayFee,
+		maxDataCarrierSize:           c.MaxDataCarrierSize,
+		maxBlockSize:                 c.MaxBlockSize,
+		xpubGapLimit:                 c.XPubGapLimit,
+	}
+	if p.maxBlockSize <= 0 {
+		p.maxBlockSize = DefaultMaxBlockSize
+	}
+	if c.MaxMoney != "" {
+		// the supply of some coins in satoshis does not fit to int64, the value is a decimal string
+		if m, ok := new(big.Int).SetString(c.MaxMoney, 10); ok && m.Sign() > 0 {
+			p.BaseParser.MaxMoney = m
+		} else {
+			glog.Error("Invalid max_money ", c.MaxMoney, ", the money range is not checked")
+		}
+	}
+	if p.maxDataCarrierSize <= 0 {
+		p.maxDataCarrierSize = DefaultMaxDataCarrierSize
+	}
+	exceptions := c.BIP30Exceptions
+	if exceptions == nil && params.Net == wire.MainNet {
+		exceptions = BitcoinMainnetBIP30Exceptions
+	}
+	if len(exceptions) > 0 {
+		p.bip30Exceptions = make(map[uint32]string, len(exceptions))
+		for _, e := range exceptions {
+			p.bip30Exceptions[e.Height] = e.Txid
+		}
 	}
 	p.OutputScriptToAddressesFunc = p.outputScriptToAddresses
 	return p
@@ -93,6 +163,17 @@ func (p *BitcoinParser) GetScriptFromAddrDesc(addrDesc bchain.AddressDescriptor)
 	return addrDesc, nil
 }
 
+// NetworkMagic returns the peer-to-peer protocol magic of the configured network
+func (p *BitcoinParser) NetworkMagic() wire.BitcoinNet {
+	return p.Params.Net
+}
+
+// Bech32HRP returns the human readable part of the bech32 segwit addresses of the configured network,
+// empty string if the network does not have segwit addresses
+func (p *BitcoinParser) Bech32HRP() string {
+	return p.Params.Bech32HRPSegwit
+}
+
 // IsAddrDescIndexable returns true if AddressDescriptor should be added to index
 // empty or OP_RETURN scripts are not indexed
 func (p *BitcoinParser) IsAddrDescIndexable(addrDesc bchain.AddressDescriptor) bool {
@@ -215,6 +296,79 @@ func (p *BitcoinParser) outputScriptToAddresses(script []byte) ([]string, bool,
 	return rv, s, nil
 }
 
+// SetTxSizes sets the base size, the witness size and the virtual size (BIP141) of the transaction from the wire Tx,
+// the virtual size is the weight (3 * base size + total size) divided by 4 and rounded up
+func SetTxSizes(tx *bchain.Tx, t *wire.MsgTx) {
+	tx.BaseSize = t.SerializeSizeStripped()
+	total := t.SerializeSize()
+	tx.WitnessSize = total - tx.BaseSize
+	tx.VSize = (tx.BaseSize*3 + total + 3) / 4
+}
+
+// CoinbaseHeight returns the block height serialized by BIP34 at the start of the scriptSig of the coinbase transaction,
+// the height is encoded as OP_0, OP_1-OP_16 or as a push of the little endian script number
+func (p *BitcoinParser) CoinbaseHeight(tx *bchain.Tx) (int64, error) {
+	if len(tx.Vin) == 0 || tx.Vin[0].Coinbase == "" {
+		return 0, errors.New("Not a coinbase transaction")
+	}
+	script, err := hex.DecodeString(tx.Vin[0].Coinbase)
+	if err != nil {
+		return 0, err
+	}
+	if len(script) == 0 {
+		return 0, errors.New("Empty coinbase script")
+	}
+	op := script[0]
+	switch {
+	case op == txscript.OP_0:
+		return 0, nil
+	case op >= txscript.OP_1 && op <= txscript.OP_16:
+		return int64(op - txscript.OP_1 + 1), nil
+	case op > 8:
+		return 0, errors.Errorf("Invalid coinbase height push %v", op)
+	case len(script) < int(op)+1:
+		return 0, errors.New("Short coinbase height push")
+	}
+	// script number, little endian with the sign in the highest bit of the last byte
+	data := script[1 : op+1]
+	var h int64
+	for i := range data {
+		h |= int64(data[i]) << uint(8*i)
+	}
+	if data[len(data)-1]&0x80 != 0 {
+		h &^= int64(0x80) << uint(8*(len(data)-1))
+		h = -h
+	}
+	return h, nil
+}
+
+// IsBIP30Exception returns true if the transaction is a known duplicate of an earlier transaction at the given height,
+// the duplicates are possible only before the activation of BIP34, later heights are never exceptions
+func (p *BitcoinParser) IsBIP30Exception(height uint32, txid string) bool {
+	if p.bip30Exceptions == nil || (p.Params.BIP0034Height > 0 && int64(height) >= int64(p.Params.BIP0034Height)) {
+		return false
+	}
+	t, found := p.bip30Exceptions[height]
+	return found && t == txid
+}
+
+// TxWeight returns the weight of the transaction (BIP141), if the sizes of the transaction are not set,
+// they are computed from its hex, 0 if the hex is missing or cannot be decoded
+func (p *BitcoinParser) TxWeight(tx *bchain.Tx) int {
+	if tx.BaseSize > 0 || tx.Hex == "" {
+		return p.BaseParser.TxWeight(tx)
+	}
+	b, err := hex.DecodeString(tx.Hex)
+	if err != nil {
+		return 0
+	}
+	t := wire.MsgTx{}
+	if err := t.Deserialize(bytes.NewReader(b)); err != nil {
+		return 0
+	}
+	return t.SerializeSizeStripped()*3 + t.SerializeSize()
+}
+
 // TxFromMsgTx converts bitcoin wire Tx to bchain.Tx
 func (p *BitcoinParser) TxFromMsgTx(t *wire.MsgTx, parseAddresses bool) bchain.Tx {
 	vin := make([]bchain.Vin, len(t.TxIn))
@@ -328,11 +482,97 @@ func (p *BitcoinParser) UnpackTx(buf []byte) (*bchain.Tx, uint32, error) {
 	return tx, height, nil
 }
 
+// XPubGapLimit returns the configured default gap of unused addresses in xpub derivation, 0 if not configured
+func (p *BitcoinParser) XPubGapLimit() int {
+	return p.xpubGapLimit
+}
+
 // MinimumCoinbaseConfirmations returns minimum number of confirmations a coinbase transaction must have before it can be spent
 func (p *BitcoinParser) MinimumCoinbaseConfirmations() int {
 	return p.minimumCoinbaseConfirmations
 }
 
+// RequiredConfirmations returns the number of confirmations the output must have before it can be spent,
+// MinimumCoinbaseConfirmations for the outputs of the coinbase and coinstake transactions, 1 for other outputs
+func (p *BitcoinParser) RequiredConfirmations(vout uint32, isCoinbase, isStake bool) int {
+	if (isCoinbase || isStake) && p.minimumCoinbaseConfirmations > 1 {
+		return p.minimumCoinbaseConfirmations
+	}
+	return 1
+}
+
+// FinalityConfirmations returns the configured number of confirmations after which a transaction is considered final,
+// bchain.DefaultFinalityConfirmations if not configured
+func (p *BitcoinParser) FinalityConfirmations() int {
+	if p.finalityConfirmations > 0 {
+		return p.finalityConfirmations
+	}
+	return bchain.DefaultFinalityConfirmations
+}
+
+// DustThresholdSat returns the value in satoshis below which an output is considered dust, it is derived
+// from the dust relay fee (dust_relay_fee option, DefaultDustRelayFee if not configured) in the same way as in Bitcoin Core,
+// as the fee of spending the output, which is the size of P2PKH output and of the input spending it
+func (p *BitcoinParser) DustThresholdSat() int64 {
+	fee := p.dustRelayFee
+	if fee <= 0 {
+		fee = DefaultDustRelayFee
+	}
+	return fee * dustOutputSpendSize / 1000
+}
+
+// MaxDataCarrierSize returns the maximum size of the script of a standard OP_RETURN output
+// (max_datacarrier_size option, DefaultMaxDataCarrierSize if not configured)
+func (p *BitcoinParser) MaxDataCarrierSize() int {
+	return p.maxDataCarrierSize
+}
+
+// IsNonStandardOutput returns true if the output script is an OP_RETURN output larger than the data-carrier size limit,
+// such outputs are valid in blocks but are not relayed by the nodes
+func (p *BitcoinParser) IsNonStandardOutput(addrDesc bchain.AddressDescriptor) bool {
+	return len(addrDesc) > 0 && addrDesc[0] == txscript.OP_RETURN && len(addrDesc) > p.maxDataCarrierSize
+}
+
+// Difficulty returns the difficulty of the compact target bits relative to the target of Difficulty1Bits,
+// as the getdifficulty RPC of Bitcoin Core, the proof-of-work and proof-of-stake blocks are not distinguished
+func (p *BitcoinParser) Difficulty(bits uint32, proofOfStake bool) float64 {
+	return CompactDifficulty(bits, Difficulty1Bits)
+}
+
+// CompactDifficulty returns the ratio of the target given by the compact limitBits to the target given by the compact bits,
+// 0 for non-positive target
+func CompactDifficulty(bits, limitBits uint32) float64 {
+	target := blockchain.CompactToBig(bits)
+	if target.Sign() <= 0 {
+		return 0
+	}
+	d, _ := new(big.Rat).SetFrac(blockchain.CompactToBig(limitBits), target).Float64()
+	return d
+}
+
+// MaxBlockSize returns the maximum size of a block in bytes used for sanity checks of the parsed data
+func (p *BitcoinParser) MaxBlockSize() int {
+	return p.maxBlockSize
+}
+
+// MaxBlockTxCount returns the maximum number of transactions in a block, derived from MaxBlockSize
+// as the number of the smallest possible transactions which fit in the block
+func (p *BitcoinParser) MaxBlockTxCount() int {
+	return p.maxBlockSize / MinTxSize
+}
+
+// MaxTxOutCount returns the maximum number of outputs of a transaction, derived from MaxBlockSize
+// as the number of the smallest possible outputs which fit in the block
+func (p *BitcoinParser) MaxTxOutCount() int {
+	return p.maxBlockSize / MinTxOutSize
+}
+
+// MaxScriptSize returns the maximum size of a script (signature or output script) in bytes,
+// a script cannot be larger than the block containing it
+func (p *BitcoinParser) MaxScriptSize() int {
+	return p.maxBlockSize
+}
+
 func (p *BitcoinParser) addrDescFromExtKey(extKey *hdkeychain.ExtendedKey) (bchain.AddressDescriptor, error) {
 	var a btcutil.Address
 	var err error
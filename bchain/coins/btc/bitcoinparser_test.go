@@ -5,12 +5,15 @@ package btc
 import (
 	"blockbook/bchain"
 	"encoding/hex"
+	"encoding/json"
+	"math"
 	"math/big"
 	"os"
 	"reflect"
 	"testing"
 
 	"github.com/martinboehm/btcutil/chaincfg"
+	"github.com/martinboehm/btcutil/txscript"
 )
 
 func TestMain(m *testing.M) {
@@ -19,6 +22,53 @@ func TestMain(m *testing.M) {
 	os.Exit(c)
 }
 
+func TestBech32HRP(t *testing.T) {
+	tests := []struct {
+		chain string
+		want  string
+	}{
+		{chain: "main", want: "bc"},
+		{chain: "test", want: "tb"},
+		{chain: "regtest", want: "bcrt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.chain, func(t *testing.T) {
+			parser := NewBitcoinParser(GetChainParams(tt.chain), &Configuration{})
+			if got := parser.Bech32HRP(); got != tt.want {
+				t.Errorf("Bech32HRP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsNonStandardOutput(t *testing.T) {
+	// OP_RETURN OP_PUSHDATA1 <len> <data>
+	opReturn := func(dataLen int) bchain.AddressDescriptor {
+		return append([]byte{txscript.OP_RETURN, txscript.OP_PUSHDATA1, byte(dataLen)}, make([]byte, dataLen)...)
+	}
+	tests := []struct {
+		name     string
+		maxSize  int
+		addrDesc bchain.AddressDescriptor
+		want     bool
+	}{
+		{name: "default limit", addrDesc: opReturn(80), want: false},
+		{name: "default limit exceeded", addrDesc: opReturn(81), want: true},
+		{name: "configured limit", maxSize: 43, addrDesc: opReturn(40), want: false},
+		{name: "configured limit exceeded", maxSize: 43, addrDesc: opReturn(41), want: true},
+		{name: "P2WSH over limit", maxSize: 10, addrDesc: append([]byte{0, 32}, make([]byte, 32)...), want: false},
+		{name: "empty", addrDesc: bchain.AddressDescriptor{}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewBitcoinParser(GetChainParams("main"), &Configuration{MaxDataCarrierSize: tt.maxSize})
+			if got := parser.IsNonStandardOutput(tt.addrDesc); got != tt.want {
+				t.Errorf("IsNonStandardOutput() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestGetAddrDescFromAddress(t *testing.T) {
 	type args struct {
 		address string
@@ -680,3 +730,142 @@ func TestBitcoinParser_DerivationBasePath(t *testing.T) {
 		})
 	}
 }
+
+func TestDifficulty(t *testing.T) {
+	tests := []struct {
+		name string
+		bits uint32
+		want float64
+	}{
+		{name: "difficulty 1", bits: 0x1d00ffff, want: 1},
+		{name: "block 100000", bits: 0x1b04864c, want: 14484.1623612254},
+		{name: "block 32256", bits: 0x1d00d86a, want: 1.182899534312841},
+		{name: "zero target", bits: 0x1d000000, want: 0},
+		{name: "negative target", bits: 0x1d800001, want: 0},
+	}
+	parser := NewBitcoinParser(GetChainParams("main"), &Configuration{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parser.Difficulty(tt.bits, false)
+			if math.Abs(got-tt.want) > 1e-9*math.Max(1, tt.want) {
+				t.Errorf("Difficulty() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTxWeight(t *testing.T) {
+	parser := NewBitcoinParser(GetChainParams("main"), &Configuration{})
+	tests := []struct {
+		name string
+		tx   bchain.Tx
+		want int
+	}{
+		{name: "non-segwit", tx: bchain.Tx{Hex: testTx1.Hex}, want: 756},
+		{name: "segwit", tx: bchain.Tx{Hex: testTx2.Hex}, want: 661},
+		{name: "sizes set", tx: bchain.Tx{BaseSize: 138, WitnessSize: 109, VSize: 166}, want: 661},
+		{name: "without hex", tx: bchain.Tx{}, want: 0},
+		{name: "invalid hex", tx: bchain.Tx{Hex: "0100"}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parser.TxWeight(&tt.tx); got != tt.want {
+				t.Errorf("TxWeight() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoinbaseHeight(t *testing.T) {
+	parser := NewBitcoinParser(GetChainParams("main"), &Configuration{})
+	tests := []struct {
+		name     string
+		coinbase string
+		want     int64
+		wantErr  bool
+	}{
+		{name: "3 bytes", coinbase: "0367ed0104ee2f375c", want: 126311},
+		{name: "2 bytes", coinbase: "02a330062f503253482f", want: 12451},
+		{name: "sign byte", coinbase: "03ffff0000", want: 65535},
+		{name: "OP_0", coinbase: "00", want: 0},
+		{name: "OP_1", coinbase: "51", want: 1},
+		{name: "OP_16", coinbase: "60", want: 16},
+		{name: "negative", coinbase: "0181", want: -1},
+		{name: "short push", coinbase: "03ab", wantErr: true},
+		{name: "not a push", coinbase: "4c0101", wantErr: true},
+		{name: "invalid hex", coinbase: "0z", wantErr: true},
+		{name: "not coinbase", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := bchain.Tx{Vin: []bchain.Vin{{Coinbase: tt.coinbase}}}
+			got, err := parser.CoinbaseHeight(&tx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CoinbaseHeight() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CoinbaseHeight() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBIP30Exception(t *testing.T) {
+	mainParser := NewBitcoinParser(GetChainParams("main"), &Configuration{})
+	testParser := NewBitcoinParser(GetChainParams("test"), &Configuration{})
+	configuredParser := NewBitcoinParser(GetChainParams("main"), &Configuration{BIP30Exceptions: []BIP30Exception{
+		{Height: 1000, Txid: "aa"},
+		{Height: 300000, Txid: "bb"},
+	}})
+	tests := []struct {
+		name   string
+		parser *BitcoinParser
+		height uint32
+		txid   string
+		want   bool
+	}{
+		{name: "91842", parser: mainParser, height: 91842, txid: "d5d27987d2a3dfc724e359870c6644b40e497bdc0589a033220fe15429d88599", want: true},
+		{name: "91880", parser: mainParser, height: 91880, txid: "e3bf3d07d4b0375638d5f1db5255fe07ba2c4cb067cd81b84ee974b6585fb468", want: true},
+		{name: "original 91812", parser: mainParser, height: 91812, txid: "d5d27987d2a3dfc724e359870c6644b40e497bdc0589a033220fe15429d88599", want: false},
+		{name: "original 91722", parser: mainParser, height: 91722, txid: "e3bf3d07d4b0375638d5f1db5255fe07ba2c4cb067cd81b84ee974b6585fb468", want: false},
+		{name: "other txid", parser: mainParser, height: 91842, txid: "e3bf3d07d4b0375638d5f1db5255fe07ba2c4cb067cd81b84ee974b6585fb468", want: false},
+		{name: "testnet", parser: testParser, height: 91842, txid: "d5d27987d2a3dfc724e359870c6644b40e497bdc0589a033220fe15429d88599", want: false},
+		{name: "configured", parser: configuredParser, height: 1000, txid: "aa", want: true},
+		{name: "configured replaces default", parser: configuredParser, height: 91842, txid: "d5d27987d2a3dfc724e359870c6644b40e497bdc0589a033220fe15429d88599", want: false},
+		{name: "configured after BIP34", parser: configuredParser, height: 300000, txid: "bb", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.parser.IsBIP30Exception(tt.height, tt.txid); got != tt.want {
+				t.Errorf("IsBIP30Exception() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxMoneyConfiguration(t *testing.T) {
+	tx := func(value string) json.RawMessage {
+		return json.RawMessage(`{"txid":"a1b2","vout":[{"value":` + value + `,"n":0,"scriptPubKey":{"hex":"76a914a7f3e9e1e9a1d0fc43d84ee5dbd1e6e7c2306b0b88ac"}}]}`)
+	}
+	tests := []struct {
+		name     string
+		maxMoney string
+		value    string
+		wantErr  bool
+	}{
+		{name: "not configured", value: "100000000000"},
+		// the supply of 10^13 coins in satoshis does not fit to int64
+		{name: "above int64", maxMoney: "1000000000000000000000", value: "100000000000"},
+		{name: "above configured", maxMoney: "1000000000000000000000", value: "10000000000000.00000001", wantErr: true},
+		{name: "invalid", maxMoney: "21e14", value: "100000000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewBitcoinParser(GetChainParams("main"), &Configuration{MaxMoney: tt.maxMoney})
+			_, err := parser.ParseTxFromJson(tx(tt.value))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTxFromJson() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -32,8 +32,17 @@ type BitcoinRPC struct {
 	mq           *bchain.MQ
 	ChainConfig  *Configuration
 	RPCMarshaler RPCMarshaler
+	// maxResponseSize limits the size of the responses of the backend, 0 means unlimited
+	maxResponseSize int64
 }
 
+// responseSizeBlockMultiple is the multiple of the maximum block size used as the default limit of the response size,
+// it covers the hex encoded block (twice the size of the block) and leaves room for the verbose JSON of normal blocks
+const responseSizeBlockMultiple = 4
+
+// ErrResponseTooLarge is returned if the response of the backend exceeds the limit set by the max_response_size option
+var ErrResponseTooLarge = errors.New("Backend response too large")
+
 // Configuration represents json config file
 type Configuration struct {
 	CoinName                     string `json:"coin_name"`
@@ -58,6 +67,23 @@ type Configuration struct {
 	AlternativeEstimateFee       string `json:"alternative_estimate_fee,omitempty"`
 	AlternativeEstimateFeeParams string `json:"alternative_estimate_fee_params,omitempty"`
 	MinimumCoinbaseConfirmations int    `json:"minimumCoinbaseConfirmations,omitempty"`
+	MaxBlockSize                 int    `json:"max_block_size,omitempty"`
+	XPubGapLimit                 int    `json:"xpub_gap_limit,omitempty"`
+	TargetBlockTime              int    `json:"target_block_time,omitempty"`
+	MempoolExpiryBlocks          int    `json:"mempool_expiry_blocks,omitempty"`
+	MempoolDisableRBF            bool   `json:"mempool_disable_rbf,omitempty"`
+	GenesisBlockTime             int64  `json:"genesis_block_time,omitempty"`
+	GenesisBlockHash             string `json:"genesis_block_hash,omitempty"`
+	GenesisCoinbaseTxid          string `json:"genesis_coinbase_txid,omitempty"`
+	FinalityConfirmations        int    `json:"finality_confirmations,omitempty"`
+	DustRelayFee                 int64  `json:"dust_relay_fee,omitempty"`
+	MaxDataCarrierSize           int    `json:"max_datacarrier_size,omitempty"`
+	MaxResponseSize              int64  `json:"max_response_size,omitempty"`
+	MaxMoney                     string `json:"max_money,omitempty"`
+
+	// BIP30Exceptions are the duplicate coinbase transactions which are not indexed,
+	// if not set, the known duplicates of the Bitcoin main network are used for it
+	BIP30Exceptions []BIP30Exception `json:"bip30_exceptions,omitempty"`
 }
 
 // NewBitcoinRPC returns new BitcoinRPC instance.
@@ -86,6 +112,14 @@ func NewBitcoinRPC(config json.RawMessage, pushHandler func(bchain.NotificationT
 	// btc supports both calls, other coins overriding BitcoinRPC can change this
 	c.SupportsEstimateFee = true
 	c.SupportsEstimateSmartFee = true
+	// by default the response must fit a hex encoded block of the maximum size, negative value disables the limit
+	if c.MaxResponseSize == 0 {
+		maxBlockSize := c.MaxBlockSize
+		if maxBlockSize <= 0 {
+			maxBlockSize = DefaultMaxBlockSize
+		}
+		c.MaxResponseSize = int64(maxBlockSize) * responseSizeBlockMultiple
+	}
 
 	transport := &http.Transport{
 		Dial:                (&net.Dialer{KeepAlive: 600 * time.Second}).Dial,
@@ -104,6 +138,9 @@ func NewBitcoinRPC(config json.RawMessage, pushHandler func(bchain.NotificationT
 		pushHandler:  pushHandler,
 		RPCMarshaler: JSONMarshalerV2{},
 	}
+	if c.MaxResponseSize > 0 {
+		s.maxResponseSize = c.MaxResponseSize
+	}
 
 	return s, nil
 }
@@ -145,10 +182,16 @@ func (b *BitcoinRPC) Initialize() error {
 	return nil
 }
 
+// mempoolExpiry returns the time after which the transactions are removed from the mempool view,
+// derived from the target block time and the number of blocks, zero if not configured
+func (b *BitcoinRPC) mempoolExpiry() time.Duration {
+	return time.Duration(b.ChainConfig.TargetBlockTime) * time.Duration(b.ChainConfig.MempoolExpiryBlocks) * time.Second
+}
+
 // CreateMempool creates mempool if not already created, however does not initialize it
 func (b *BitcoinRPC) CreateMempool(chain bchain.BlockChain) (bchain.Mempool, error) {
 	if b.Mempool == nil {
-		b.Mempool = bchain.NewMempoolBitcoinType(chain, b.ChainConfig.MempoolWorkers, b.ChainConfig.MempoolSubWorkers)
+		b.Mempool = bchain.NewMempoolBitcoinType(chain, b.ChainConfig.MempoolWorkers, b.ChainConfig.MempoolSubWorkers, b.mempoolExpiry(), !b.ChainConfig.MempoolDisableRBF)
 	}
 	return b.Mempool, nil
 }
@@ -719,10 +762,10 @@ func (b *BitcoinRPC) GetTransaction(txid string) (*bchain.Tx, error) {
 		return nil, err
 	}
 	tx, err := b.Parser.ParseTxFromJson(r)
-	tx.CoinSpecificData = r
 	if err != nil {
 		return nil, errors.Annotatef(err, "txid %v", txid)
 	}
+	tx.CoinSpecificData = r
 	return tx, nil
 }
 
@@ -862,7 +905,9 @@ func (b *BitcoinRPC) GetMempoolEntry(txid string) (*bchain.MempoolEntry, error)
 	return res.Result, nil
 }
 
-func safeDecodeResponse(body io.ReadCloser, res interface{}) (err error) {
+// safeDecodeResponse reads and unmarshals the response, if maxSize is positive, the response larger than maxSize
+// is not read whole and ErrResponseTooLarge is returned
+func safeDecodeResponse(body io.ReadCloser, res interface{}, maxSize int64) (err error) {
 	var data []byte
 	defer func() {
 		if r := recover(); r != nil {
@@ -875,10 +920,18 @@ func safeDecodeResponse(body io.ReadCloser, res interface{}) (err error) {
 			}
 		}
 	}()
-	data, err = ioutil.ReadAll(body)
+	if maxSize > 0 {
+		data, err = ioutil.ReadAll(io.LimitReader(body, maxSize+1))
+	} else {
+		data, err = ioutil.ReadAll(body)
+	}
 	if err != nil {
 		return err
 	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		data = nil
+		return errors.Annotatef(ErrResponseTooLarge, "response exceeds max_response_size %d bytes, increase the option or fetch the block in the raw (binary) form", maxSize)
+	}
 	return json.Unmarshal(data, &res)
 }
 
@@ -905,11 +958,11 @@ func (b *BitcoinRPC) Call(req interface{}, res interface{}) error {
 	// if server returns HTTP error code it might not return json with response
 	// handle both cases
 	if httpRes.StatusCode != 200 {
-		err = safeDecodeResponse(httpRes.Body, &res)
+		err = safeDecodeResponse(httpRes.Body, &res, b.maxResponseSize)
 		if err != nil {
 			return errors.Errorf("%v %v", httpRes.Status, err)
 		}
 		return nil
 	}
-	return safeDecodeResponse(httpRes.Body, &res)
+	return safeDecodeResponse(httpRes.Body, &res, b.maxResponseSize)
 }
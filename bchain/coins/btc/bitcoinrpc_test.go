@@ -0,0 +1,36 @@
+// +build unittest
+
+package btc
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/juju/errors"
+)
+
+func Test_safeDecodeResponse(t *testing.T) {
+	response := `{"result":"0100000000","error":null,"id":"1"}`
+	tests := []struct {
+		name    string
+		maxSize int64
+		wantErr error
+	}{
+		{name: "unlimited", maxSize: 0},
+		{name: "exact limit", maxSize: int64(len(response))},
+		{name: "over limit", maxSize: int64(len(response)) - 1, wantErr: ErrResponseTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var res ResGetBlockRaw
+			err := safeDecodeResponse(ioutil.NopCloser(strings.NewReader(response)), &res, tt.maxSize)
+			if errors.Cause(err) != tt.wantErr {
+				t.Fatalf("safeDecodeResponse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && res.Result != "0100000000" {
+				t.Errorf("safeDecodeResponse() result = %v", res.Result)
+			}
+		})
+	}
+}
@@ -131,7 +131,7 @@ func whatTheFeeGetData(res interface{}) error {
 	if httpRes.StatusCode != 200 {
 		return errors.New("whatthefee.io returned status " + strconv.Itoa(httpRes.StatusCode))
 	}
-	return safeDecodeResponse(httpRes.Body, &res)
+	return safeDecodeResponse(httpRes.Body, &res, 0)
 }
 
 func whatTheFeeCompareToDefault() {
@@ -0,0 +1,435 @@
+package xzc
+
+import (
+	"blockbook/bchain"
+	"bytes"
+	"crypto/sha256"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcutil/base58"
+	"github.com/martinboehm/btcutil/txscript"
+)
+
+// base58 address checksum variants
+const (
+	AddressChecksumSha256D = "sha256d"
+	AddressChecksumSha256  = "sha256"
+)
+
+// checkAddressChecksum returns error if the base58 checksum variant given by name is unknown, empty name is the default sha256d
+func checkAddressChecksum(name string) error {
+	switch name {
+	case "", AddressChecksumSha256D, AddressChecksumSha256:
+		return nil
+	}
+	return errors.Errorf("Unknown address checksum %v", name)
+}
+
+// sha256Checksum returns the first four bytes of the single sha256 hash of the input, the checksum
+// of the sha256 variant, which is not supported by the base58 package of btcutil
+func sha256Checksum(input []byte) (cksum [4]byte) {
+	h := sha256.Sum256(input)
+	copy(cksum[:], h[:4])
+	return
+}
+
+// base58CheckEncodeSha256 prepends the version and appends the sha256 checksum to the input and encodes it
+func base58CheckEncodeSha256(input, version []byte) string {
+	b := make([]byte, 0, len(version)+len(input)+4)
+	b = append(b, version...)
+	b = append(b, input...)
+	cksum := sha256Checksum(b)
+	return base58.Encode(append(b, cksum[:]...))
+}
+
+// base58CheckDecodeSha256 decodes the string encoded by base58CheckEncodeSha256 and verifies the checksum
+func base58CheckDecodeSha256(input string, versionLen uint8) ([]byte, []byte, error) {
+	decoded := base58.Decode(input)
+	if len(decoded) < int(versionLen)+4 {
+		return nil, nil, base58.ErrInvalidFormat
+	}
+	var cksum [4]byte
+	copy(cksum[:], decoded[len(decoded)-4:])
+	if sha256Checksum(decoded[:len(decoded)-4]) != cksum {
+		return nil, nil, base58.ErrChecksum
+	}
+	return decoded[versionLen : len(decoded)-4], decoded[:versionLen], nil
+}
+
+// hasSha256Checksum returns true if the base58 addresses use the sha256 checksum variant
+func (p *ZcoinParser) hasSha256Checksum() bool {
+	return p.config.AddressChecksum == AddressChecksumSha256
+}
+
+// initAddressVariant sets up the address checksum and bech32 prefix variants of the forks given by the configuration
+// the chain params are copied, the registered params of the network are not modified
+func (p *ZcoinParser) initAddressVariant() error {
+	if p.config.AddressChecksum == "" && p.config.Bech32HRP == "" && !p.config.Taproot {
+		return nil
+	}
+	if err := checkAddressChecksum(p.config.AddressChecksum); err != nil {
+		return err
+	}
+	if p.config.Bech32HRP != "" {
+		params := *p.Params
+		params.Bech32HRPSegwit = p.config.Bech32HRP
+		p.Params = &params
+	}
+	p.BitcoinOutputScriptToAddressesFunc = p.OutputScriptToAddressesFunc
+	p.OutputScriptToAddressesFunc = p.outputScriptToAddresses
+	return nil
+}
+
+// base58AddressToOutputScript converts the base58 encoded P2PKH or P2SH address to the output script,
+// the checksum is verified in the configured variant and the version must be of the network of the parser
+func (p *ZcoinParser) base58AddressToOutputScript(address string) ([]byte, error) {
+	var hash, version []byte
+	var err error
+	if p.hasSha256Checksum() {
+		hash, version, err = base58CheckDecodeSha256(address, p.Params.AddressMagicLen)
+	} else {
+		hash, version, err = base58.CheckDecode(address, p.Params.AddressMagicLen, p.Params.Base58CksumHasher)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(hash) != 20 {
+		return nil, errors.Errorf("Invalid address hash length %v", len(hash))
+	}
+	switch {
+	case bytes.Equal(version, p.Params.PubKeyHashAddrID):
+		script := append([]byte{txscript.OP_DUP, txscript.OP_HASH160, 20}, hash...)
+		return append(script, txscript.OP_EQUALVERIFY, txscript.OP_CHECKSIG), nil
+	case bytes.Equal(version, p.Params.ScriptHashAddrID):
+		script := append([]byte{txscript.OP_HASH160, 20}, hash...)
+		return append(script, txscript.OP_EQUAL), nil
+	}
+	return nil, errors.Errorf("Address version %x is not of the network", version)
+}
+
+// GetAddrDescFromAddress returns internal address representation of given address
+// the bech32 addresses with the configured prefix and the taproot addresses (if enabled) are decoded by the parser,
+// other addresses by the base implementation using the configured base58 checksum, the privacy pseudo addresses
+// are converted to the one byte descriptor of their opcode, which is not searchable
+func (p *ZcoinParser) GetAddrDescFromAddress(address string) (bchain.AddressDescriptor, error) {
+	if op, ok := pseudoAddressOp(address); ok {
+		return bchain.AddressDescriptor{op}, nil
+	}
+	return p.addrDescFromAddress(address, p.Bech32HRP())
+}
+
+// addrDescFromAddress converts the address, which is not a pseudo address, to the address descriptor,
+// hrp is the bech32 prefix of the enabled segwit addresses as returned by Bech32HRP
+func (p *ZcoinParser) addrDescFromAddress(address string, hrp string) (bchain.AddressDescriptor, error) {
+	if hrp != "" && strings.HasPrefix(strings.ToLower(address), hrp+"1") {
+		return p.witnessAddressToOutputScript(address)
+	}
+	if p.hasSha256Checksum() {
+		return p.base58AddressToOutputScript(address)
+	}
+	return p.BitcoinParser.GetAddrDescFromAddress(address)
+}
+
+// Bech32HRP returns the human readable part of the bech32 addresses if the segwit addresses are enabled
+// by the bech32_hrp or taproot options, otherwise empty string, Zcoin itself does not have bech32 addresses
+// and the prefix inherited by the chain params from Bitcoin is not used
+func (p *ZcoinParser) Bech32HRP() string {
+	if p.config.Bech32HRP == "" && !p.config.Taproot {
+		return ""
+	}
+	return p.bech32HRP()
+}
+
+// bech32HRP returns the human readable part of the bech32 addresses, the configured one or the one of the chain params
+func (p *ZcoinParser) bech32HRP() string {
+	if p.config.Bech32HRP != "" {
+		return p.config.Bech32HRP
+	}
+	return p.Params.Bech32HRPSegwit
+}
+
+// isTaprootScript returns true for the segwit v1 output with 32 bytes witness program (P2TR)
+func isTaprootScript(script []byte) bool {
+	return len(script) == 34 && script[0] == txscript.OP_1 && script[1] == 32
+}
+
+// outputScriptToAddresses encodes the witness v0 outputs with the configured bech32 prefix, the taproot outputs
+// (if enabled) as bech32m addresses and the P2PKH and P2SH outputs with the sha256 checksum (if configured),
+// other outputs are processed by the bitcoin implementation
+func (p *ZcoinParser) outputScriptToAddresses(script []byte) ([]string, bool, error) {
+	if p.hasSha256Checksum() {
+		if len(script) == 25 && script[0] == txscript.OP_DUP && script[1] == txscript.OP_HASH160 && script[2] == 20 &&
+			script[23] == txscript.OP_EQUALVERIFY && script[24] == txscript.OP_CHECKSIG {
+			return []string{base58CheckEncodeSha256(script[3:23], p.Params.PubKeyHashAddrID)}, true, nil
+		}
+		if len(script) == 23 && script[0] == txscript.OP_HASH160 && script[1] == 20 && script[22] == txscript.OP_EQUAL {
+			return []string{base58CheckEncodeSha256(script[2:22], p.Params.ScriptHashAddrID)}, true, nil
+		}
+	}
+	if p.config.Bech32HRP != "" && len(script) >= 2 && script[0] == 0 && int(script[1]) == len(script)-2 &&
+		(script[1] == 20 || script[1] == 32) {
+		data, err := convertBits(script[2:], 8, 5, true)
+		if err != nil {
+			return nil, false, err
+		}
+		return []string{bech32Encode(p.config.Bech32HRP, append([]byte{0}, data...))}, true, nil
+	}
+	if p.config.Taproot && isTaprootScript(script) && p.bech32HRP() != "" {
+		data, err := convertBits(script[2:], 8, 5, true)
+		if err != nil {
+			return nil, false, err
+		}
+		return []string{bech32mEncode(p.bech32HRP(), append([]byte{1}, data...))}, true, nil
+	}
+	return p.BitcoinOutputScriptToAddressesFunc(script)
+}
+
+// witnessAddressToOutputScript converts the bech32 encoded witness v0 address or the bech32m encoded
+// witness v1 (taproot) address, if taproot is enabled, to the output script
+func (p *ZcoinParser) witnessAddressToOutputScript(address string) ([]byte, error) {
+	hrp, data, bech32m, err := bech32Decode(address)
+	if err != nil {
+		return nil, err
+	}
+	if hrp != p.bech32HRP() {
+		return nil, errors.Errorf("Invalid address prefix %v", hrp)
+	}
+	if len(data) < 1 {
+		return nil, errors.New("Missing witness version")
+	}
+	version := data[0]
+	switch {
+	case version == 0 && !bech32m:
+	case version == 1 && bech32m && p.config.Taproot:
+	case version > 1 || (version == 1 && !p.config.Taproot):
+		return nil, errors.New("Unsupported witness version")
+	default:
+		return nil, errors.New("Invalid checksum variant for the witness version")
+	}
+	program, err := convertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return nil, err
+	}
+	if version == 1 {
+		if len(program) != 32 {
+			return nil, errors.Errorf("Invalid taproot program length %v", len(program))
+		}
+		return append([]byte{txscript.OP_1, 32}, program...), nil
+	}
+	if len(program) != 20 && len(program) != 32 {
+		return nil, errors.Errorf("Invalid witness program length %v", len(program))
+	}
+	return append([]byte{0, byte(len(program))}, program...), nil
+}
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var bech32Generator = []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+func bech32Polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		b := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (b>>uint(i))&1 == 1 {
+				chk ^= bech32Generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HrpExpand(hrp string) []byte {
+	r := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		r = append(r, hrp[i]>>5)
+	}
+	r = append(r, 0)
+	for i := 0; i < len(hrp); i++ {
+		r = append(r, hrp[i]&31)
+	}
+	return r
+}
+
+// checksum constants of the bech32 (BIP173) and bech32m (BIP350) encodings
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+// bech32Encode encodes the 5 bit data with the human readable part hrp
+func bech32Encode(hrp string, data []byte) string {
+	return bech32EncodeConst(hrp, data, bech32Const)
+}
+
+// bech32mEncode encodes the 5 bit data with the human readable part hrp using the bech32m checksum
+func bech32mEncode(hrp string, data []byte) string {
+	return bech32EncodeConst(hrp, data, bech32mConst)
+}
+
+func bech32EncodeConst(hrp string, data []byte, c uint32) string {
+	values := append(bech32HrpExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ c
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, d := range data {
+		sb.WriteByte(bech32Charset[d])
+	}
+	for i := 0; i < 6; i++ {
+		sb.WriteByte(bech32Charset[(polymod>>uint(5*(5-i)))&31])
+	}
+	return sb.String()
+}
+
+// bech32Decode decodes the bech32 or bech32m string to the human readable part and the 5 bit data without the checksum,
+// bech32m is true if the string has the bech32m checksum
+func bech32Decode(s string) (string, []byte, bool, error) {
+	if len(s) > 90 || (strings.ToLower(s) != s && strings.ToUpper(s) != s) {
+		return "", nil, false, errors.New("Invalid bech32 string")
+	}
+	s = strings.ToLower(s)
+	pos := strings.LastIndexByte(s, '1')
+	if pos < 1 || pos+7 > len(s) {
+		return "", nil, false, errors.New("Invalid bech32 separator position")
+	}
+	hrp := s[:pos]
+	data := make([]byte, 0, len(s)-pos-1)
+	for i := pos + 1; i < len(s); i++ {
+		d := strings.IndexByte(bech32Charset, s[i])
+		if d < 0 {
+			return "", nil, false, errors.New("Invalid bech32 character")
+		}
+		data = append(data, byte(d))
+	}
+	var bech32m bool
+	switch bech32Polymod(append(bech32HrpExpand(hrp), data...)) {
+	case bech32Const:
+	case bech32mConst:
+		bech32m = true
+	default:
+		return "", nil, false, errors.New("Invalid bech32 checksum")
+	}
+	return hrp, data[:len(data)-6], bech32m, nil
+}
+
+// convertBits regroups the data from fromBits to toBits per byte
+func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	var acc uint32
+	var bits uint
+	maxv := uint32(1)<<toBits - 1
+	r := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	for _, v := range data {
+		if uint32(v)>>fromBits != 0 {
+			return nil, errors.New("Invalid data range")
+		}
+		acc = acc<<fromBits | uint32(v)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			r = append(r, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			r = append(r, byte(acc<<(toBits-bits)&maxv))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxv != 0 {
+		return nil, errors.New("Invalid padding")
+	}
+	return r, nil
+}
+
+// address types returned by IsValidAddress
+const (
+	AddressTypeP2PKH  = "p2pkh"
+	AddressTypeP2SH   = "p2sh"
+	AddressTypeBech32 = "bech32"
+	AddressTypeP2TR   = "p2tr"
+)
+
+// pseudoAddressNames are the names of the privacy pseudo addresses, without the network suffix
+var pseudoAddressNames = []string{"Zeromint", "Zerospend", "Sigmamint", "Sigmaspend"}
+
+// pseudoAddressOps are the opcodes of the pseudo address descriptors, in the order of pseudoAddressNames
+var pseudoAddressOps = []byte{OpZeroCoinMint, OpZeroCoinSpend, OpSigmaMint, OpSigmaSpend}
+
+// pseudoAddressOp returns the opcode of the privacy pseudo address, with or without a network suffix
+func pseudoAddressOp(address string) (byte, bool) {
+	for i, n := range pseudoAddressNames {
+		if address == n || strings.HasPrefix(address, n+"-") {
+			return pseudoAddressOps[i], true
+		}
+	}
+	return 0, false
+}
+
+// isPseudoAddress returns true if the address is a privacy pseudo address, with or without a network suffix
+func isPseudoAddress(address string) bool {
+	_, ok := pseudoAddressOp(address)
+	return ok
+}
+
+// IsValidAddress returns true and the type of the address (p2pkh, p2sh, bech32 or p2tr) if the address is a valid address
+// of the network of the parser, the privacy pseudo addresses are not valid addresses, the bech32 addresses are valid
+// only if the bech32 prefix is configured (bech32_hrp option) and the taproot addresses if taproot is enabled
+// (taproot option), the version of the base58 addresses must be of the network of the parser
+func (p *ZcoinParser) IsValidAddress(address string) (bool, string) {
+	return p.isValidAddress(address, p.Bech32HRP())
+}
+
+func (p *ZcoinParser) isValidAddress(address string, hrp string) (bool, string) {
+	if isPseudoAddress(address) {
+		return false, ""
+	}
+	var ad []byte
+	var err error
+	if hrp != "" && strings.HasPrefix(strings.ToLower(address), hrp+"1") {
+		ad, err = p.witnessAddressToOutputScript(address)
+	} else {
+		// btcutil accepts the versions of all registered networks, the version is checked by the parser
+		ad, err = p.base58AddressToOutputScript(address)
+	}
+	if err != nil {
+		return false, ""
+	}
+	switch {
+	case len(ad) == 25 && ad[0] == txscript.OP_DUP && ad[1] == txscript.OP_HASH160 && ad[2] == 20 &&
+		ad[23] == txscript.OP_EQUALVERIFY && ad[24] == txscript.OP_CHECKSIG:
+		return true, AddressTypeP2PKH
+	case len(ad) == 23 && ad[0] == txscript.OP_HASH160 && ad[1] == 20 && ad[22] == txscript.OP_EQUAL:
+		return true, AddressTypeP2SH
+	case p.config.Bech32HRP != "" && len(ad) >= 2 && ad[0] == 0 && int(ad[1]) == len(ad)-2:
+		return true, AddressTypeBech32
+	case p.config.Taproot && isTaprootScript(ad):
+		return true, AddressTypeP2TR
+	}
+	return false, ""
+}
+
+// AddressValidation is the result of the validation of one address by ValidateAddresses
+type AddressValidation struct {
+	Address string
+	Valid   bool
+	Type    string
+}
+
+// ValidateAddresses validates the addresses in the same way as IsValidAddress and returns the results in the order
+// of the addresses, the bech32 prefix is resolved once for the whole batch and repeated addresses are validated only once
+func (p *ZcoinParser) ValidateAddresses(addrs []string) []AddressValidation {
+	r := make([]AddressValidation, len(addrs))
+	hrp := p.Bech32HRP()
+	seen := make(map[string]int, len(addrs))
+	for i, a := range addrs {
+		if j, found := seen[a]; found {
+			r[i] = r[j]
+			continue
+		}
+		seen[a] = i
+		valid, t := p.isValidAddress(a, hrp)
+		r[i] = AddressValidation{Address: a, Valid: valid, Type: t}
+	}
+	return r
+}
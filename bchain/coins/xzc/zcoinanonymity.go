@@ -0,0 +1,70 @@
+package xzc
+
+import (
+	"blockbook/bchain"
+	"math/big"
+
+	"github.com/juju/errors"
+)
+
+// MintDenomination identifies the pool of the mints, the type of the mint ("zerocoinmint" or "sigmamint")
+// and the denomination in satoshis
+type MintDenomination struct {
+	Type  string
+	Value int64
+}
+
+// MintTally holds the running number of privacy mints per pool, it is maintained by the caller during the sync
+// by AddEvents of the connected blocks
+type MintTally map[MintDenomination]int
+
+// AddEvents adds the mints of the privacy events (as returned by GetPrivacyEvents) to the tally
+func (t MintTally) AddEvents(events []bchain.PrivacyEvent) {
+	for i := range events {
+		e := &events[i]
+		if e.Type != "zerocoinmint" && e.Type != "sigmamint" {
+			continue
+		}
+		v, ok := new(big.Int).SetString(e.Value, 10)
+		if !ok || !v.IsInt64() {
+			continue
+		}
+		t[MintDenomination{Type: e.Type, Value: v.Int64()}]++
+	}
+}
+
+// AnonymitySetSize estimates the anonymity set of the privacy spend input as the number of the mints
+// of the same type and denomination in the tally, the tally must contain the mints up to the block before the spend
+// the denomination of the Zerocoin spends is decoded from the spend script, the denomination of the Sigma spends
+// is taken from the denomination parameter or, if it is nil, decoded from the spend script, the batched Sigma spends
+// with multiple denominations are not supported
+// the estimate is an upper bound of the real anonymity set, the mints are not split into the accumulators
+// (Zerocoin) or the coin groups (Sigma), from which the spends actually draw, and the mints which are not yet
+// usable for the spends are counted too, the spent mints are not subtracted as they stay in the set
+func (p *ZcoinParser) AnonymitySetSize(vin *bchain.Vin, denomination *big.Int, tally MintTally) (int, error) {
+	script := vinScript(vin)
+	var t string
+	switch scriptOp(script) {
+	case OpZeroCoinSpend:
+		d, err := zerocoinSpendDenomination(script)
+		if err != nil {
+			return 0, err
+		}
+		t, denomination = "zerocoinmint", d
+	case OpSigmaSpend:
+		if denomination == nil {
+			d, err := GetSigmaSpendDenomination(script)
+			if err != nil {
+				return 0, errors.Annotatef(err, "Sigma spend denomination not specified")
+			}
+			denomination = big.NewInt(d)
+		}
+		t = "sigmamint"
+	default:
+		return 0, errors.New("Not a privacy spend")
+	}
+	if !denomination.IsInt64() || denomination.Sign() <= 0 {
+		return 0, errors.Errorf("Invalid denomination %v", denomination)
+	}
+	return tally[MintDenomination{Type: t, Value: denomination.Int64()}], nil
+}
@@ -0,0 +1,79 @@
+package xzc
+
+import (
+	"blockbook/bchain"
+	"encoding/json"
+)
+
+type blockDump struct {
+	bchain.BlockHeader
+	HeaderTime int64    `json:"headerTime,omitempty"`
+	Txs        []txDump `json:"tx"`
+}
+
+type txDump struct {
+	bchain.Tx
+	Vin           []vinDump  `json:"vin"`
+	Vout          []voutDump `json:"vout"`
+	Privacy       bool       `json:"privacy,omitempty"`
+	FullyShielded bool       `json:"fullyShielded,omitempty"`
+	Minted        string     `json:"minted,omitempty"`
+	Spent         string     `json:"spent,omitempty"`
+}
+
+type vinDump struct {
+	bchain.Vin
+	PrivacySpend *PrivacySpend `json:"privacySpend,omitempty"`
+}
+
+type voutDump struct {
+	ValueSat     string              `json:"valueSat"`
+	N            uint32              `json:"n"`
+	ScriptPubKey bchain.ScriptPubKey `json:"scriptPubKey"`
+	Mint         bool                `json:"mint,omitempty"`
+}
+
+// DumpBlock returns the parsed block as indented JSON including the decoded privacy data, for debugging
+// the amounts are formatted as strings in satoshis
+func (p *ZcoinParser) DumpBlock(b *bchain.Block) (string, error) {
+	bd := blockDump{
+		BlockHeader: b.BlockHeader,
+		HeaderTime:  b.HeaderTime,
+		Txs:         make([]txDump, len(b.Txs)),
+	}
+	for i := range b.Txs {
+		tx := &b.Txs[i]
+		td := &bd.Txs[i]
+		td.Tx = *tx
+		td.Vin = make([]vinDump, len(tx.Vin))
+		for j := range tx.Vin {
+			ps, err := p.ParsePrivacySpend(&tx.Vin[j])
+			if err != nil {
+				return "", err
+			}
+			td.Vin[j] = vinDump{Vin: tx.Vin[j], PrivacySpend: ps}
+		}
+		td.Vout = make([]voutDump, len(tx.Vout))
+		for j := range tx.Vout {
+			vout := &tx.Vout[j]
+			td.Vout[j] = voutDump{
+				ValueSat:     vout.ValueSat.String(),
+				N:            vout.N,
+				ScriptPubKey: vout.ScriptPubKey,
+				Mint:         isMintScript(vout.ScriptPubKey.Hex),
+			}
+		}
+		if p.IsPrivacyTx(tx) {
+			minted, spent := p.GetShieldedFlows(tx)
+			td.Privacy = true
+			td.FullyShielded = p.IsFullyShieldedTx(tx)
+			td.Minted = minted.String()
+			td.Spent = spent.String()
+		}
+	}
+	d, err := json.MarshalIndent(&bd, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(d), nil
+}
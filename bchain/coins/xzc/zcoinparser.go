@@ -3,14 +3,22 @@ package xzc
 import (
 	"blockbook/bchain"
 	"blockbook/bchain/coins/btc"
+	"bufio"
 	"bytes"
 	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"io"
+	"math/big"
+	"sort"
+	"strconv"
 
+	"github.com/golang/glog"
+	"github.com/juju/errors"
 	"github.com/martinboehm/btcd/chaincfg/chainhash"
 	"github.com/martinboehm/btcd/wire"
 	"github.com/martinboehm/btcutil/chaincfg"
+	"github.com/martinboehm/btcutil/txscript"
 )
 
 const (
@@ -23,11 +31,27 @@ const (
 	TestnetMagic wire.BitcoinNet = 0xcffcbeea
 	RegtestMagic wire.BitcoinNet = 0xfabfb5da
 
+	// GenesisBlockTime is the time of the Zcoin genesis block, used if genesis_block_time is not configured
 	GenesisBlockTime       = 1414776286
 	SwitchToMTPBlockHeader = 1544443200
 	MTPL                   = 64
 
+	// FinalityConfirmations is the default number of confirmations after which a Zcoin transaction is considered final,
+	// it is higher than in Bitcoin to account for the depth of proof-of-stake reorgs
+	FinalityConfirmations = 12
+
+	// MaxMoney is the maximum money supply of Zcoin in satoshis (21.4 million coins), used if max_money is not configured
+	MaxMoney = 21400000 * 100000000
+
 	SpendTxID = "0000000000000000000000000000000000000000000000000000000000000000"
+	// CoinbaseVout is the prevout index of the coinbase input in the serialized transaction
+	CoinbaseVout = wire.MaxPrevOutIndex
+
+	// DefaultConsolidationSpends is the default number of privacy spend inputs a consolidation transaction must exceed
+	DefaultConsolidationSpends = 5
+
+	// BlockTimeSourceCoinbase takes the block time from the timestamp pushed to the coinbase script
+	BlockTimeSourceCoinbase = "coinbase"
 )
 
 var (
@@ -58,22 +82,120 @@ func init() {
 	RegtestParams.Net = RegtestMagic
 }
 
+// ErrNotProofOfStake is returned if proof-of-stake data are requested for a proof-of-work block
+var ErrNotProofOfStake = errors.New("Block is not proof-of-stake")
+
+// TxCountReader reads the number of transactions in the block
+type TxCountReader func(r io.Reader) (uint64, error)
+
+// ParseProgressFunc is called after a block at the height with txs transactions is parsed
+type ParseProgressFunc func(height uint32, txs int)
+
 // ZcoinParser handle
 type ZcoinParser struct {
 	*btc.BitcoinParser
+	config *Configuration
+	// TxCountReader can be replaced by forks which encode the number of transactions in the block differently
+	TxCountReader TxCountReader
+	// ParseProgress, if set, is called by ParseBlockAtHeight after each parsed block, for example to report
+	// the progress of a reindex, it is not set by default
+	ParseProgress                      ParseProgressFunc
+	BitcoinOutputScriptToAddressesFunc btc.OutputScriptToAddressesFunc
+	segwitTxVersions                   map[int32]struct{}
+	commentTxVersions                  map[int32]struct{}
+	genesis                            GenesisParams
+	pseudoAddressSuffix                string
+	finalityConfirmations              int
+}
+
+// GenesisParams contains the parameters of the genesis block of the chain, forks override them in btc.Configuration
+type GenesisParams struct {
+	Time int64
+	// Hash and CoinbaseTxid are not checked if empty
+	Hash         string
+	CoinbaseTxid string
 }
 
-// NewZcoinParser returns new ZcoinParser instance
+// NewZcoinParser returns new ZcoinParser instance with default Zcoin specific configuration
 func NewZcoinParser(params *chaincfg.Params, c *btc.Configuration) *ZcoinParser {
-	return &ZcoinParser{
+	return NewZcoinParserWithConfig(params, c, &Configuration{})
+}
+
+// NewZcoinParserWithConfig returns new ZcoinParser instance using the Zcoin specific configuration zc
+func NewZcoinParserWithConfig(params *chaincfg.Params, c *btc.Configuration, zc *Configuration) *ZcoinParser {
+	p := &ZcoinParser{
 		BitcoinParser: btc.NewBitcoinParser(params, c),
+		config:        zc,
+		TxCountReader: readVarIntTxCount,
+		genesis: GenesisParams{
+			Time:         c.GenesisBlockTime,
+			Hash:         c.GenesisBlockHash,
+			CoinbaseTxid: c.GenesisCoinbaseTxid,
+		},
+		finalityConfirmations: c.FinalityConfirmations,
+	}
+	if p.finalityConfirmations <= 0 {
+		p.finalityConfirmations = FinalityConfirmations
+	}
+	if p.BaseParser.MaxMoney == nil {
+		p.BaseParser.MaxMoney = big.NewInt(MaxMoney)
+	}
+	if p.genesis.Time == 0 {
+		p.genesis.Time = GenesisBlockTime
+	}
+	if zc.PseudoAddressNetwork && params.Net != MainnetMagic {
+		p.pseudoAddressSuffix = "-" + networkName(params)
 	}
+	if len(zc.SegwitTxVersions) > 0 {
+		p.segwitTxVersions = make(map[int32]struct{}, len(zc.SegwitTxVersions))
+		for _, v := range zc.SegwitTxVersions {
+			p.segwitTxVersions[v] = struct{}{}
+		}
+	}
+	if len(zc.CommentTxVersions) > 0 {
+		p.commentTxVersions = make(map[int32]struct{}, len(zc.CommentTxVersions))
+		for _, v := range zc.CommentTxVersions {
+			p.commentTxVersions[v] = struct{}{}
+		}
+	}
+	if err := p.initAddressVariant(); err != nil {
+		glog.Error("Address variant not initialized: ", err)
+	}
+	return p
+}
+
+// FinalityConfirmations returns number of confirmations after which a transaction is considered final,
+// the finality_confirmations option or the Zcoin default
+func (p *ZcoinParser) FinalityConfirmations() int {
+	return p.finalityConfirmations
+}
+
+// Genesis returns the genesis block parameters of the chain
+func (p *ZcoinParser) Genesis() GenesisParams {
+	return p.genesis
+}
+
+// IsGenesisBlock returns true if the hash is the configured genesis block hash
+func (p *ZcoinParser) IsGenesisBlock(hash string) bool {
+	return p.genesis.Hash != "" && hash == p.genesis.Hash
+}
+
+// IsGenesisCoinbase returns true if the txid is the configured coinbase transaction of the genesis block,
+// the output of the genesis coinbase is not spendable
+func (p *ZcoinParser) IsGenesisCoinbase(txid string) bool {
+	return p.genesis.CoinbaseTxid != "" && txid == p.genesis.CoinbaseTxid
+}
+
+// readVarIntTxCount reads the number of transactions encoded as the standard Bitcoin varint
+func readVarIntTxCount(r io.Reader) (uint64, error) {
+	return wire.ReadVarInt(r, 0)
 }
 
 // GetChainParams contains network parameters for the main Zcoin network,
 // the regression test Zcoin network, the test Zcoin network and
 // the simulation test Zcoin network, in this order
-func GetChainParams(chain string) *chaincfg.Params {
+// an error is returned if the networks cannot be registered, for example if another coin registered the same magic
+func GetChainParams(chain string) (*chaincfg.Params, error) {
 	if !chaincfg.IsRegistered(&MainNetParams) {
 		err := chaincfg.Register(&MainNetParams)
 		if err == nil {
@@ -83,38 +205,101 @@ func GetChainParams(chain string) *chaincfg.Params {
 			err = chaincfg.Register(&RegtestParams)
 		}
 		if err != nil {
-			panic(err)
+			return nil, errors.Annotatef(err, "Zcoin chain params registration")
 		}
 	}
 	switch chain {
 	case "test":
-		return &TestNetParams
+		return &TestNetParams, nil
 	case "regtest":
-		return &RegtestParams
+		return &RegtestParams, nil
 	default:
-		return &MainNetParams
+		return &MainNetParams, nil
+	}
+}
+
+// networkName returns the short name of the network used in the privacy pseudo addresses
+func networkName(params *chaincfg.Params) string {
+	switch params.Net {
+	case TestnetMagic:
+		return "test"
+	case RegtestMagic:
+		return "regtest"
 	}
+	return params.Name
 }
 
 // GetAddressesFromAddrDesc returns addresses for given address descriptor with flag if the addresses are searchable
+// the privacy pseudo addresses have the network suffix if configured
 func (p *ZcoinParser) GetAddressesFromAddrDesc(addrDesc bchain.AddressDescriptor) ([]string, bool, error) {
 
 	if len(addrDesc) > 0 {
 		switch addrDesc[0] {
 		case OpZeroCoinMint:
-			return []string{"Zeromint"}, false, nil
+			return []string{"Zeromint" + p.pseudoAddressSuffix}, false, nil
 		case OpZeroCoinSpend:
-			return []string{"Zerospend"}, false, nil
+			return []string{"Zerospend" + p.pseudoAddressSuffix}, false, nil
 		case OpSigmaMint:
-			return []string{"Sigmamint"}, false, nil
+			return []string{"Sigmamint" + p.pseudoAddressSuffix}, false, nil
 		case OpSigmaSpend:
-			return []string{"Sigmaspend"}, false, nil
+			return []string{"Sigmaspend" + p.pseudoAddressSuffix}, false, nil
 		}
 	}
 
 	return p.OutputScriptToAddressesFunc(addrDesc)
 }
 
+// GetPseudoAddressType returns the type of the privacy operation of the pseudo address descriptor
+// (zerocoinmint, zerocoinspend, sigmamint or sigmaspend), empty string for other descriptors
+func (p *ZcoinParser) GetPseudoAddressType(addrDesc bchain.AddressDescriptor) string {
+	if len(addrDesc) > 0 {
+		switch addrDesc[0] {
+		case OpZeroCoinMint:
+			return "zerocoinmint"
+		case OpZeroCoinSpend:
+			return "zerocoinspend"
+		case OpSigmaMint:
+			return "sigmamint"
+		case OpSigmaSpend:
+			return "sigmaspend"
+		}
+	}
+	return ""
+}
+
+// GetPrivacySpendAddrDesc returns the spend opcode as pseudo address descriptor of Zerocoin and Sigma spend inputs
+// the descriptor distinguishes the spends from coinbase inputs in the index
+func (p *ZcoinParser) GetPrivacySpendAddrDesc(vin *bchain.Vin) bchain.AddressDescriptor {
+	op := scriptOp(vinScript(vin))
+	if op == OpZeroCoinSpend || op == OpSigmaSpend {
+		return bchain.AddressDescriptor{byte(op)}
+	}
+	return nil
+}
+
+// GetPrivacySpendSerial returns the serial of the Zerocoin or Sigma spend input, empty string if it cannot be decoded
+func (p *ZcoinParser) GetPrivacySpendSerial(vin *bchain.Vin) string {
+	ps, err := p.ParsePrivacySpend(vin)
+	if err != nil || ps == nil {
+		return ""
+	}
+	return ps.Serial
+}
+
+// IsPrivacyMintAddrDesc returns true for the Zerocoin and Sigma mint outputs
+func (p *ZcoinParser) IsPrivacyMintAddrDesc(addrDesc bchain.AddressDescriptor) bool {
+	return len(addrDesc) > 0 && (addrDesc[0] == OpZeroCoinMint || addrDesc[0] == OpSigmaMint)
+}
+
+// IsAddrDescIndexable returns true if AddressDescriptor should be added to index
+// the pseudo address descriptors of privacy spends are not indexed
+func (p *ZcoinParser) IsAddrDescIndexable(addrDesc bchain.AddressDescriptor) bool {
+	if len(addrDesc) > 0 && (addrDesc[0] == OpZeroCoinSpend || addrDesc[0] == OpSigmaSpend) {
+		return false
+	}
+	return p.BitcoinParser.IsAddrDescIndexable(addrDesc)
+}
+
 // PackTx packs transaction to byte array using protobuf
 func (p *ZcoinParser) PackTx(tx *bchain.Tx, height uint32, blockTime int64) ([]byte, error) {
 	return p.BaseParser.PackTx(tx, height, blockTime)
@@ -127,6 +312,9 @@ func (p *ZcoinParser) UnpackTx(buf []byte) (*bchain.Tx, uint32, error) {
 
 // ParseBlock parses raw block to our Block struct
 func (p *ZcoinParser) ParseBlock(b []byte) (*bchain.Block, error) {
+	if len(b) > p.MaxBlockSize() {
+		return nil, errors.Errorf("Block size %v exceeds maximum block size %v", len(b), p.MaxBlockSize())
+	}
 	reader := bytes.NewReader(b)
 
 	// parse standard block header first
@@ -136,72 +324,322 @@ func (p *ZcoinParser) ParseBlock(b []byte) (*bchain.Block, error) {
 	}
 
 	// then MTP header
-	if isMTP(header) {
-		mtpHeader := MTPBlockHeader{}
-		mtpHashData := MTPHashData{}
-
-		// header
-		err = binary.Read(reader, binary.LittleEndian, &mtpHeader)
-		if err != nil {
+	if p.isMTP(header) {
+		if err = skipMTPData(reader); err != nil {
 			return nil, err
 		}
+	}
+
+	// parse txs
+	ntx, err := p.TxCountReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	if ntx > uint64(p.MaxBlockTxCount()) {
+		return nil, errors.Errorf("Number of transactions %v exceeds maximum %v", ntx, p.MaxBlockTxCount())
+	}
+
+	txs := make([]bchain.Tx, ntx)
+	enc := p.txEncoding(header)
+	var txHashes []chainhash.Hash
+	if p.config.StrictMerkleRoot {
+		txHashes = make([]chainhash.Hash, ntx)
+	}
+	var spendProofs map[string]bchain.SpendProofSize
 
-		// hash data
-		err = binary.Read(reader, binary.LittleEndian, &mtpHashData)
+	for i := uint64(0); i < ntx; i++ {
+		if err := p.checkTxOutCount(b[len(b)-reader.Len():], enc); err != nil {
+			return nil, errors.Annotatef(err, "Tx %v", i)
+		}
+		tx, err := p.decodeTx(reader, enc)
 		if err != nil {
+			if c := errors.Cause(err); c == io.EOF || c == io.ErrUnexpectedEOF {
+				return nil, errors.Errorf("Short block, tx %v of %v: %v", i, ntx, err)
+			}
 			return nil, err
 		}
 
-		// proof
-		for i := 0; i < MTPL*3; i++ {
-			var numberProofBlocks uint8
+		comment, n, err := p.readTxComment(reader, tx.Version)
+		if err != nil {
+			if c := errors.Cause(err); c == io.EOF || c == io.ErrUnexpectedEOF {
+				return nil, errors.Errorf("Short block, tx %v of %v: %v", i, ntx, err)
+			}
+			return nil, errors.Annotatef(err, "Tx %v", i)
+		}
 
-			err = binary.Read(reader, binary.LittleEndian, &numberProofBlocks)
-			if err != nil {
-				return nil, err
+		// the txid of the commented transaction is the hash of the serialization including the comment,
+		// the same hash is the merkle leaf of the transaction
+		var hash chainhash.Hash
+		if txHashes != nil || n > 0 {
+			end := len(b) - reader.Len()
+			if hash, err = txHash(tx, b[end-n:end]); err != nil {
+				return nil, errors.Annotatef(err, "Tx %v", i)
+			}
+			if txHashes != nil {
+				txHashes[i] = hash
 			}
+		}
+
+		btx := p.TxFromMsgTx(tx, false)
+		if n > 0 {
+			btx.Txid = hash.String()
+		}
+		btc.SetTxSizes(&btx, tx)
+		setTxComment(&btx, comment, n)
 
-			for j := uint8(0); j < numberProofBlocks; j++ {
-				var mtpData [16]uint8
+		err = p.parseZcoinTx(&btx)
+		if err != nil {
+			return nil, err
+		}
 
-				err = binary.Read(reader, binary.LittleEndian, mtpData[:])
-				if err != nil {
-					return nil, err
+		if p.config.SpendWitness {
+			for j := range tx.TxIn {
+				if len(tx.TxIn[j].Witness) > 0 && isSpendScript(vinScript(&btx.Vin[j])) {
+					btx.Vin[j].Witness = tx.TxIn[j].Witness
 				}
 			}
 		}
+		if p.config.SpendProofStats {
+			spendProofs = p.addSpendProofSizes(spendProofs, &btx, tx)
+		}
+
+		btx.BlockPosition = int(i)
+		txs[i] = btx
+	}
+	// all data of the block must be consumed by the declared number of transactions
+	if reader.Len() > 0 {
+		return nil, errors.Errorf("Trailing data, %v bytes after %v transactions", reader.Len(), ntx)
+	}
+	if txHashes != nil {
+		if ntx == 0 {
+			return nil, errors.New("Block without transactions")
+		}
+		if root := merkleRoot(txHashes); root != header.MerkleRoot {
+			return nil, errors.Errorf("Merkle root mismatch, header %v, transactions %v", header.MerkleRoot, root)
+		}
 	}
 
-	// parse txs
-	ntx, err := wire.ReadVarInt(reader, 0)
+	block := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{
+			Size: len(b),
+			Time: header.Timestamp.Unix(),
+		},
+		Txs:       txs,
+		Version:   header.Version,
+		RawHeader: append([]byte(nil), b[:wire.MaxBlockHeaderPayload]...),
+	}
+	if p.config.BlockTimeSource == BlockTimeSourceCoinbase {
+		if t := p.coinbaseTime(txs); t != 0 && t != block.Time {
+			block.HeaderTime = block.Time
+			block.Time = t
+		}
+	}
+	if p.config.DenominationTally {
+		block.Denominations = p.BlockDenominations(block)
+	}
+	if p.config.IntraBlockSpends {
+		bchain.MarkIntraBlockSpends(block)
+	}
+	block.SpendProofs = spendProofs
+	return block, nil
+}
+
+// addSpendProofSizes adds the sizes of the proofs of the privacy spend inputs of the transaction, the size
+// of the scriptSig and of the serialized witness, to the sizes by the type of the spend, m is allocated if needed
+func (p *ZcoinParser) addSpendProofSizes(m map[string]bchain.SpendProofSize, btx *bchain.Tx, tx *wire.MsgTx) map[string]bchain.SpendProofSize {
+	for j := range btx.Vin {
+		script := vinScript(&btx.Vin[j])
+		if !isSpendScript(script) {
+			continue
+		}
+		in := tx.TxIn[j]
+		size := len(in.SignatureScript)
+		if len(in.Witness) > 0 {
+			size += in.Witness.SerializeSize()
+		}
+		if m == nil {
+			m = make(map[string]bchain.SpendProofSize)
+		}
+		t := p.GetPseudoAddressType(bchain.AddressDescriptor{byte(scriptOp(script))})
+		s := m[t]
+		s.Spends++
+		s.TotalSize += size
+		if size > s.MaxSize {
+			s.MaxSize = size
+		}
+		m[t] = s
+	}
+	return m
+}
+
+// checkCoinbaseHeight checks that the BIP34 height in the coinbase of the block is the height of the block,
+// the blocks below the BIP34 activation height of the chain params are not checked
+func (p *ZcoinParser) checkCoinbaseHeight(block *bchain.Block, height uint32) error {
+	if p.Params.BIP0034Height <= 0 || int64(height) < int64(p.Params.BIP0034Height) {
+		return nil
+	}
+	if len(block.Txs) == 0 {
+		return errors.New("Block without transactions")
+	}
+	h, err := p.CoinbaseHeight(&block.Txs[0])
 	if err != nil {
-		return nil, err
+		return errors.Annotatef(err, "Coinbase height")
+	}
+	if h != int64(height) {
+		return errors.Errorf("Coinbase height %v does not match block height %v", h, height)
 	}
+	return nil
+}
 
-	txs := make([]bchain.Tx, ntx)
+// txHash returns the hash of the transaction serialized without the witness data, the serialized comment
+// (empty for the transactions without comment) is part of the hashed data
+func txHash(tx *wire.MsgTx, comment []byte) (chainhash.Hash, error) {
+	if len(comment) == 0 {
+		return tx.TxHash(), nil
+	}
+	var buf bytes.Buffer
+	buf.Grow(tx.SerializeSizeStripped() + len(comment))
+	if err := tx.SerializeNoWitness(&buf); err != nil {
+		return chainhash.Hash{}, err
+	}
+	buf.Write(comment)
+	return chainhash.DoubleHashH(buf.Bytes()), nil
+}
 
-	for i := uint64(0); i < ntx; i++ {
-		tx := wire.MsgTx{}
+// merkleRoot computes the merkle root of the transaction hashes, the last hash of a level with odd number
+// of hashes is paired with itself, the root of a single transaction is its hash
+func merkleRoot(hashes []chainhash.Hash) chainhash.Hash {
+	level := append([]chainhash.Hash(nil), hashes...)
+	var buf [2 * chainhash.HashSize]byte
+	for len(level) > 1 {
+		if len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+		}
+		for i := 0; i < len(level)/2; i++ {
+			copy(buf[:chainhash.HashSize], level[2*i][:])
+			copy(buf[chainhash.HashSize:], level[2*i+1][:])
+			level[i] = chainhash.DoubleHashH(buf[:])
+		}
+		level = level[:len(level)/2]
+	}
+	return level[0]
+}
 
-		err := tx.BtcDecode(reader, 0, wire.WitnessEncoding)
-		if err != nil {
+// ParseBlockAtHeight parses the block like ParseBlock, sets its height and reports the progress to ParseProgress,
+// if StrictCoinbaseHeight is configured, the height of the block must match the height in its coinbase
+func (p *ZcoinParser) ParseBlockAtHeight(b []byte, height uint32) (*bchain.Block, error) {
+	block, err := p.ParseBlock(b)
+	if err != nil {
+		return nil, err
+	}
+	if p.config.StrictCoinbaseHeight {
+		if err := p.checkCoinbaseHeight(block, height); err != nil {
 			return nil, err
 		}
+	}
+	block.Height = height
+	if p.ParseProgress != nil {
+		p.ParseProgress(height, len(block.Txs))
+	}
+	return block, nil
+}
 
-		btx := p.TxFromMsgTx(&tx, false)
-
-		p.parseZcoinTx(&btx)
+// ParseBlockFile parses the blocks stored in the blk*.dat format of the backend, each block is preceded
+// by the network magic and the block size (both uint32 little endian), the data between the blocks
+// (for example zero padding of the preallocated files) are skipped, fn is called for each parsed block
+// the blocks do not have the height and hash set, the truncated last block ends the parsing without error
+func (p *ZcoinParser) ParseBlockFile(r io.Reader, net wire.BitcoinNet, fn func(*bchain.Block) error) error {
+	br := bufio.NewReader(r)
+	var magic [4]byte
+	binary.LittleEndian.PutUint32(magic[:], uint32(net))
+	for n := 0; ; n++ {
+		found, err := skipToMagic(br, magic)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return nil
+		}
+		var size uint32
+		if err = binary.Read(br, binary.LittleEndian, &size); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				glog.Warning("ParseBlockFile: truncated size of block ", n)
+				return nil
+			}
+			return err
+		}
+		if int64(size) > int64(p.MaxBlockSize()) {
+			return errors.Errorf("Block %v size %v exceeds maximum block size %v", n, size, p.MaxBlockSize())
+		}
+		b := make([]byte, size)
+		if _, err = io.ReadFull(br, b); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				glog.Warning("ParseBlockFile: truncated block ", n, ", size ", size)
+				return nil
+			}
+			return err
+		}
+		block, err := p.ParseBlock(b)
+		if err != nil {
+			return errors.Annotatef(err, "block %v", n)
+		}
+		if err = fn(block); err != nil {
+			return err
+		}
+	}
+}
 
-		txs[i] = btx
+// skipToMagic reads the data up to and including the network magic, returns false at the end of data
+func skipToMagic(br *bufio.Reader, magic [4]byte) (bool, error) {
+	matched := 0
+	for matched < len(magic) {
+		c, err := br.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return false, nil
+			}
+			return false, err
+		}
+		if c == magic[matched] {
+			matched++
+		} else if c == magic[0] {
+			matched = 1
+		} else {
+			matched = 0
+		}
 	}
+	return true, nil
+}
 
-	return &bchain.Block{
-		BlockHeader: bchain.BlockHeader{
-			Size: len(b),
-			Time: header.Timestamp.Unix(),
-		},
-		Txs: txs,
-	}, nil
+// coinbaseTime returns the timestamp pushed to the coinbase script after the block height or 0 if not found,
+// timestamps not later than the genesis block time are ignored
+func (p *ZcoinParser) coinbaseTime(txs []bchain.Tx) int64 {
+	if len(txs) == 0 || len(txs[0].Vin) == 0 {
+		return 0
+	}
+	script, err := hex.DecodeString(txs[0].Vin[0].Coinbase)
+	if err != nil {
+		return 0
+	}
+	// the first push is the block height (BIP34), the time is the first following 4 bytes push
+	// only the direct pushes (opcodes 0x01-0x4b) are expected in the coinbase script
+	first := true
+	for i := 0; i < len(script); {
+		l := int(script[i])
+		i++
+		if l == 0 || l > 0x4b || i+l > len(script) {
+			return 0
+		}
+		if !first && l == 4 {
+			t := int64(binary.LittleEndian.Uint32(script[i:]))
+			if t > p.genesis.Time {
+				return t
+			}
+		}
+		first = false
+		i += l
+	}
+	return 0
 }
 
 // ParseTxFromJson parses JSON message containing transaction and returns Tx struct
@@ -222,39 +660,1218 @@ func (p *ZcoinParser) ParseTxFromJson(msg json.RawMessage) (*bchain.Tx, error) {
 		vout.JsonValue = ""
 	}
 
-	p.parseZcoinTx(&tx)
+	if err = bchain.CheckMoneyRange(&tx, p.MaxMoneySat()); err != nil {
+		return nil, err
+	}
+
+	err = p.parseZcoinTx(&tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.config.SpendWitness || p.config.InputValues {
+		vins, err := parseJsonVinExtras(msg, &tx)
+		if err != nil {
+			return nil, err
+		}
+		if p.config.SpendWitness {
+			if err = setSpendWitness(vins, &tx); err != nil {
+				return nil, err
+			}
+		}
+		if p.config.InputValues {
+			if err = p.setInputValues(vins, &tx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	p.setTxSizesFromHex(&tx)
 
 	return &tx, nil
 }
 
+// TxWeight returns the weight of the transaction (BIP141), if the sizes of the transaction are not set,
+// they are computed from its hex including the comment, 0 if the hex is missing or cannot be decoded
+func (p *ZcoinParser) TxWeight(tx *bchain.Tx) int {
+	if tx.BaseSize > 0 {
+		return p.BaseParser.TxWeight(tx)
+	}
+	t := bchain.Tx{Txid: tx.Txid, Hex: tx.Hex}
+	p.setTxSizesFromHex(&t)
+	return p.BaseParser.TxWeight(&t)
+}
+
+// setTxSizesFromHex sets the sizes of the transaction decoded from its hex, the sizes are not set if the hex is missing
+// or cannot be decoded
+func (p *ZcoinParser) setTxSizesFromHex(tx *bchain.Tx) {
+	if tx.Hex == "" {
+		return
+	}
+	b, err := hex.DecodeString(tx.Hex)
+	if err != nil {
+		return
+	}
+	r := bytes.NewReader(b)
+	t, err := p.decodeTx(r, wire.WitnessEncoding)
+	if err != nil {
+		glog.V(1).Info("tx ", tx.Txid, ": sizes not set, ", err)
+		return
+	}
+	comment, n, err := p.readTxComment(r, t.Version)
+	if err != nil {
+		glog.V(1).Info("tx ", tx.Txid, ": sizes not set, ", err)
+		return
+	}
+	btc.SetTxSizes(tx, t)
+	setTxComment(tx, comment, n)
+}
+
+// readTxComment reads the comment serialized after the lock time of the transaction of the given version,
+// it returns the comment and the number of bytes read, nothing is read for versions without the comment
+func (p *ZcoinParser) readTxComment(r *bytes.Reader, version int32) (string, int, error) {
+	if _, has := p.commentTxVersions[version]; !has {
+		return "", 0, nil
+	}
+	l := r.Len()
+	c, err := wire.ReadVarBytes(r, 0, uint32(p.MaxBlockSize()), "comment")
+	if err != nil {
+		return "", 0, err
+	}
+	return string(c), l - r.Len(), nil
+}
+
+// setTxComment sets the comment of the transaction and adds its serialized size n to the sizes of the transaction,
+// the comment is not witness data
+func setTxComment(tx *bchain.Tx, comment string, n int) {
+	tx.Comment = comment
+	tx.BaseSize += n
+	tx.VSize += n
+}
+
+// jsonVinExtra contains the fields of the input of the JSON message which are not decoded to bchain.Vin
+type jsonVinExtra struct {
+	Value   json.Number `json:"value"`
+	Witness []string    `json:"txinwitness"`
+}
+
+// parseJsonVinExtras decodes the fields of the inputs of the JSON message which are not decoded to bchain.Vin,
+// all the fields are decoded in a single pass to a slice presized by the number of inputs of the already decoded tx,
+// spend transactions with hundreds of inputs are thus not decoded repeatedly
+func parseJsonVinExtras(msg json.RawMessage, tx *bchain.Tx) ([]jsonVinExtra, error) {
+	v := struct {
+		Vin []jsonVinExtra `json:"vin"`
+	}{
+		Vin: make([]jsonVinExtra, 0, len(tx.Vin)),
+	}
+	if err := json.Unmarshal(msg, &v); err != nil {
+		return nil, err
+	}
+	return v.Vin, nil
+}
+
+// setInputValues sets the values of the spent outputs of the transparent inputs from the value field
+// of the JSON inputs, the privacy spends and the inputs without the value are skipped
+func (p *ZcoinParser) setInputValues(vins []jsonVinExtra, tx *bchain.Tx) error {
+	for i := range vins {
+		if i >= len(tx.Vin) || vins[i].Value == "" || tx.Vin[i].Txid == "" || tx.Vin[i].Txid == SpendTxID {
+			continue
+		}
+		value, err := p.AmountToBigInt(vins[i].Value)
+		if err != nil {
+			return err
+		}
+		tx.Vin[i].ValueSat = &value
+	}
+	return nil
+}
+
+// setSpendWitness sets witness data of the privacy spend inputs from txinwitness field of the JSON inputs
+func setSpendWitness(vins []jsonVinExtra, tx *bchain.Tx) error {
+	for i := range vins {
+		if i >= len(tx.Vin) || len(vins[i].Witness) == 0 || !isSpendScript(vinScript(&tx.Vin[i])) {
+			continue
+		}
+		witness := make([][]byte, len(vins[i].Witness))
+		for j, h := range vins[i].Witness {
+			b, err := hex.DecodeString(h)
+			if err != nil {
+				return errors.Annotatef(err, "txid %v, vin %v witness", tx.Txid, i)
+			}
+			witness[j] = b
+		}
+		tx.Vin[i].Witness = witness
+	}
+	return nil
+}
+
 func (p *ZcoinParser) parseZcoinTx(tx *bchain.Tx) error {
 	for i := range tx.Vin {
 		vin := &tx.Vin[i]
 
+		if err := p.ValidateSpendInput(vin); err != nil {
+			if p.config.StrictSpendValidation {
+				return errors.Annotatef(err, "txid %v, vin %v", tx.Txid, i)
+			}
+			glog.Warning("txid ", tx.Txid, ", vin ", i, ": ", err)
+		}
+		// the Zerocoin spend is validated before the input is converted, the accumulator id is its original sequence
+		if scriptOp(vinScript(vin)) == OpZeroCoinSpend {
+			if err := p.ValidateZerocoinSpend(vin); err != nil {
+				if p.config.StrictSpendValidation {
+					return errors.Annotatef(err, "txid %v, vin %v", tx.Txid, i)
+				}
+				glog.Warning("txid ", tx.Txid, ", vin ", i, ": ", err)
+			}
+		}
+
 		// FIXME: right now we treat zerocoin spend vin as coinbase
 		// change this after blockbook support special type of vin
+		// the sequence is kept so that the original input can be reconstructed by spendTxIn
 		if vin.Txid == SpendTxID {
 			vin.Coinbase = vin.Txid
 			vin.Txid = ""
-			vin.Sequence = 0
 			vin.Vout = 0
 		}
+		// the spend input of a single input transaction is decoded from the raw block directly as coinbase input,
+		// its script is kept also as scriptSig so that it is available in the same place as for the other spends
+		if vin.Txid == "" && vin.ScriptSig.Hex == "" && isSpendScript(vin.Coinbase) {
+			vin.ScriptSig.Hex = vin.Coinbase
+		}
+		if p.config.CoinbaseSpendVout && vin.Txid == "" && isSpendScript(vinScript(vin)) {
+			vin.Vout = CoinbaseVout
+		}
+	}
+
+	for i := range tx.Vout {
+		vout := &tx.Vout[i]
+		script, err := p.GetAddrDescFromVout(vout)
+		if err != nil {
+			continue
+		}
+		if err = p.ValidateMintOutput(script); err != nil {
+			if p.config.StrictMintValidation {
+				return errors.Annotatef(err, "txid %v, vout %v", tx.Txid, vout.N)
+			}
+			glog.Warning("txid ", tx.Txid, ", vout ", vout.N, ": ", err)
+		}
 	}
 
 	return nil
 }
 
-func parseBlockHeader(r io.Reader) (*wire.BlockHeader, error) {
-	h := &wire.BlockHeader{}
-	err := h.Deserialize(r)
-	return h, err
+// SpendScriptSig returns the original scriptSig of the privacy spend input converted to coinbase input
+// or an empty string if the input is not a privacy spend
+func (p *ZcoinParser) SpendScriptSig(vin *bchain.Vin) string {
+	if s := vinScript(vin); isSpendScript(s) {
+		return s
+	}
+	return ""
+}
+
+// ValidateSpendInput checks that the classification of the input as a privacy spend is consistent with its prevout,
+// a privacy spend must not reference a real prevout and an input with the zero prevout txid must be a privacy spend,
+// otherwise a normal input could be indexed as a spend (or the other way round) and the referenced output would stay unspent
+func (p *ZcoinParser) ValidateSpendInput(vin *bchain.Vin) error {
+	spend := isSpendScript(vinScript(vin))
+	if spend && vin.Txid != "" && vin.Txid != SpendTxID {
+		return errors.Errorf("Privacy spend input references prevout %v:%v", vin.Txid, vin.Vout)
+	}
+	if !spend && vin.Txid == SpendTxID {
+		return errors.Errorf("Input with zero prevout txid is not a privacy spend, script %v", vinScript(vin))
+	}
+	return nil
 }
 
-func isMTP(h *wire.BlockHeader) bool {
-	epoch := h.Timestamp.Unix()
+// ValidateMintOutput checks that the script of a mint output does not decode also to a standard (P2PKH or P2SH) address
+// such an output is malformed, it is indexed only as a mint and the standard address would be hidden
+func (p *ZcoinParser) ValidateMintOutput(script []byte) error {
+	if len(script) == 0 || (script[0] != OpZeroCoinMint && script[0] != OpSigmaMint) {
+		return nil
+	}
+	sc, _, _, err := txscript.ExtractPkScriptAddrs(script[1:], p.Params)
+	if err != nil {
+		return nil
+	}
+	switch sc {
+	case txscript.PubKeyHashTy:
+		return errors.Errorf("Mint output script %x contains P2PKH address", script)
+	case txscript.ScriptHashTy:
+		return errors.Errorf("Mint output script %x contains P2SH address", script)
+	}
+	return nil
+}
+
+// IsPrivacyTx returns true if the transaction contains Zerocoin or Sigma mint or spend or is fully shielded
+func (p *ZcoinParser) IsPrivacyTx(tx *bchain.Tx) bool {
+	if p.IsFullyShieldedTx(tx) {
+		return true
+	}
+	for i := range tx.Vin {
+		if isSpendScript(vinScript(&tx.Vin[i])) {
+			return true
+		}
+	}
+	for i := range tx.Vout {
+		if isMintScript(tx.Vout[i].ScriptPubKey.Hex) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFullyShieldedTx returns true if the transaction has no transparent inputs, i.e. it has no inputs at all
+// and its outputs are funded only from the shielded pool, such transaction is not a coinbase
+func (p *ZcoinParser) IsFullyShieldedTx(tx *bchain.Tx) bool {
+	return len(tx.Vin) == 0 && len(tx.Vout) > 0
+}
+
+// lockTimeThreshold is the value of the lock time below which it is interpreted as a block height
+const lockTimeThreshold = 500000000
+
+// PrivacySpendUnlockHeight returns the height from which the transaction with privacy spends can be included in a block,
+// i.e. the lock time plus one, if the lock time is a block height and it is enforced (some input is not final),
+// otherwise 0, the lock times given as timestamps are not handled
+func (p *ZcoinParser) PrivacySpendUnlockHeight(tx *bchain.Tx) uint32 {
+	if tx.LockTime == 0 || tx.LockTime >= lockTimeThreshold || p.PrivacySpendCount(tx) == 0 {
+		return 0
+	}
+	for i := range tx.Vin {
+		if tx.Vin[i].Sequence != wire.MaxTxInSequenceNum {
+			return tx.LockTime + 1
+		}
+	}
+	return 0
+}
+
+// PrivacySpendCount returns the number of privacy spend inputs of the transaction
+func (p *ZcoinParser) PrivacySpendCount(tx *bchain.Tx) int {
+	n := 0
+	for i := range tx.Vin {
+		if isSpendScript(vinScript(&tx.Vin[i])) {
+			n++
+		}
+	}
+	return n
+}
+
+// IsConsolidationTx returns true if the transaction merges more privacy mints than the configured number
+// (consolidation_spends option) by spending them in its inputs
+func (p *ZcoinParser) IsConsolidationTx(tx *bchain.Tx) bool {
+	limit := p.config.ConsolidationSpends
+	if limit <= 0 {
+		limit = DefaultConsolidationSpends
+	}
+	return p.PrivacySpendCount(tx) > limit
+}
+
+// IsMigrationTx returns true if the transaction spends Zerocoin and mints Sigma, i.e. migrates the funds between the protocols
+func (p *ZcoinParser) IsMigrationTx(tx *bchain.Tx) bool {
+	var zerocoinSpend, sigmaMint bool
+	for i := range tx.Vin {
+		if scriptOp(vinScript(&tx.Vin[i])) == OpZeroCoinSpend {
+			zerocoinSpend = true
+			break
+		}
+	}
+	for i := range tx.Vout {
+		if scriptOp(tx.Vout[i].ScriptPubKey.Hex) == OpSigmaMint {
+			sigmaMint = true
+			break
+		}
+	}
+	return zerocoinSpend && sigmaMint
+}
+
+// TxPrivacyFlags returns the flags of the privacy mints and spends of the transaction, the transactions
+// with both spends and mints, including the migrations from Zerocoin to Sigma, are flagged as remints
+func (p *ZcoinParser) TxPrivacyFlags(tx *bchain.Tx) bchain.TxFlags {
+	var f bchain.TxFlags
+	for i := range tx.Vin {
+		if isSpendScript(vinScript(&tx.Vin[i])) {
+			f |= bchain.TxFlagPrivacySpend
+			break
+		}
+	}
+	for i := range tx.Vout {
+		if isMintScript(tx.Vout[i].ScriptPubKey.Hex) {
+			f |= bchain.TxFlagPrivacyMint
+			break
+		}
+	}
+	if f == bchain.TxFlagPrivacySpend|bchain.TxFlagPrivacyMint {
+		f |= bchain.TxFlagRemint
+	}
+	return f
+}
+
+// TransparentVolume returns the sum of the values of the transparent outputs of the block, the privacy mint outputs
+// and the outputs of the coinbase and coinstake transactions (the block rewards and the returned stake) are not counted
+func (p *ZcoinParser) TransparentVolume(block *bchain.Block) *big.Int {
+	volume := big.NewInt(0)
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		if isCoinbaseTx(tx) || bchain.IsCoinstakeTx(tx) {
+			continue
+		}
+		for j := range tx.Vout {
+			if !isMintScript(tx.Vout[j].ScriptPubKey.Hex) {
+				volume.Add(volume, &tx.Vout[j].ValueSat)
+			}
+		}
+	}
+	return volume
+}
+
+// isCoinbaseTx returns true if the transaction has an input without prevout which is not a privacy spend,
+// the privacy spends are also converted to the inputs without prevout
+func isCoinbaseTx(tx *bchain.Tx) bool {
+	for i := range tx.Vin {
+		if tx.Vin[i].Coinbase != "" && !isSpendScript(vinScript(&tx.Vin[i])) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetShieldedFlows returns the value minted to and spent from the Zerocoin and Sigma pools by the transaction
+// the spends are accounted by the denominations stored in the spend scripts, in the same way as in ComputeFee,
+// so that the fee paid from the spent denominations leaves the shielded pool, the value of the transaction outputs
+// is used only for fully shielded transactions and transactions with Sigma spends with undecodable denominations
+func (p *ZcoinParser) GetShieldedFlows(tx *bchain.Tx) (*big.Int, *big.Int) {
+	minted, spent := big.NewInt(0), big.NewInt(0)
+	byOutputs := p.IsFullyShieldedTx(tx)
+	for i := range tx.Vin {
+		script := vinScript(&tx.Vin[i])
+		switch scriptOp(script) {
+		case OpZeroCoinSpend:
+			if d, err := zerocoinSpendDenomination(script); err == nil {
+				spent.Add(spent, d)
+			}
+		case OpSigmaSpend:
+			ds, err := GetSigmaSpendDenominations(script)
+			if err != nil {
+				byOutputs = true
+			}
+			for _, d := range ds {
+				spent.Add(spent, big.NewInt(d))
+			}
+		}
+	}
+	if byOutputs {
+		spent.SetInt64(0)
+	}
+	for i := range tx.Vout {
+		vout := &tx.Vout[i]
+		if isMintScript(vout.ScriptPubKey.Hex) {
+			minted.Add(minted, &vout.ValueSat)
+		}
+		if byOutputs {
+			spent.Add(spent, &vout.ValueSat)
+		}
+	}
+	return minted, spent
+}
+
+// PrivacyScore returns the privacy indicator of the transaction in the range 0 to 100 computed as
+// 50 * shielded parts / all parts + 50 * shielded value / total value, rounded to the nearest integer, where the parts are the inputs and outputs, the privacy spend inputs
+// and mint outputs are shielded, the shielded value is the value minted and spent by GetShieldedFlows
+// and the total value is the shielded value plus the value of the transparent outputs not paid
+// from the shielded pool (the value of non-mint outputs minus the spent value, at least zero)
+// fully shielded transactions score 100, transactions without privacy operations score 0
+func (p *ZcoinParser) PrivacyScore(tx *bchain.Tx) int {
+	if p.IsFullyShieldedTx(tx) {
+		return 100
+	}
+	parts := len(tx.Vin) + len(tx.Vout)
+	shieldedParts := p.PrivacySpendCount(tx)
+	transparent := new(big.Int)
+	for i := range tx.Vout {
+		if isMintScript(tx.Vout[i].ScriptPubKey.Hex) {
+			shieldedParts++
+		} else {
+			transparent.Add(transparent, &tx.Vout[i].ValueSat)
+		}
+	}
+	if shieldedParts == 0 {
+		return 0
+	}
+	minted, spent := p.GetShieldedFlows(tx)
+	shielded := new(big.Int).Add(minted, spent)
+	transparent.Sub(transparent, spent)
+	if transparent.Sign() < 0 {
+		transparent.SetInt64(0)
+	}
+	score := 50 * float64(shieldedParts) / float64(parts)
+	if total := new(big.Int).Add(shielded, transparent); total.Sign() > 0 {
+		v, _ := new(big.Rat).SetFrac(shielded, total).Float64()
+		score += 50 * v
+	}
+	return int(score + 0.5)
+}
+
+// ComputeFee returns the fee of the transaction as the value of the transparent inputs plus the denominations
+// spent by the Zerocoin and Sigma spends minus the value of the transparent outputs and of the mints,
+// the fee of a coinbase transaction is 0, the fee of a fully shielded transaction cannot be computed
+func (p *ZcoinParser) ComputeFee(tx *bchain.Tx, prevouts []*big.Int) (*big.Int, error) {
+	if len(prevouts) != len(tx.Vin) {
+		return nil, errors.Errorf("%v values of spent outputs for %v inputs", len(prevouts), len(tx.Vin))
+	}
+	if p.IsFullyShieldedTx(tx) {
+		return nil, errors.New("Fee of fully shielded transaction is not known")
+	}
+	if isCoinbaseTx(tx) {
+		return big.NewInt(0), nil
+	}
+	fee := new(big.Int)
+	for i := range tx.Vin {
+		script := vinScript(&tx.Vin[i])
+		switch scriptOp(script) {
+		case OpZeroCoinSpend:
+			d, err := zerocoinSpendDenomination(script)
+			if err != nil {
+				return nil, errors.Annotatef(err, "input %v", i)
+			}
+			fee.Add(fee, d)
+		case OpSigmaSpend:
+			ds, err := GetSigmaSpendDenominations(script)
+			if err != nil {
+				return nil, errors.Annotatef(err, "input %v", i)
+			}
+			for _, d := range ds {
+				fee.Add(fee, big.NewInt(d))
+			}
+		default:
+			if prevouts[i] == nil {
+				return nil, errors.Errorf("Missing value of input %v", i)
+			}
+			fee.Add(fee, prevouts[i])
+		}
+	}
+	for i := range tx.Vout {
+		fee.Sub(fee, &tx.Vout[i].ValueSat)
+	}
+	if fee.Sign() < 0 {
+		return nil, errors.Errorf("Negative fee %v", fee)
+	}
+	return fee, nil
+}
+
+// zerocoinSpendDenomination returns the value of the Zerocoin spend in satoshis
+// the script contains the spend opcode, the length of the serialized CoinSpend pushed as a number and the CoinSpend itself,
+// which starts with the denomination in whole coins as int32 little endian
+func zerocoinSpendDenomination(script string) (*big.Int, error) {
+	b, err := hex.DecodeString(script)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 2 || b[1] < 1 || b[1] > 4 {
+		return nil, errors.New("Invalid Zerocoin spend script")
+	}
+	o := 2 + int(b[1])
+	if len(b) < o+4 {
+		return nil, errors.New("Zerocoin spend script too short")
+	}
+	d := int64(int32(binary.LittleEndian.Uint32(b[o:])))
+	if d <= 0 {
+		return nil, errors.Errorf("Invalid Zerocoin spend denomination %v", d)
+	}
+	return new(big.Int).Mul(big.NewInt(d), big.NewInt(100000000)), nil
+}
+
+// GetSigmaSpendDenominations returns the denominations in satoshis of the Sigma spend given by the hex script
+// the script contains the spend opcode, the length of the serialized CoinSpend pushed as a number and the CoinSpend,
+// which starts with the denomination as int64 little endian, the batched spends of the newer protocol versions,
+// which reference multiple denominations in one proof, have zero in place of the denomination followed by
+// the CompactSize number of the denominations and the denominations as int64 little endian
+func GetSigmaSpendDenominations(script string) ([]int64, error) {
+	b, err := hex.DecodeString(script)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 2 || b[0] != OpSigmaSpend || b[1] < 1 || b[1] > 4 {
+		return nil, errors.New("Invalid Sigma spend script")
+	}
+	r := bytes.NewReader(b[2+int(b[1]):])
+	var d int64
+	if err = binary.Read(r, binary.LittleEndian, &d); err != nil {
+		return nil, errors.Annotatef(err, "Sigma spend denomination")
+	}
+	if d != 0 {
+		if d < 0 {
+			return nil, errors.Errorf("Invalid Sigma spend denomination %v", d)
+		}
+		return []int64{d}, nil
+	}
+	n, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, errors.Annotatef(err, "Sigma spend number of denominations")
+	}
+	if n == 0 || n > uint64(r.Len()/8) {
+		return nil, errors.Errorf("Invalid Sigma spend number of denominations %v", n)
+	}
+	ds := make([]int64, n)
+	for i := range ds {
+		if err = binary.Read(r, binary.LittleEndian, &ds[i]); err != nil {
+			return nil, err
+		}
+		if ds[i] <= 0 {
+			return nil, errors.Errorf("Invalid Sigma spend denomination %v", ds[i])
+		}
+	}
+	return ds, nil
+}
+
+// decodeSigmaSpend returns the denominations in satoshis of the Sigma spend given by the hex script
+// and the rest of the serialized CoinSpend following the denominations
+func decodeSigmaSpend(script string) ([]int64, []byte, error) {
+	ds, err := GetSigmaSpendDenominations(script)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, _ := hex.DecodeString(script)
+	// skip the opcode, the length push, the denomination and the denominations of the batched spend
+	o := 2 + int(b[1])
+	if binary.LittleEndian.Uint64(b[o:]) == 0 {
+		o += wire.VarIntSerializeSize(uint64(len(ds))) + 8*len(ds)
+	}
+	return ds, b[o+8:], nil
+}
+
+// sigmaSerialSize is the size of the coin serial number (secp256k1 scalar) serialized after the denominations in the Sigma CoinSpend
+const sigmaSerialSize = 32
+
+// sigmaSpendSerial returns the hex encoded serial of the Sigma spend, the serial follows the denominations,
+// the spend must contain also the proof and the accumulator block hash, the spends with the proof
+// in the witness or with zero serial return empty string
+func sigmaSpendSerial(rest []byte) string {
+	if len(rest) < sigmaSerialSize+accumulatorBlockHashSize {
+		return ""
+	}
+	serial := rest[:sigmaSerialSize]
+	if bytes.Equal(serial, make([]byte, sigmaSerialSize)) {
+		return ""
+	}
+	return hex.EncodeToString(serial)
+}
+
+// GetSigmaSpendDenomination returns the denomination in satoshis of the Sigma spend referencing a single denomination,
+// the batched spends with multiple denominations return error
+func GetSigmaSpendDenomination(script string) (int64, error) {
+	ds, err := GetSigmaSpendDenominations(script)
+	if err != nil {
+		return 0, err
+	}
+	if len(ds) != 1 {
+		return 0, errors.Errorf("Sigma spend with %v denominations", len(ds))
+	}
+	return ds[0], nil
+}
+
+// GetPrivacyEvents returns the privacy mints and spends of the block, the mints with the minted value,
+// the spends with the serial and the value (denomination, the sum of the denominations of the batched Sigma spends)
+// if they can be decoded from the spend script
+func (p *ZcoinParser) GetPrivacyEvents(block *bchain.Block) []bchain.PrivacyEvent {
+	var events []bchain.PrivacyEvent
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		for j := range tx.Vin {
+			ps, err := p.ParsePrivacySpend(&tx.Vin[j])
+			if err != nil {
+				// only the decoding of the Zerocoin spends can fail, the spend is published without the serial
+				glog.Warning("txid ", tx.Txid, ", vin ", j, ": ", err)
+				ps = &PrivacySpend{Type: "zerocoinspend"}
+			}
+			if ps == nil {
+				continue
+			}
+			e := bchain.PrivacyEvent{Txid: tx.Txid, Height: block.Height, Type: ps.Type, Serial: ps.Serial}
+			if d, err := zerocoinSpendDenomination(vinScript(&tx.Vin[j])); err == nil {
+				e.Value = d.String()
+			} else if ds, err := GetSigmaSpendDenominations(vinScript(&tx.Vin[j])); err == nil {
+				v := new(big.Int)
+				for _, d := range ds {
+					v.Add(v, big.NewInt(d))
+				}
+				e.Value = v.String()
+			}
+			events = append(events, e)
+		}
+		for j := range tx.Vout {
+			vout := &tx.Vout[j]
+			script := vout.ScriptPubKey.Hex
+			if !isMintScript(script) {
+				continue
+			}
+			t := "zerocoinmint"
+			if scriptOp(script) == OpSigmaMint {
+				t = "sigmamint"
+			}
+			events = append(events, bchain.PrivacyEvent{Txid: tx.Txid, Height: block.Height, Type: t, Value: vout.ValueSat.String()})
+		}
+	}
+	return events
+}
+
+// BlockDenominations returns the number of the privacy mints and spends of the block by the denomination in satoshis,
+// each denomination of a batched Sigma spend is counted as a separate spend, the spends with undecodable
+// denomination are skipped, returns nil if the block has no privacy mints or spends
+func (p *ZcoinParser) BlockDenominations(block *bchain.Block) map[string]bchain.DenominationCount {
+	var m map[string]bchain.DenominationCount
+	add := func(d string, mint bool) {
+		if m == nil {
+			m = make(map[string]bchain.DenominationCount)
+		}
+		c := m[d]
+		if mint {
+			c.Mints++
+		} else {
+			c.Spends++
+		}
+		m[d] = c
+	}
+	for i := range block.Txs {
+		spends, mints := txDenominations(&block.Txs[i])
+		for _, d := range spends {
+			add(d, false)
+		}
+		for _, d := range mints {
+			add(d, true)
+		}
+	}
+	return m
+}
+
+// txDenominations returns the denominations in satoshis of the privacy spends and mints of the transaction,
+// each denomination of a batched Sigma spend is returned separately, the spends with undecodable
+// denomination are skipped
+func txDenominations(tx *bchain.Tx) ([]string, []string) {
+	var spends, mints []string
+	for j := range tx.Vin {
+		script := vinScript(&tx.Vin[j])
+		switch scriptOp(script) {
+		case OpZeroCoinSpend:
+			if d, err := zerocoinSpendDenomination(script); err == nil {
+				spends = append(spends, d.String())
+			}
+		case OpSigmaSpend:
+			if ds, err := GetSigmaSpendDenominations(script); err == nil {
+				for _, d := range ds {
+					spends = append(spends, strconv.FormatInt(d, 10))
+				}
+			}
+		}
+	}
+	for j := range tx.Vout {
+		if isMintScript(tx.Vout[j].ScriptPubKey.Hex) {
+			mints = append(mints, tx.Vout[j].ValueSat.String())
+		}
+	}
+	return spends, mints
+}
+
+// IsChurnTx returns true if the transaction mints exactly the denominations it spends from the shielded pool,
+// i.e. it re-shields the spent coins without changing their denominations, which only costs the fee,
+// a transaction changing the denominations of the spent coins is not a churn
+func (p *ZcoinParser) IsChurnTx(tx *bchain.Tx) bool {
+	spends, mints := txDenominations(tx)
+	if len(spends) == 0 || len(spends) != len(mints) {
+		return false
+	}
+	sort.Strings(spends)
+	sort.Strings(mints)
+	for i := range spends {
+		if spends[i] != mints[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// PrivacySpend contains information decoded from the script of a privacy spend input
+type PrivacySpend struct {
+	Type         string `json:"type"`
+	Denomination string `json:"denomination,omitempty"`
+	Serial       string `json:"serial,omitempty"`
+}
+
+// ParsePrivacySpend decodes the privacy spend input, returns nil if the input is not a privacy spend
+// the denomination of the Sigma spends is the sum of the denominations of the (batched) spend, the denomination
+// and the serial of the Sigma spends are omitted if they cannot be decoded
+func (p *ZcoinParser) ParsePrivacySpend(vin *bchain.Vin) (*PrivacySpend, error) {
+	script := vinScript(vin)
+	switch scriptOp(script) {
+	case OpZeroCoinSpend:
+		b, err := hex.DecodeString(script)
+		if err != nil {
+			return nil, err
+		}
+		d, serial, err := parseZerocoinSpend(b)
+		if err != nil {
+			return nil, err
+		}
+		return &PrivacySpend{
+			Type:         "zerocoinspend",
+			Denomination: p.AmountToDecimalString(d),
+			Serial:       hex.EncodeToString(serial),
+		}, nil
+	case OpSigmaSpend:
+		ps := &PrivacySpend{Type: "sigmaspend"}
+		if ds, rest, err := decodeSigmaSpend(script); err == nil {
+			v := new(big.Int)
+			for _, d := range ds {
+				v.Add(v, big.NewInt(d))
+			}
+			ps.Denomination = p.AmountToDecimalString(v)
+			ps.Serial = sigmaSpendSerial(rest)
+		}
+		return ps, nil
+	}
+	return nil, nil
+}
+
+// zerocoinSpend contains the fields of the serialized CoinSpend of a Zerocoin spend
+type zerocoinSpend struct {
+	denomination     int32
+	accCommitment    []byte
+	serialCommitment []byte
+	serial           []byte
+	// proof contains the remaining data, the accumulator and commitment proofs and the serial number signature
+	proof []byte
+}
+
+// decodeZerocoinSpend decodes the serialized CoinSpend from the spend script
+// the CoinSpend contains the denomination followed by accCommitmentToCoinValue, serialCommitmentToCoinValue
+// and coinSerialNumber, each serialized as little endian bignum with CompactSize length, and the proofs
+func decodeZerocoinSpend(script []byte) (*zerocoinSpend, error) {
+	if len(script) < 2 || script[1] < 1 || script[1] > 4 || len(script) < 2+int(script[1]) {
+		return nil, errors.New("Invalid Zerocoin spend script")
+	}
+	r := bytes.NewReader(script[2+int(script[1]):])
+	var zs zerocoinSpend
+	if err := binary.Read(r, binary.LittleEndian, &zs.denomination); err != nil {
+		return nil, errors.Annotatef(err, "Zerocoin spend denomination")
+	}
+	if zs.denomination <= 0 {
+		return nil, errors.Errorf("Invalid Zerocoin spend denomination %v", zs.denomination)
+	}
+	for i, bn := range []*[]byte{&zs.accCommitment, &zs.serialCommitment, &zs.serial} {
+		l, err := wire.ReadVarInt(r, 0)
+		if err != nil {
+			return nil, errors.Annotatef(err, "Zerocoin spend bignum %v", i)
+		}
+		if l > uint64(r.Len()) {
+			return nil, errors.Errorf("Zerocoin spend bignum %v length %v out of range", i, l)
+		}
+		*bn = make([]byte, l)
+		if _, err = io.ReadFull(r, *bn); err != nil {
+			return nil, err
+		}
+	}
+	zs.proof = make([]byte, r.Len())
+	if _, err := io.ReadFull(r, zs.proof); err != nil {
+		return nil, err
+	}
+	return &zs, nil
+}
+
+// parseZerocoinSpend returns the denomination in satoshis and the coin serial number (big endian) of the Zerocoin spend
+func parseZerocoinSpend(script []byte) (*big.Int, []byte, error) {
+	zs, err := decodeZerocoinSpend(script)
+	if err != nil {
+		return nil, nil, err
+	}
+	return new(big.Int).Mul(big.NewInt(int64(zs.denomination)), big.NewInt(100000000)), bignumToBigEndian(zs.serial), nil
+}
+
+// bignumToBigEndian converts the little endian bignum to big endian and removes the sign byte
+func bignumToBigEndian(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	for len(r) > 0 && r[0] == 0 {
+		r = r[1:]
+	}
+	return r
+}
+
+// accumulatorBlockHashSize is the size of the accumulator block hash serialized at the end of the CoinSpend
+const accumulatorBlockHashSize = chainhash.HashSize
+
+// GetSpendAccumulatorBlockHash returns the hash of the block with the accumulator state referenced by the privacy spend,
+// the hash (uint256) is the last field of the serialized CoinSpend of both Zerocoin and Sigma spends, it follows the proofs
+// the spend must decode (the Zerocoin spend up to the proofs, the Sigma spend up to the denominations)
+// and contain a nonzero hash
+func (p *ZcoinParser) GetSpendAccumulatorBlockHash(vin *bchain.Vin) (string, error) {
+	script := vinScript(vin)
+	var tail []byte
+	switch scriptOp(script) {
+	case OpZeroCoinSpend:
+		b, err := hex.DecodeString(script)
+		if err != nil {
+			return "", err
+		}
+		zs, err := decodeZerocoinSpend(b)
+		if err != nil {
+			return "", err
+		}
+		tail = zs.proof
+	case OpSigmaSpend:
+		_, rest, err := decodeSigmaSpend(script)
+		if err != nil {
+			return "", err
+		}
+		tail = rest
+	default:
+		return "", errors.New("Not a privacy spend")
+	}
+	if len(tail) < accumulatorBlockHashSize {
+		return "", errors.Errorf("Spend proof too short for the accumulator block hash, %v bytes", len(tail))
+	}
+	h, err := chainhash.NewHash(tail[len(tail)-accumulatorBlockHashSize:])
+	if err != nil {
+		return "", err
+	}
+	if *h == (chainhash.Hash{}) {
+		return "", errors.New("Zero accumulator block hash")
+	}
+	return h.String(), nil
+}
+
+// GetSpendAccumulatorHeight returns the height of the accumulator state referenced by the privacy spend,
+// the referenced block hash is resolved to the height by the blockHeight function
+func (p *ZcoinParser) GetSpendAccumulatorHeight(vin *bchain.Vin, blockHeight func(hash string) (uint32, error)) (uint32, error) {
+	hash, err := p.GetSpendAccumulatorBlockHash(vin)
+	if err != nil {
+		return 0, err
+	}
+	h, err := blockHeight(hash)
+	if err != nil {
+		return 0, errors.Annotatef(err, "accumulator block %v", hash)
+	}
+	return h, nil
+}
+
+// ZerocoinDenominations are the valid denominations of Zerocoin mints and spends in whole coins
+var ZerocoinDenominations = []int32{1, 10, 25, 50, 100}
+
+// ValidateZerocoinSpend performs structural validation of the Zerocoin spend input, it does not verify the proofs
+// the spend must decode, have a valid denomination, nonempty commitments and proof and a nonzero serial
+// of at most 256 bits, the accumulator is referenced by the input sequence, the accumulator ids start at 1
+func (p *ZcoinParser) ValidateZerocoinSpend(vin *bchain.Vin) error {
+	script := vinScript(vin)
+	if scriptOp(script) != OpZeroCoinSpend {
+		return errors.New("Not a Zerocoin spend")
+	}
+	b, err := hex.DecodeString(script)
+	if err != nil {
+		return err
+	}
+	zs, err := decodeZerocoinSpend(b)
+	if err != nil {
+		return err
+	}
+	valid := false
+	for _, d := range ZerocoinDenominations {
+		if zs.denomination == d {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return errors.Errorf("Invalid Zerocoin spend denomination %v", zs.denomination)
+	}
+	if len(zs.accCommitment) == 0 || len(zs.serialCommitment) == 0 {
+		return errors.New("Empty Zerocoin spend commitment")
+	}
+	serial := bignumToBigEndian(zs.serial)
+	if len(serial) == 0 || len(serial) > 32 {
+		return errors.Errorf("Invalid Zerocoin spend serial length %v", len(serial))
+	}
+	if len(zs.proof) == 0 {
+		return errors.New("Missing Zerocoin spend proof")
+	}
+	if vin.Sequence == 0 {
+		return errors.New("Invalid Zerocoin spend accumulator id 0")
+	}
+	return nil
+}
+
+// DecodePrivacySpendsJSON adds decoded privacy spend information to the privacySpend field of the spend inputs
+// in the JSON transaction returned by the backend and removes the misleading coinbase field of these inputs,
+// a spend which cannot be decoded is rendered only with its type and does not fail the whole transaction
+func (p *ZcoinParser) DecodePrivacySpendsJSON(msg json.RawMessage) (json.RawMessage, error) {
+	tx, err := p.ParseTxFromJson(msg)
+	if err != nil {
+		return nil, err
+	}
+	if !p.IsPrivacyTx(tx) {
+		return msg, nil
+	}
+	d := json.NewDecoder(bytes.NewReader(msg))
+	d.UseNumber()
+	var m map[string]interface{}
+	if err = d.Decode(&m); err != nil {
+		return nil, err
+	}
+	vins, ok := m["vin"].([]interface{})
+	if !ok || len(vins) != len(tx.Vin) {
+		return msg, nil
+	}
+	for i := range tx.Vin {
+		ps, err := p.ParsePrivacySpend(&tx.Vin[i])
+		if err != nil {
+			// only the decoding of the Zerocoin spends can fail, the spend is rendered without the serial
+			glog.Warning("txid ", tx.Txid, ", vin ", i, ": ", err)
+			ps = &PrivacySpend{Type: "zerocoinspend"}
+		}
+		vin, ok := vins[i].(map[string]interface{})
+		if ps == nil || !ok {
+			continue
+		}
+		delete(vin, "coinbase")
+		vin["privacySpend"] = ps
+	}
+	return json.Marshal(m)
+}
+
+// vinScript returns hex encoded script of the input
+// spend script is in the coinbase field if the tx was recognized as coinbase by the decoder
+func vinScript(vin *bchain.Vin) string {
+	if vin.ScriptSig.Hex != "" {
+		return vin.ScriptSig.Hex
+	}
+	return vin.Coinbase
+}
+
+// scriptOp returns the first opcode of hex encoded script or -1 if the script is empty or invalid
+func scriptOp(h string) int {
+	if len(h) < 2 {
+		return -1
+	}
+	b, err := hex.DecodeString(h[:2])
+	if err != nil {
+		return -1
+	}
+	return int(b[0])
+}
+
+func isSpendScript(h string) bool {
+	op := scriptOp(h)
+	return op == OpZeroCoinSpend || op == OpSigmaSpend
+}
+
+func isMintScript(h string) bool {
+	op := scriptOp(h)
+	return op == OpZeroCoinMint || op == OpSigmaMint
+}
+
+// RequiredConfirmations returns the number of confirmations the output must have before it can be spent,
+// the outputs of the coinstake transactions use the configured stake maturity, if set
+func (p *ZcoinParser) RequiredConfirmations(vout uint32, isCoinbase, isStake bool) int {
+	if isStake && !isCoinbase && p.config.StakeMaturity > 0 {
+		return p.config.StakeMaturity
+	}
+	return p.BitcoinParser.RequiredConfirmations(vout, isCoinbase, isStake)
+}
+
+// Difficulty returns the difficulty decoded from the compact target bits, the difficulty of the proof-of-stake blocks
+// is relative to the target limit PosLimitBits if it is configured, otherwise to the target of the difficulty 1
+func (p *ZcoinParser) Difficulty(bits uint32, proofOfStake bool) float64 {
+	if proofOfStake && p.config.PosLimitBits != 0 {
+		return btc.CompactDifficulty(bits, p.config.PosLimitBits)
+	}
+	return p.BitcoinParser.Difficulty(bits, proofOfStake)
+}
+
+// KernelHash returns the proof-of-stake kernel hash of the block, computed from the stake modifier,
+// the time of the block containing the stake input, the stake input outpoint and the block time as
+// sha256d(stakeModifier uint64 | blockFromTime uint32 | prevout hash | prevout n uint32 | time uint32)
+// the stake modifier is not part of the block data and must be supplied by the caller
+// ErrNotProofOfStake is returned for blocks without coinstake transaction
+func (p *ZcoinParser) KernelHash(block *bchain.Block, stakeModifier uint64, blockFromTime int64) (string, error) {
+	if len(block.Txs) < 2 || !bchain.IsCoinstakeTx(&block.Txs[1]) {
+		return "", ErrNotProofOfStake
+	}
+	prevout := &block.Txs[1].Vin[0]
+	h, err := chainhash.NewHashFromStr(prevout.Txid)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, 0, 8+4+chainhash.HashSize+4+4)
+	buf = appendUint64(buf, stakeModifier)
+	buf = appendUint32(buf, uint32(blockFromTime))
+	buf = append(buf, h[:]...)
+	buf = appendUint32(buf, prevout.Vout)
+	buf = appendUint32(buf, uint32(block.Time))
+	return chainhash.DoubleHashH(buf).String(), nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var t [4]byte
+	binary.LittleEndian.PutUint32(t[:], v)
+	return append(b, t[:]...)
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var t [8]byte
+	binary.LittleEndian.PutUint64(t[:], v)
+	return append(b, t[:]...)
+}
+
+// txEncoding returns encoding of the transactions in the block with the given header
+// blocks before the segwit activation do not contain the witness marker, decoding them
+// with the witness encoding could misinterpret a tx with empty vin as a segwit tx
+func (p *ZcoinParser) txEncoding(h *wire.BlockHeader) wire.MessageEncoding {
+	if p.config.SegwitActivationTime > 0 && h.Timestamp.Unix() < p.config.SegwitActivationTime {
+		return wire.BaseEncoding
+	}
+	return wire.WitnessEncoding
+}
+
+// checkTxOutCount checks the number of outputs declared by the serialized transaction at the start of b,
+// the check is done before the transaction is decoded because the decoder allocates the outputs by the declared count
+// the count is implausible if the outputs cannot fit in the rest of the block or exceed the maximum given by the block size,
+// if the transaction may be decoded in both encodings, the count is accepted if it is plausible in either of them
+func (p *ZcoinParser) checkTxOutCount(b []byte, enc wire.MessageEncoding) error {
+	var counts []uint64
+	if enc == wire.WitnessEncoding && len(b) > 5 && b[4] == 0 && b[5] == 1 {
+		if c, ok := declaredTxOutCount(b[6:], b); ok {
+			counts = append(counts, c)
+		}
+	}
+	if len(b) > 4 {
+		if c, ok := declaredTxOutCount(b[4:], b); ok {
+			counts = append(counts, c)
+		}
+	}
+	max := uint64(p.MaxTxOutCount())
+	for _, c := range counts {
+		if c <= max && c*btc.MinTxOutSize <= uint64(len(b)) {
+			return nil
+		}
+	}
+	if len(counts) > 0 {
+		return errors.Errorf("Number of outputs %v exceeds maximum %v or the remaining block size %v", counts[0], max, len(b))
+	}
+	// the transaction is not valid, the decoder reports the error
+	return nil
+}
+
+// declaredTxOutCount skips the inputs of the serialized transaction b starting after the version and the segwit marker
+// and returns the declared number of outputs, ok is false if the inputs cannot be read,
+// tx is the whole serialized transaction used to limit the number of inputs
+func declaredTxOutCount(b []byte, tx []byte) (uint64, bool) {
+	r := bytes.NewReader(b)
+	nIn, err := wire.ReadVarInt(r, 0)
+	// the smallest input has the prevout, the empty script and the sequence
+	if err != nil || nIn > uint64(len(tx))/41 {
+		return 0, false
+	}
+	for i := uint64(0); i < nIn; i++ {
+		if _, err = r.Seek(36, io.SeekCurrent); err != nil {
+			return 0, false
+		}
+		l, err := wire.ReadVarInt(r, 0)
+		if err != nil || l > uint64(r.Len()) {
+			return 0, false
+		}
+		if _, err = r.Seek(int64(l)+4, io.SeekCurrent); err != nil {
+			return 0, false
+		}
+	}
+	nOut, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return 0, false
+	}
+	return nOut, true
+}
+
+// decodeTx decodes a transaction from the block using the block encoding enc
+// a transaction of a version not in the configured segwit transaction versions (any version if none are configured)
+// is decoded again with the base encoding when the witness decoding fails
+func (p *ZcoinParser) decodeTx(r *bytes.Reader, enc wire.MessageEncoding) (*wire.MsgTx, error) {
+	tx := &wire.MsgTx{}
+	if enc != wire.WitnessEncoding {
+		return tx, tx.BtcDecode(r, 0, enc)
+	}
+	start := r.Size() - int64(r.Len())
+	var version int32
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return nil, err
+	}
+	err := tx.BtcDecode(r, 0, wire.WitnessEncoding)
+	if err == nil {
+		return tx, nil
+	}
+	if _, known := p.segwitTxVersions[version]; known {
+		return nil, err
+	}
+	glog.V(1).Infof("Witness decoding of tx version %v failed (%v), using base encoding", version, err)
+	if _, serr := r.Seek(start, io.SeekStart); serr != nil {
+		return nil, serr
+	}
+	tx = &wire.MsgTx{}
+	if berr := tx.BtcDecode(r, 0, wire.BaseEncoding); berr != nil {
+		return nil, berr
+	}
+	// the witness decoding can fail only for the transaction without transparent inputs (fully shielded spend),
+	// which starts with the zero input count indistinguishable from the segwit marker, other results are invalid
+	if len(tx.TxIn) != 0 || len(tx.TxOut) == 0 {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// mtpDataSize is the size of the fixed part of the MTP data, the MTP header and the hash data
+var mtpDataSize = int64(binary.Size(MTPBlockHeader{}) + binary.Size(MTPHashData{}))
+
+// skipMTPData moves the reader past the MTP data of the block, the MTP header, the hash data and the proof,
+// which consists of MTPL*3 proof lists of 16 bytes blocks, the data are not used and are skipped without allocation
+func skipMTPData(r *bytes.Reader) error {
+	if err := skipBytes(r, mtpDataSize); err != nil {
+		return err
+	}
+	for i := 0; i < MTPL*3; i++ {
+		numberProofBlocks, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if err = skipBytes(r, int64(numberProofBlocks)*16); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipBytes moves the reader n bytes forward, io.ErrUnexpectedEOF is returned if there is less data
+func skipBytes(r *bytes.Reader, n int64) error {
+	if int64(r.Len()) < n {
+		return io.ErrUnexpectedEOF
+	}
+	_, err := r.Seek(n, io.SeekCurrent)
+	return err
+}
+
+func parseBlockHeader(r io.Reader) (*wire.BlockHeader, error) {
+	h := &wire.BlockHeader{}
+	err := h.Deserialize(r)
+	return h, err
+}
+
+func (p *ZcoinParser) isMTP(h *wire.BlockHeader) bool {
+	return p.IsMTPActivated(h.Timestamp.Unix())
+}
 
+// IsMTPActivated returns true if the block with the header time blockTime has the MTP header,
+// i.e. the time is at or after the switch to MTP (SwitchToMTPBlockHeader)
+func (p *ZcoinParser) IsMTPActivated(blockTime int64) bool {
 	// the genesis block never be MTP block
-	return epoch > GenesisBlockTime && epoch >= SwitchToMTPBlockHeader
+	return blockTime > p.genesis.Time && blockTime >= SwitchToMTPBlockHeader
 }
 
 type MTPHashData struct {
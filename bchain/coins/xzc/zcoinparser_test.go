@@ -4,26 +4,44 @@ package xzc
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"blockbook/bchain"
 	"blockbook/bchain/coins/btc"
 
+	"github.com/martinboehm/btcd/chaincfg/chainhash"
+	"github.com/martinboehm/btcd/wire"
 	"github.com/martinboehm/btcutil/chaincfg"
 )
 
 var (
 	testTx1, testTx2, testTx3, testTx4                         bchain.Tx
 	testTxPacked1, testTxPacked2, testTxPacked3, testTxPacked4 string
-	rawBlock1, rawBlock2                                       string
-	jsonTx                                                     json.RawMessage
+	rawBlock1, rawBlock2, rawBlockTxCount, rawBlockEmptyVin    string
+	rawBlockTrailing, rawBlockShort, rawBlockComment           string
+	rawBlockSegwitSigma, rawSigmaSpend, rawSigmaSpendBatched   string
+	rawBlockDenominations                                      string
+	accumulatorSpends                                          []string
+	rawBlockMixed, rawBlockBadMerkle, rawBlockIntraSpend       string
+	rawBlockSpendProofs, rawBlockSegwitMarker                  string
+	jsonTx, jsonAmbiguousMintTx, jsonSpoofedSpendTx            json.RawMessage
+	jsonMigrationTx, jsonShieldedSpendTx, jsonTransparentTx    json.RawMessage
+	jsonConsolidationTx, jsonTimelockedSpendTx, jsonForkIDTx   json.RawMessage
 )
 
 func readHexs(path string) []string {
@@ -40,6 +58,27 @@ func init() {
 	rawBlocks := readHexs("./testdata/rawblock.hex")
 	rawBlock1 = rawBlocks[0]
 	rawBlock2 = rawBlocks[1]
+	rawBlockTxCount = readHexs("./testdata/rawblocktxcount.hex")[0]
+	rawBlockEmptyVin = readHexs("./testdata/rawblockemptyvin.hex")[0]
+	rawBlockTrailing = readHexs("./testdata/rawblocktrailing.hex")[0]
+	rawBlockShort = readHexs("./testdata/rawblockshort.hex")[0]
+	rawBlockComment = readHexs("./testdata/rawblockcomment.hex")[0]
+	rawBlockSegwitSigma = readHexs("./testdata/rawblocksegwitsigma.hex")[0]
+	sigmaSpends := readHexs("./testdata/sigmaspends.hex")
+	rawSigmaSpend, rawSigmaSpendBatched = sigmaSpends[0], sigmaSpends[1]
+	rawBlockDenominations = readHexs("./testdata/rawblockdenominations.hex")[0]
+	accumulatorSpends = readHexs("./testdata/accumulatorspends.hex")[:3]
+	rawBlockMixed = readHexs("./testdata/rawblockmixed.hex")[0]
+	// rawBlock2 with the lock time of the last transaction changed
+	rawBlockBadMerkle = readHexs("./testdata/rawblockbadmerkle.hex")[0]
+	// rawBlock2 with a transaction spending the outputs 2:0 and 3:1 of the block and an output of another block
+	rawBlockIntraSpend = readHexs("./testdata/rawblockintraspend.hex")[0]
+	// rawBlock2 with a copy of its zerocoin spend transaction, a transaction with two zerocoin spends
+	// and the sigma spend with witness of rawBlockSegwitSigma
+	rawBlockSpendProofs = readHexs("./testdata/rawblockspendproofs.hex")[0]
+	// rawBlockEmptyVin with the empty vin transaction changed so that it is valid also in the witness encoding,
+	// the value 512 of its only output starts with the zero input count and two output count of the segwit reading
+	rawBlockSegwitMarker = readHexs("./testdata/rawblocksegwitmarker.hex")[0]
 
 	hextxs := readHexs("./testdata/txs.hex")
 	rawTestTx1 := hextxs[0]
@@ -55,6 +94,54 @@ func init() {
 	}
 	jsonTx = json.RawMessage(rawSpendTx)
 
+	rawAmbiguousMintTx, err := ioutil.ReadFile("./testdata/ambiguousminttx.json")
+	if err != nil {
+		panic(err)
+	}
+	jsonAmbiguousMintTx = json.RawMessage(rawAmbiguousMintTx)
+
+	rawSpoofedSpendTx, err := ioutil.ReadFile("./testdata/spoofedspendtx.json")
+	if err != nil {
+		panic(err)
+	}
+	jsonSpoofedSpendTx = json.RawMessage(rawSpoofedSpendTx)
+
+	rawMigrationTx, err := ioutil.ReadFile("./testdata/migrationtx.json")
+	if err != nil {
+		panic(err)
+	}
+	jsonMigrationTx = json.RawMessage(rawMigrationTx)
+
+	rawShieldedSpendTx, err := ioutil.ReadFile("./testdata/shieldedspendtx.json")
+	if err != nil {
+		panic(err)
+	}
+	jsonShieldedSpendTx = json.RawMessage(rawShieldedSpendTx)
+
+	rawTransparentTx, err := ioutil.ReadFile("./testdata/transparenttx.json")
+	if err != nil {
+		panic(err)
+	}
+	jsonTransparentTx = json.RawMessage(rawTransparentTx)
+
+	rawConsolidationTx, err := ioutil.ReadFile("./testdata/consolidationtx.json")
+	if err != nil {
+		panic(err)
+	}
+	jsonConsolidationTx = json.RawMessage(rawConsolidationTx)
+
+	rawTimelockedSpendTx, err := ioutil.ReadFile("./testdata/timelockedspendtx.json")
+	if err != nil {
+		panic(err)
+	}
+	jsonTimelockedSpendTx = json.RawMessage(rawTimelockedSpendTx)
+
+	rawForkIDTx, err := ioutil.ReadFile("./testdata/forkidtx.json")
+	if err != nil {
+		panic(err)
+	}
+	jsonForkIDTx = json.RawMessage(rawForkIDTx)
+
 	testTxPackeds := readHexs("./testdata/packedtxs.hex")
 	testTxPacked1 = testTxPackeds[0]
 	testTxPacked2 = testTxPackeds[1]
@@ -253,6 +340,54 @@ func init() {
 			},
 		},
 	}
+
+	jsonSpendTx500, testSpendTx500 = spendTxWithInputs(500)
+}
+
+// testChainParams returns the main network params, the registration does not fail in the tests
+func testChainParams() *chaincfg.Params {
+	params, err := GetChainParams("main")
+	if err != nil {
+		panic(err)
+	}
+	return params
+}
+
+func TestGetChainParamsRegistrationConflict(t *testing.T) {
+	chaincfg.ResetParams()
+	defer chaincfg.ResetParams()
+	// another coin registered the Zcoin testnet magic
+	other := chaincfg.TestNet3Params
+	other.Net = TestnetMagic
+	if err := chaincfg.Register(&other); err != nil {
+		t.Fatal(err)
+	}
+	if params, err := GetChainParams("main"); err == nil || params != nil {
+		t.Errorf("GetChainParams() = %v, %v, want error", params, err)
+	}
+}
+
+func TestNetworkMagic(t *testing.T) {
+	tests := []struct {
+		chain string
+		want  wire.BitcoinNet
+	}{
+		{chain: "main", want: MainnetMagic},
+		{chain: "test", want: TestnetMagic},
+		{chain: "regtest", want: RegtestMagic},
+	}
+	for _, tt := range tests {
+		t.Run(tt.chain, func(t *testing.T) {
+			params, err := GetChainParams(tt.chain)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := NewZcoinParser(params, &btc.Configuration{})
+			if got := p.NetworkMagic(); got != tt.want {
+				t.Errorf("NetworkMagic() = %#x, want %#x", uint32(got), uint32(tt.want))
+			}
+		})
+	}
 }
 
 func TestMain(m *testing.M) {
@@ -274,7 +409,7 @@ func TestGetAddrDesc(t *testing.T) {
 			name: "xzc-1",
 			args: args{
 				tx:     testTx1,
-				parser: NewZcoinParser(GetChainParams("main"), &btc.Configuration{}),
+				parser: NewZcoinParser(testChainParams(), &btc.Configuration{}),
 			},
 		},
 		// FIXME: work around handle zerocoin spend as coinbase
@@ -282,14 +417,14 @@ func TestGetAddrDesc(t *testing.T) {
 		// 	name: "xzc-2",
 		// 	args: args{
 		// 		tx:     testTx2,
-		// 		parser: NewZcoinParser(GetChainParams("main"), &btc.Configuration{}),
+		// 		parser: NewZcoinParser(testChainParams(), &btc.Configuration{}),
 		// 	},
 		// },
 		{
 			name: "xzc-3",
 			args: args{
 				tx:     testTx3,
-				parser: NewZcoinParser(GetChainParams("main"), &btc.Configuration{}),
+				parser: NewZcoinParser(testChainParams(), &btc.Configuration{}),
 			},
 		},
 	}
@@ -318,6 +453,111 @@ func TestGetAddrDesc(t *testing.T) {
 	}
 }
 
+func TestAddressVariantRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      Configuration
+		script      string
+		wantAddress string
+	}{
+		{
+			name:        "p2pkh-default",
+			config:      Configuration{},
+			script:      "76a914b9e262e30df03e88ccea312652bc83ca7290c8fc88ac",
+			wantAddress: "aHfKwzFZMiSxDuNL4jts819nh57t2yJG1h",
+		},
+		{
+			name:        "p2sh-default",
+			config:      Configuration{},
+			script:      "a914b9e262e30df03e88ccea312652bc83ca7290c8fc87",
+			wantAddress: "47K66qtx7BhEtPuqEGuxkbjnVEo8dPqH7e",
+		},
+		{
+			name:        "p2pkh-sha256d-checksum",
+			config:      Configuration{AddressChecksum: AddressChecksumSha256D},
+			script:      "76a914b9e262e30df03e88ccea312652bc83ca7290c8fc88ac",
+			wantAddress: "aHfKwzFZMiSxDuNL4jts819nh57t2yJG1h",
+		},
+		{
+			name:        "p2pkh-sha256-checksum",
+			config:      Configuration{AddressChecksum: AddressChecksumSha256},
+			script:      "76a914b9e262e30df03e88ccea312652bc83ca7290c8fc88ac",
+			wantAddress: "aHfKwzFZMiSxDuNL4jts819nh57szmwd5S",
+		},
+		{
+			name:        "p2sh-sha256-checksum",
+			config:      Configuration{AddressChecksum: AddressChecksumSha256},
+			script:      "a914b9e262e30df03e88ccea312652bc83ca7290c8fc87",
+			wantAddress: "47K66qtx7BhEtPuqEGuxkbjnVEo8g74F9U",
+		},
+		{
+			name:        "p2wpkh-bech32",
+			config:      Configuration{Bech32HRP: "bc"},
+			script:      "0014751e76e8199196d454941c45d1b3a323f1433bd6",
+			wantAddress: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+		},
+		{
+			name:   "p2wsh-custom-hrp",
+			config: Configuration{Bech32HRP: "xz"},
+			script: "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			parser := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &config)
+			script, _ := hex.DecodeString(tt.script)
+			addresses, searchable, err := parser.GetAddressesFromAddrDesc(script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(addresses) != 1 || !searchable {
+				t.Fatalf("GetAddressesFromAddrDesc() = %v, %v", addresses, searchable)
+			}
+			if tt.wantAddress != "" && addresses[0] != tt.wantAddress {
+				t.Errorf("GetAddressesFromAddrDesc() = %v, want %v", addresses[0], tt.wantAddress)
+			}
+			got, err := parser.GetAddrDescFromAddress(addresses[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, script) {
+				t.Errorf("GetAddrDescFromAddress() = %x, want %v", got, tt.script)
+			}
+		})
+	}
+}
+
+func TestAddressVariantChecksum(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	sha256Parser := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{AddressChecksum: AddressChecksumSha256})
+	script, _ := hex.DecodeString("76a914b9e262e30df03e88ccea312652bc83ca7290c8fc88ac")
+	addresses, _, err := sha256Parser.GetAddressesFromAddrDesc(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addresses[0] == "aHfKwzFZMiSxDuNL4jts819nh57t2yJG1h" {
+		t.Errorf("GetAddressesFromAddrDesc() with sha256 checksum = default address %v", addresses[0])
+	}
+	if _, err = parser.GetAddrDescFromAddress(addresses[0]); err == nil {
+		t.Errorf("GetAddrDescFromAddress() of the sha256 checksum address with the default checksum succeeded")
+	}
+	if _, err = sha256Parser.GetAddrDescFromAddress("aHfKwzFZMiSxDuNL4jts819nh57t2yJG1h"); err == nil {
+		t.Errorf("GetAddrDescFromAddress() of the default address with the sha256 checksum succeeded")
+	}
+	if _, err = NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{AddressChecksum: "blake"}).
+		GetAddrDescFromAddress("aHfKwzFZMiSxDuNL4jts819nh57t2yJG1h"); err != nil {
+		t.Errorf("GetAddrDescFromAddress() with unknown checksum variant error = %v, want the default checksum", err)
+	}
+	if err = checkAddressChecksum("blake"); err == nil {
+		t.Errorf("checkAddressChecksum() of unknown variant succeeded")
+	}
+	if _, err = NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{Bech32HRP: "bc"}).
+		GetAddrDescFromAddress("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5"); err == nil {
+		t.Errorf("GetAddrDescFromAddress() of the bech32 address with invalid checksum succeeded")
+	}
+}
+
 func TestGetAddrDescFromVoutForMint(t *testing.T) {
 	type args struct {
 		vout bchain.Vout
@@ -347,7 +587,7 @@ func TestGetAddrDescFromVoutForMint(t *testing.T) {
 			wantErr: false,
 		},
 	}
-	parser := NewZcoinParser(GetChainParams("main"), &btc.Configuration{})
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -397,7 +637,7 @@ func TestGetAddressesFromAddrDescForMint(t *testing.T) {
 			wantErr: false,
 		},
 	}
-	parser := NewZcoinParser(GetChainParams("main"), &btc.Configuration{})
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -417,195 +657,2025 @@ func TestGetAddressesFromAddrDescForMint(t *testing.T) {
 	}
 }
 
-func TestPackTx(t *testing.T) {
-	type args struct {
-		tx        bchain.Tx
-		height    uint32
-		blockTime int64
-		parser    *ZcoinParser
-	}
+func TestPseudoAddressNetwork(t *testing.T) {
 	tests := []struct {
 		name    string
-		args    args
+		chain   string
+		network bool
 		want    string
-		wantErr bool
+	}{
+		{name: "mainnet", chain: "main", network: false, want: "Sigmamint"},
+		{name: "mainnet with network", chain: "main", network: true, want: "Sigmamint"},
+		{name: "testnet", chain: "test", network: false, want: "Sigmamint"},
+		{name: "testnet with network", chain: "test", network: true, want: "Sigmamint-test"},
+		{name: "regtest with network", chain: "regtest", network: true, want: "Sigmamint-regtest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := GetChainParams(tt.chain)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parser := NewZcoinParserWithConfig(params, &btc.Configuration{}, &Configuration{PseudoAddressNetwork: tt.network})
+			got, _, err := parser.GetAddressesFromAddrDesc(bchain.AddressDescriptor{OpSigmaMint, 0x00})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, []string{tt.want}) {
+				t.Errorf("GetAddressesFromAddrDesc() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetAddressesFromAddrDescMalformed(t *testing.T) {
+	tests := []struct {
+		name     string
+		addrDesc string
+		want     []string
+		want2    bool
+		wantErr  bool
 	}{
 		{
-			name: "xzc-1",
-			args: args{
-				tx:        testTx1,
-				height:    100002,
-				blockTime: 1533980594,
-				parser:    NewZcoinParser(GetChainParams("main"), &btc.Configuration{}),
-			},
-			want:    testTxPacked1,
-			wantErr: false,
+			name:     "empty",
+			addrDesc: "",
+			want:     []string{},
+			want2:    false,
+			wantErr:  false,
 		},
-		// FIXME: work around handle zerocoin spend as coinbase
-		// {
-		// 	name: "xzc-2",
-		// 	args: args{
-		// 		tx:        testTx2,
-		// 		height:    11002,
-		// 		blockTime: 1481277009,
-		// 		parser:    NewZcoinParser(GetChainParams("main"), &btc.Configuration{}),
-		// 	},
-		// 	want:    testTxPacked2,
-		// 	wantErr: true,
-		// },
 		{
-			name: "xzc-3",
-			args: args{
-				tx:        testTx3,
-				height:    126202,
-				blockTime: 1547091829,
-				parser:    NewZcoinParser(GetChainParams("main"), &btc.Configuration{}),
-			},
-			want:    testTxPacked3,
-			wantErr: false,
+			name:     "OP_ZEROCOINMINT only",
+			addrDesc: "c1",
+			want:     []string{"Zeromint"},
+			want2:    false,
+			wantErr:  false,
 		},
 		{
-			name: "xzc-coinbase",
-			args: args{
-				tx:        testTx4,
-				height:    100001,
-				blockTime: 1533977563,
-				parser:    NewZcoinParser(GetChainParams("main"), &btc.Configuration{}),
-			},
-			want:    testTxPacked4,
-			wantErr: false,
+			name:     "OP_ZEROCOINSPEND only",
+			addrDesc: "c2",
+			want:     []string{"Zerospend"},
+			want2:    false,
+			wantErr:  false,
+		},
+		{
+			name:     "OP_SIGMAMINT only",
+			addrDesc: "c3",
+			want:     []string{"Sigmamint"},
+			want2:    false,
+			wantErr:  false,
+		},
+		{
+			name:     "OP_SIGMASPEND only",
+			addrDesc: "c4",
+			want:     []string{"Sigmaspend"},
+			want2:    false,
+			wantErr:  false,
+		},
+		{
+			name:     "OP_SIGMAMINT with garbage",
+			addrDesc: "c3ff00ff",
+			want:     []string{"Sigmamint"},
+			want2:    false,
+			wantErr:  false,
+		},
+		{
+			name:     "OP_ZEROCOINSPEND with garbage",
+			addrDesc: "c24d",
+			want:     []string{"Zerospend"},
+			want2:    false,
+			wantErr:  false,
+		},
+		{
+			name:     "single non privacy byte",
+			addrDesc: "76",
+			want:     []string{},
+			want2:    false,
+			wantErr:  false,
 		},
 	}
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := tt.args.parser.PackTx(&tt.args.tx, tt.args.height, tt.args.blockTime)
+			b, _ := hex.DecodeString(tt.addrDesc)
+			got, got2, err := parser.GetAddressesFromAddrDesc(b)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("packTx() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("GetAddressesFromAddrDesc() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !tt.wantErr {
-				h := hex.EncodeToString(got)
-				if !reflect.DeepEqual(h, tt.want) {
-					t.Errorf("packTx() = %v, want %v", h, tt.want)
-				}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetAddressesFromAddrDesc() = %v, want %v", got, tt.want)
+			}
+			if got2 != tt.want2 {
+				t.Errorf("GetAddressesFromAddrDesc() = %v, want %v", got2, tt.want2)
 			}
 		})
 	}
 }
 
-func TestUnpackTx(t *testing.T) {
-	type args struct {
-		packedTx string
-		parser   *ZcoinParser
-	}
+func TestValidateMintOutput(t *testing.T) {
 	tests := []struct {
 		name    string
-		args    args
-		want    *bchain.Tx
-		want1   uint32
+		script  string
 		wantErr bool
 	}{
 		{
-			name: "xzc-1",
-			args: args{
-				packedTx: testTxPacked1,
-				parser:   NewZcoinParser(GetChainParams("main"), &btc.Configuration{}),
-			},
-			want:    &testTx1,
-			want1:   100002,
+			name:    "P2PKH",
+			script:  "76a914b9e262e30df03e88ccea312652bc83ca7290c8fc88ac",
 			wantErr: false,
 		},
-		// FIXME: work around handle zerocoin spend as coinbase
-		// {
-		// 	name: "xzc-2",
-		// 	args: args{
-		// 		packedTx: testTxPacked2,
-		// 		parser:   NewZcoinParser(GetChainParams("main"), &btc.Configuration{}),
-		// 	},
-		// 	want:    &testTx2,
-		// 	want1:   11002,
-		// 	wantErr: true,
-		// },
 		{
-			name: "xzc-3",
-			args: args{
-				packedTx: testTxPacked3,
-				parser:   NewZcoinParser(GetChainParams("main"), &btc.Configuration{}),
-			},
-			want:    &testTx3,
-			want1:   126202,
+			name:    "OP_ZEROCOINMINT",
+			script:  "c10280004c80f767f3ee79953c67a7ed386dcccf1243619eb4bbbe414a3982dd94a83c1b69ac52d6ab3b653a3e05c4e4516c8dfe1e58ada40461bc5835a4a0d0387a51c29ac11b72ae25bbcdef745f50ad08f08b3e9bc2c31a35444398a490e65ac090e9f341f1abdebe47e57e8237ac25d098e951b4164a35caea29f30acb50b12e4425df28",
 			wantErr: false,
 		},
 		{
-			name: "xzc-coinbase",
-			args: args{
-				packedTx: testTxPacked4,
-				parser:   NewZcoinParser(GetChainParams("main"), &btc.Configuration{}),
-			},
-			want:    &testTx4,
-			want1:   100001,
+			name:    "OP_SIGMAMINT",
+			script:  "c317dcee5b8b2c5b79728abc3a39abc54682b31a4e18f5abb6f34dc8089544763b0000",
 			wantErr: false,
 		},
+		{
+			name:    "OP_SIGMAMINT with P2PKH",
+			script:  "c376a914b9e262e30df03e88ccea312652bc83ca7290c8fc88ac",
+			wantErr: true,
+		},
+		{
+			name:    "OP_ZEROCOINMINT with P2SH",
+			script:  "c1a914b9e262e30df03e88ccea312652bc83ca7290c8fc87",
+			wantErr: true,
+		},
 	}
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			b, _ := hex.DecodeString(tt.args.packedTx)
-			got, got1, err := tt.args.parser.UnpackTx(b)
+			b, _ := hex.DecodeString(tt.script)
+			err := parser.ValidateMintOutput(b)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("unpackTx() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-
-			if !tt.wantErr {
-				if !reflect.DeepEqual(got, tt.want) {
-					t.Errorf("unpackTx() got = %v, want %v", got, tt.want)
-				}
-				if got1 != tt.want1 {
-					t.Errorf("unpackTx() got1 = %v, want %v", got1, tt.want1)
-				}
+				t.Errorf("ValidateMintOutput() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func TestParseBlock(t *testing.T) {
-	type args struct {
-		rawBlock string
-		parser   *ZcoinParser
-	}
+func TestParseTxFromJsonAmbiguousMint(t *testing.T) {
 	tests := []struct {
 		name    string
-		args    args
-		want    *bchain.Block
-		wantTxs int
+		parser  *ZcoinParser
 		wantErr bool
 	}{
 		{
-			name: "normal-block",
-			args: args{
-				rawBlock: rawBlock1,
-				parser:   NewZcoinParser(GetChainParams("main"), &btc.Configuration{}),
-			},
-			want: &bchain.Block{
-				BlockHeader: bchain.BlockHeader{
-					Size: 200286,
-					Time: 1547120622,
-				},
-			},
-			wantTxs: 3,
+			name:    "lenient",
+			parser:  NewZcoinParser(testChainParams(), &btc.Configuration{}),
 			wantErr: false,
 		},
 		{
-			name: "spend-block",
-			args: args{
-				rawBlock: rawBlock2,
-				parser:   NewZcoinParser(GetChainParams("main"), &btc.Configuration{}),
-			},
-			want: &bchain.Block{
-				BlockHeader: bchain.BlockHeader{
-					Size: 25298,
-					Time: 1482107572,
-				},
+			name:    "strict",
+			parser:  NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{StrictMintValidation: true}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := tt.parser.ParseTxFromJson(jsonAmbiguousMintTx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTxFromJson() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && len(tx.Vout) != 2 {
+				t.Errorf("ParseTxFromJson() got %v outputs, want 2", len(tx.Vout))
+			}
+		})
+	}
+}
+
+func TestGetTransactionInvalidMint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"result":%s,"error":null}`, jsonAmbiguousMintTx)
+	}))
+	defer server.Close()
+	tests := []struct {
+		name    string
+		config  Configuration
+		wantErr bool
+	}{
+		{name: "lenient"},
+		{name: "strict", config: Configuration{StrictMintValidation: true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc, err := NewZcoinRPC(json.RawMessage(`{"rpc_url":"`+server.URL+`","rpc_timeout":5}`), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			zc := bc.(*ZcoinRPC)
+			zc.Parser = NewZcoinParserWithConfig(testChainParams(), zc.ChainConfig, &tt.config)
+			tx, err := zc.GetTransaction("a1b2")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetTransaction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if tx != nil {
+					t.Errorf("GetTransaction() = %+v with error", tx)
+				}
+				return
+			}
+			if _, ok := tx.CoinSpecificData.(json.RawMessage); !ok {
+				t.Errorf("GetTransaction() CoinSpecificData = %T, want json.RawMessage", tx.CoinSpecificData)
+			}
+		})
+	}
+}
+
+func TestParseTxFromJsonMaxMoney(t *testing.T) {
+	msg := func(value string) json.RawMessage {
+		return json.RawMessage(`{"txid":"a1b2","vin":[{"coinbase":"03","sequence":4294967295}],"vout":[{"value":` + value + `,"n":0,"scriptPubKey":{"hex":"76a914a7f3e9e1e9a1d0fc43d84ee5dbd1e6e7c2306b0b88ac"}}]}`)
+	}
+	tests := []struct {
+		name    string
+		config  btc.Configuration
+		value   string
+		wantErr bool
+	}{
+		{name: "above Bitcoin max money", value: "21000001"},
+		{name: "Zcoin max money", value: "21400000"},
+		{name: "above Zcoin max money", value: "21400000.00000001", wantErr: true},
+		{name: "configured max money", config: btc.Configuration{MaxMoney: "100000000"}, value: "1"},
+		{name: "above configured max money", config: btc.Configuration{MaxMoney: "100000000"}, value: "1.00000001", wantErr: true},
+		{name: "invalid configured max money", config: btc.Configuration{MaxMoney: "1e8"}, value: "21400000.00000001", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewZcoinParser(testChainParams(), &tt.config)
+			_, err := parser.ParseTxFromJson(msg(tt.value))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTxFromJson() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseTxFromJsonSpoofedSpend(t *testing.T) {
+	tests := []struct {
+		name    string
+		parser  *ZcoinParser
+		wantErr bool
+	}{
+		{
+			name:    "lenient",
+			parser:  NewZcoinParser(testChainParams(), &btc.Configuration{}),
+			wantErr: false,
+		},
+		{
+			name:    "strict",
+			parser:  NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{StrictSpendValidation: true}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.parser.ParseTxFromJson(jsonSpoofedSpendTx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTxFromJson() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseTxFromJsonCoinbaseSpendVout(t *testing.T) {
+	tests := []struct {
+		name     string
+		parser   *ZcoinParser
+		wantVout uint32
+	}{
+		{
+			name:     "default",
+			parser:   NewZcoinParser(testChainParams(), &btc.Configuration{}),
+			wantVout: 0,
+		},
+		{
+			name:     "coinbase convention",
+			parser:   NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{CoinbaseSpendVout: true}),
+			wantVout: 0xffffffff,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := tt.parser.ParseTxFromJson(jsonTx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tx.Vin[0].Txid != "" || tx.Vin[0].Vout != tt.wantVout {
+				t.Errorf("ParseTxFromJson() vin txid %q, vout %v, want empty txid and vout %v", tx.Vin[0].Txid, tx.Vin[0].Vout, tt.wantVout)
+			}
+		})
+	}
+}
+
+func TestValidateSpendInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		vin     bchain.Vin
+		wantErr bool
+	}{
+		{
+			name: "sigma spend",
+			vin:  bchain.Vin{Txid: SpendTxID, Vout: 0xffffffff, ScriptSig: bchain.ScriptSig{Hex: "c40100"}},
+		},
+		{
+			name: "sigma spend as coinbase",
+			vin:  bchain.Vin{Coinbase: "c40100"},
+		},
+		{
+			name: "normal input",
+			vin:  bchain.Vin{Txid: "3d721fdce2855e2b4a54b74a26edd58a7262e1f195b5acaaae7832be6e0b3d32", ScriptSig: bchain.ScriptSig{Hex: "4730440220"}},
+		},
+		{
+			name:    "spend referencing real prevout",
+			vin:     bchain.Vin{Txid: "3d721fdce2855e2b4a54b74a26edd58a7262e1f195b5acaaae7832be6e0b3d32", ScriptSig: bchain.ScriptSig{Hex: "c20100"}},
+			wantErr: true,
+		},
+		{
+			name:    "zero txid without spend script",
+			vin:     bchain.Vin{Txid: SpendTxID, ScriptSig: bchain.ScriptSig{Hex: "4730440220"}},
+			wantErr: true,
+		},
+	}
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := parser.ValidateSpendInput(&tt.vin); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSpendInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsPrivacyTx(t *testing.T) {
+	tests := []struct {
+		name string
+		tx   bchain.Tx
+		want bool
+	}{
+		{
+			name: "mint",
+			tx:   testTx1,
+			want: true,
+		},
+		{
+			name: "spend",
+			tx:   testTx2,
+			want: true,
+		},
+		{
+			name: "transparent",
+			tx:   testTx3,
+			want: false,
+		},
+		{
+			name: "coinbase",
+			tx:   testTx4,
+			want: false,
+		},
+	}
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parser.IsPrivacyTx(&tt.tx); got != tt.want {
+				t.Errorf("IsPrivacyTx() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsFullyShieldedTx(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	tx, err := parser.ParseTxFromJson(jsonShieldedSpendTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parser.IsFullyShieldedTx(tx) || !parser.IsPrivacyTx(tx) {
+		t.Errorf("IsFullyShieldedTx() = %v, IsPrivacyTx() = %v, want true", parser.IsFullyShieldedTx(tx), parser.IsPrivacyTx(tx))
+	}
+	if minted, spent := parser.GetShieldedFlows(tx); minted.Int64() != 0 || spent.Int64() != 2499990000 {
+		t.Errorf("GetShieldedFlows() = %v, %v, want 0, 2499990000", minted, spent)
+	}
+	for _, tx := range []*bchain.Tx{&testTx1, &testTx2, &testTx3, &testTx4} {
+		if parser.IsFullyShieldedTx(tx) {
+			t.Errorf("IsFullyShieldedTx(%v) = true, want false", tx.Txid)
+		}
+	}
+
+	b, _ := hex.DecodeString(rawBlockEmptyVin)
+	block, err := parser.ParseBlock(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parser.IsFullyShieldedTx(&block.Txs[1]) {
+		t.Errorf("IsFullyShieldedTx() of the empty vin block tx = false, want true")
+	}
+	if parser.IsFullyShieldedTx(&block.Txs[0]) || len(block.Txs[1].Vin) != 0 {
+		t.Errorf("Coinbase of the empty vin block classified as fully shielded or empty vin tx has inputs")
+	}
+}
+
+func TestIsMigrationTx(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	migration, err := parser.ParseTxFromJson(jsonMigrationTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// remint of the spent Zerocoin to a new Zerocoin mint
+	remint := *migration
+	remint.Vout = []bchain.Vout{migration.Vout[0]}
+	remint.Vout[0].ScriptPubKey.Hex = "c1" + migration.Vout[0].ScriptPubKey.Hex[2:]
+
+	tests := []struct {
+		name string
+		tx   *bchain.Tx
+		want bool
+	}{
+		{
+			name: "migration",
+			tx:   migration,
+			want: true,
+		},
+		{
+			name: "remint",
+			tx:   &remint,
+			want: false,
+		},
+		{
+			name: "mint",
+			tx:   &testTx1,
+			want: false,
+		},
+		{
+			name: "spend",
+			tx:   &testTx2,
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parser.IsMigrationTx(tt.tx); got != tt.want {
+				t.Errorf("IsMigrationTx() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// sigmaSpendTx returns the transaction with the Sigma spend input given by the script paying the transparent value
+// and minting the remint value
+func sigmaSpendTx(script string, transparent, remint int64) bchain.Tx {
+	tx := bchain.Tx{
+		Txid: "sigmaspend",
+		Vin:  []bchain.Vin{{ScriptSig: bchain.ScriptSig{Hex: script}, Sequence: 0xffffffff}},
+		Vout: []bchain.Vout{{N: 0, ValueSat: *big.NewInt(transparent), ScriptPubKey: testTx3.Vout[0].ScriptPubKey}},
+	}
+	if remint > 0 {
+		tx.Vout = append(tx.Vout, bchain.Vout{N: 1, ValueSat: *big.NewInt(remint), ScriptPubKey: bchain.ScriptPubKey{Hex: "c3" + strings.Repeat("00", 34)}})
+	}
+	return tx
+}
+
+func TestGetShieldedFlows(t *testing.T) {
+	tests := []struct {
+		name       string
+		tx         bchain.Tx
+		wantMinted int64
+		wantSpent  int64
+	}{
+		{
+			name:       "mint",
+			tx:         testTx1,
+			wantMinted: 18188266638,
+			wantSpent:  0,
+		},
+		{
+			name:       "spend",
+			tx:         testTx2,
+			wantMinted: 0,
+			wantSpent:  5000000000,
+		},
+		{
+			name:       "transparent",
+			tx:         testTx3,
+			wantMinted: 0,
+			wantSpent:  0,
+		},
+		{
+			// the fee of 1 XZC is paid from the spent denomination and leaves the shielded pool
+			name:       "sigma spend",
+			tx:         sigmaSpendTx(rawSigmaSpend, 500000000, 400000000),
+			wantMinted: 400000000,
+			wantSpent:  1000000000,
+		},
+		{
+			name:       "batched sigma spend",
+			tx:         sigmaSpendTx(rawSigmaSpendBatched, 11000000000, 0),
+			wantMinted: 0,
+			wantSpent:  11100000000,
+		},
+		{
+			// the outputs are taken as the spent value if the denomination cannot be decoded
+			name:       "undecodable sigma spend",
+			tx:         sigmaSpendTx("c4010a", 500000000, 400000000),
+			wantMinted: 400000000,
+			wantSpent:  900000000,
+		},
+		{
+			name:       "fully shielded",
+			tx:         bchain.Tx{Vout: []bchain.Vout{{ValueSat: *big.NewInt(100000000)}}},
+			wantMinted: 0,
+			wantSpent:  100000000,
+		},
+	}
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minted, spent := parser.GetShieldedFlows(&tt.tx)
+			if minted.Int64() != tt.wantMinted {
+				t.Errorf("GetShieldedFlows() minted = %v, want %v", minted, tt.wantMinted)
+			}
+			if spent.Int64() != tt.wantSpent {
+				t.Errorf("GetShieldedFlows() spent = %v, want %v", spent, tt.wantSpent)
+			}
+		})
+	}
+}
+
+func TestPrivacyScore(t *testing.T) {
+	tests := []struct {
+		name string
+		tx   bchain.Tx
+		want int
+	}{
+		// 1 of 3 parts shielded, half of the value minted
+		{name: "mint with change", tx: testTx1, want: 42},
+		// the spend input shielded, the output paid from the shielded pool
+		{name: "spend", tx: testTx2, want: 75},
+		{name: "transparent", tx: testTx3, want: 0},
+		{name: "fully shielded", tx: bchain.Tx{Vout: []bchain.Vout{{ValueSat: *big.NewInt(100000000)}}}, want: 100},
+	}
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parser.PrivacyScore(&tt.tx); got != tt.want {
+				t.Errorf("PrivacyScore() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeFee(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	readTx := func(file string) bchain.Tx {
+		msg, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := parser.ParseTxFromJson(msg)
+		if err != nil {
+			t.Fatalf("ParseTxFromJson() error = %v", err)
+		}
+		return *tx
+	}
+	// Zerocoin spend of 50 XZC paying 49.9 XZC
+	spendWithFee := testTx2
+	spendWithFee.Vout = []bchain.Vout{testTx2.Vout[0]}
+	spendWithFee.Vout[0].ValueSat = *big.NewInt(4990000000)
+	tests := []struct {
+		name     string
+		tx       bchain.Tx
+		prevouts []*big.Int
+		want     int64
+		wantErr  bool
+	}{
+		{name: "transparent", tx: testTx3, prevouts: []*big.Int{big.NewInt(149300100000)}, want: 100000},
+		// the minted value is part of the outputs
+		{name: "mint", tx: testTx1, prevouts: []*big.Int{big.NewInt(36378533276)}, want: 2000000},
+		{name: "spend", tx: testTx2, prevouts: []*big.Int{nil}, want: 0},
+		{name: "spend with fee", tx: spendWithFee, prevouts: []*big.Int{nil}, want: 10000000},
+		// Sigma spend of 10 XZC minted again as 10 XZC
+		{name: "remint", tx: readTx("./testdata/churntx.json"), prevouts: []*big.Int{nil}, want: 0},
+		// batched Sigma spend of 1, 10 and 100 XZC minted as 1, 10 and 4 times 25 XZC
+		{name: "redenomination", tx: readTx("./testdata/redenominationtx.json"), prevouts: []*big.Int{nil}, want: 0},
+		{name: "sigma spend with fee", tx: sigmaSpendTx(rawSigmaSpend, 500000000, 400000000), prevouts: []*big.Int{nil}, want: 100000000},
+		{name: "missing input value", tx: testTx3, prevouts: []*big.Int{nil}, wantErr: true},
+		{name: "negative", tx: testTx3, prevouts: []*big.Int{big.NewInt(100)}, wantErr: true},
+		{name: "prevouts count mismatch", tx: testTx3, prevouts: nil, wantErr: true},
+		{name: "fully shielded", tx: bchain.Tx{Vout: []bchain.Vout{{ValueSat: *big.NewInt(100000000)}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.ComputeFee(&tt.tx, tt.prevouts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ComputeFee() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.Int64() != tt.want {
+				t.Errorf("ComputeFee() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// addTestWitness adds txinwitness to all inputs of the json transaction
+func addTestWitness(t *testing.T, msg json.RawMessage, witness []string) json.RawMessage {
+	var m map[string]interface{}
+	if err := json.Unmarshal(msg, &m); err != nil {
+		t.Fatal(err)
+	}
+	for _, vin := range m["vin"].([]interface{}) {
+		vin.(map[string]interface{})["txinwitness"] = witness
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func addTestInputValue(t *testing.T, msg json.RawMessage, value json.Number) json.RawMessage {
+	var m map[string]interface{}
+	if err := json.Unmarshal(msg, &m); err != nil {
+		t.Fatal(err)
+	}
+	for _, vin := range m["vin"].([]interface{}) {
+		vin.(map[string]interface{})["value"] = value
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParseTxFromJsonInputValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		msg    json.RawMessage
+		parser *ZcoinParser
+		want   *big.Int
+	}{
+		{
+			name:   "transparent, input values disabled",
+			msg:    addTestInputValue(t, jsonTransparentTx, "1493.0001"),
+			parser: NewZcoinParser(testChainParams(), &btc.Configuration{}),
+			want:   nil,
+		},
+		{
+			name:   "transparent, input values enabled",
+			msg:    addTestInputValue(t, jsonTransparentTx, "1493.0001"),
+			parser: NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{InputValues: true}),
+			want:   big.NewInt(149300010000),
+		},
+		{
+			name:   "transparent without value",
+			msg:    jsonTransparentTx,
+			parser: NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{InputValues: true}),
+			want:   nil,
+		},
+		{
+			name:   "spend",
+			msg:    addTestInputValue(t, jsonTx, "100"),
+			parser: NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{InputValues: true}),
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := tt.parser.ParseTxFromJson(tt.msg)
+			if err != nil {
+				t.Errorf("ParseTxFromJson() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(tx.Vin[0].ValueSat, tt.want) {
+				t.Errorf("ParseTxFromJson() input value = %v, want %v", tx.Vin[0].ValueSat, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTxFromJsonSpendWitness(t *testing.T) {
+	witness := []string{"3045022100a1b2", "02c3d4"}
+	tests := []struct {
+		name   string
+		msg    json.RawMessage
+		parser *ZcoinParser
+		want   [][]byte
+	}{
+		{
+			name:   "spend, witness disabled",
+			msg:    addTestWitness(t, jsonTx, witness),
+			parser: NewZcoinParser(testChainParams(), &btc.Configuration{}),
+			want:   nil,
+		},
+		{
+			name:   "spend, witness enabled",
+			msg:    addTestWitness(t, jsonTx, witness),
+			parser: NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{SpendWitness: true}),
+			want:   [][]byte{{0x30, 0x45, 0x02, 0x21, 0x00, 0xa1, 0xb2}, {0x02, 0xc3, 0xd4}},
+		},
+		{
+			name:   "spend without witness",
+			msg:    jsonTx,
+			parser: NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{SpendWitness: true}),
+			want:   nil,
+		},
+		{
+			name:   "non spend, witness enabled",
+			msg:    addTestWitness(t, jsonAmbiguousMintTx, witness),
+			parser: NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{SpendWitness: true}),
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := tt.parser.ParseTxFromJson(tt.msg)
+			if err != nil {
+				t.Errorf("ParseTxFromJson() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(tx.Vin[0].Witness, tt.want) {
+				t.Errorf("ParseTxFromJson() witness = %v, want %v", tx.Vin[0].Witness, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpendScriptSig(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	var orig struct {
+		Vin []struct {
+			ScriptSig struct {
+				Hex string `json:"hex"`
+			} `json:"scriptSig"`
+		} `json:"vin"`
+	}
+	if err := json.Unmarshal(jsonTx, &orig); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := parser.ParseTxFromJson(jsonTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Vin[0].Txid != "" || tx.Vin[0].Coinbase == "" {
+		t.Fatalf("ParseTxFromJson() spend input not converted to coinbase input: %+v", tx.Vin[0])
+	}
+	if got := parser.SpendScriptSig(&tx.Vin[0]); got != orig.Vin[0].ScriptSig.Hex {
+		t.Errorf("SpendScriptSig() = %.20v..., want %.20v...", got, orig.Vin[0].ScriptSig.Hex)
+	}
+	packed, err := parser.PackTx(tx, 100, 1481277009)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unpacked, _, err := parser.UnpackTx(packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parser.SpendScriptSig(&unpacked.Vin[0]); got != orig.Vin[0].ScriptSig.Hex {
+		t.Errorf("SpendScriptSig() of unpacked tx = %.20v..., want %.20v...", got, orig.Vin[0].ScriptSig.Hex)
+	}
+	if got := parser.SpendScriptSig(&testTx3.Vin[0]); got != "" {
+		t.Errorf("SpendScriptSig() of transparent input = %v, want empty", got)
+	}
+
+	b, _ := hex.DecodeString(rawBlock2)
+	block, err := parser.ParseBlock(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spends := 0
+	for i := range block.Txs {
+		for _, vin := range block.Txs[i].Vin {
+			if s := parser.SpendScriptSig(&vin); s != "" {
+				if vin.ScriptSig.Hex != s {
+					t.Errorf("tx %v: spend scriptSig not preserved", block.Txs[i].Txid)
+				}
+				spends++
+			}
+		}
+	}
+	if spends == 0 {
+		t.Errorf("no privacy spends found in the block")
+	}
+}
+
+func TestGetPrivacyEvents(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	migration, err := parser.ParseTxFromJson(jsonMigrationTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{Height: 1000},
+		Txs:         []bchain.Tx{testTx4, testTx1, *migration, testTx3},
+	}
+	got := parser.GetPrivacyEvents(block)
+	want := []bchain.PrivacyEvent{
+		{Txid: testTx1.Txid, Height: 1000, Type: "zerocoinmint", Value: "18188266638"},
+		{Txid: migration.Txid, Height: 1000, Type: "zerocoinspend", Value: "2500000000"},
+		{Txid: migration.Txid, Height: 1000, Type: "sigmamint", Value: "2500000000"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetPrivacyEvents() = %+v, want %+v", got, want)
+	}
+
+	b, _ := hex.DecodeString(rawBlock2)
+	spendBlock, err := parser.ParseBlock(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spends int
+	for _, e := range parser.GetPrivacyEvents(spendBlock) {
+		if e.Type == "zerocoinspend" {
+			if e.Serial == "" || e.Value == "" {
+				t.Errorf("GetPrivacyEvents() spend without serial or value %+v", e)
+			}
+			spends++
+		}
+	}
+	if spends == 0 {
+		t.Errorf("GetPrivacyEvents() no spends in the spend block")
+	}
+}
+
+func TestParsePrivacySpend(t *testing.T) {
+	tests := []struct {
+		name    string
+		vin     bchain.Vin
+		want    *PrivacySpend
+		wantErr bool
+	}{
+		{
+			name: "zerocoin spend",
+			vin:  testTx2.Vin[0],
+			want: &PrivacySpend{
+				Type:         "zerocoinspend",
+				Denomination: "50",
+				Serial:       "21eecf480a569bb1170b4d81ccf2beafceead157fa53ba63696982717dd22dce",
+			},
+		},
+		{
+			name: "zerocoin spend as coinbase",
+			vin:  bchain.Vin{Coinbase: testTx2.Vin[0].ScriptSig.Hex},
+			want: &PrivacySpend{
+				Type:         "zerocoinspend",
+				Denomination: "50",
+				Serial:       "21eecf480a569bb1170b4d81ccf2beafceead157fa53ba63696982717dd22dce",
+			},
+		},
+		{
+			name: "sigma spend",
+			vin:  bchain.Vin{Coinbase: SpendTxID, ScriptSig: bchain.ScriptSig{Hex: "c4000102"}},
+			want: &PrivacySpend{Type: "sigmaspend"},
+		},
+		{
+			name: "sigma spend with denomination",
+			vin:  bchain.Vin{ScriptSig: bchain.ScriptSig{Hex: rawSigmaSpend}},
+			want: &PrivacySpend{
+				Type:         "sigmaspend",
+				Denomination: "10",
+				Serial:       "997a7f012a272dc170824c9f44f01e1b635fb2d0e43c51801c0b88b55b04c694",
+			},
+		},
+		{
+			name: "sigma spend with serial",
+			vin:  bchain.Vin{ScriptSig: bchain.ScriptSig{Hex: rawSigmaSpend[:24] + strings.Repeat("ab", 32) + rawSigmaSpend[88:]}},
+			want: &PrivacySpend{
+				Type:         "sigmaspend",
+				Denomination: "10",
+				Serial:       strings.Repeat("ab", 32),
+			},
+		},
+		{
+			name: "batched sigma spend",
+			vin:  bchain.Vin{ScriptSig: bchain.ScriptSig{Hex: rawSigmaSpendBatched}},
+			want: &PrivacySpend{
+				Type:         "sigmaspend",
+				Denomination: "111",
+				Serial:       "997a7f012a272dc170824c9f44f01e1b635fb2d0e43c51801c0b88b55b04c694",
+			},
+		},
+		{
+			// the proof and the accumulator block hash do not follow the serial
+			name: "sigma spend without proof",
+			vin:  bchain.Vin{ScriptSig: bchain.ScriptSig{Hex: rawSigmaSpend[:88]}},
+			want: &PrivacySpend{Type: "sigmaspend", Denomination: "10"},
+		},
+		{
+			name: "sigma spend with zero serial",
+			vin:  bchain.Vin{ScriptSig: bchain.ScriptSig{Hex: rawSigmaSpend[:24] + strings.Repeat("00", 32) + rawSigmaSpend[88:]}},
+			want: &PrivacySpend{Type: "sigmaspend", Denomination: "10"},
+		},
+		{
+			name:    "truncated zerocoin spend",
+			vin:     bchain.Vin{ScriptSig: bchain.ScriptSig{Hex: testTx2.Vin[0].ScriptSig.Hex[:40]}},
+			wantErr: true,
+		},
+		{
+			name: "transparent input",
+			vin:  testTx3.Vin[0],
+			want: nil,
+		},
+	}
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.ParsePrivacySpend(&tt.vin)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePrivacySpend() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePrivacySpend() = %+v, want %+v", got, tt.want)
+			}
+			if tt.want != nil {
+				if serial := parser.GetPrivacySpendSerial(&tt.vin); serial != tt.want.Serial {
+					t.Errorf("GetPrivacySpendSerial() = %v, want %v", serial, tt.want.Serial)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateZerocoinSpend(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	// spend from the Zerocoin era, the serialized CoinSpend starts at offset 4 after the pushed length,
+	// the coin serial number is at offset 210 with length 32
+	spend := testTx2.Vin[0].ScriptSig.Hex
+	withScript := func(script string) bchain.Vin {
+		return bchain.Vin{Coinbase: SpendTxID, ScriptSig: bchain.ScriptSig{Hex: script}, Sequence: 2}
+	}
+	tests := []struct {
+		name    string
+		vin     bchain.Vin
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			vin:  testTx2.Vin[0],
+		},
+		{
+			name: "valid as coinbase",
+			vin:  bchain.Vin{Coinbase: spend, Sequence: 2},
+		},
+		{
+			name:    "invalid denomination",
+			vin:     withScript(spend[:8] + "03000000" + spend[16:]),
+			wantErr: true,
+		},
+		{
+			name:    "truncated",
+			vin:     withScript(spend[:200]),
+			wantErr: true,
+		},
+		{
+			name:    "zero serial",
+			vin:     withScript(spend[:2*210] + strings.Repeat("00", 32) + spend[2*242:]),
+			wantErr: true,
+		},
+		{
+			name:    "missing proof",
+			vin:     withScript(spend[:2*242]),
+			wantErr: true,
+		},
+		{
+			name:    "zero accumulator id",
+			vin:     bchain.Vin{Coinbase: SpendTxID, ScriptSig: bchain.ScriptSig{Hex: spend}},
+			wantErr: true,
+		},
+		{
+			name:    "sigma spend",
+			vin:     withScript("c4000102"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parser.ValidateZerocoinSpend(&tt.vin)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateZerocoinSpend() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateZerocoinSpendParsed(t *testing.T) {
+	// the parsed spend keeps its sequence, the accumulator id, and passes the validation also after the conversion
+	for _, strict := range []bool{false, true} {
+		parser := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{StrictSpendValidation: strict})
+		tx, err := parser.ParseTxFromJson(jsonTx)
+		if err != nil {
+			t.Fatalf("ParseTxFromJson() strict %v error = %v", strict, err)
+		}
+		if tx.Vin[0].Sequence != 2 {
+			t.Errorf("Sequence of the parsed spend = %v, want 2", tx.Vin[0].Sequence)
+		}
+		if err = parser.ValidateZerocoinSpend(&tx.Vin[0]); err != nil {
+			t.Errorf("ValidateZerocoinSpend() of the parsed spend error = %v", err)
+		}
+	}
+	// the malformed spend is rejected in the strict mode before it is converted
+	var m map[string]interface{}
+	if err := json.Unmarshal(jsonTx, &m); err != nil {
+		t.Fatal(err)
+	}
+	m["vin"].([]interface{})[0].(map[string]interface{})["sequence"] = 0
+	msg, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewZcoinParser(testChainParams(), &btc.Configuration{}).ParseTxFromJson(msg); err != nil {
+		t.Errorf("ParseTxFromJson() of the malformed spend in lenient mode error = %v", err)
+	}
+	strict := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{StrictSpendValidation: true})
+	if _, err = strict.ParseTxFromJson(msg); err == nil {
+		t.Error("ParseTxFromJson() of the malformed spend in strict mode, want error")
+	}
+}
+
+func TestDecodePrivacySpendsJSON(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+
+	got, err := parser.DecodePrivacySpendsJSON(jsonTx)
+	if err != nil {
+		t.Fatalf("DecodePrivacySpendsJSON() error = %v", err)
+	}
+	var tx struct {
+		Txid string `json:"txid"`
+		Vin  []struct {
+			Coinbase     *string       `json:"coinbase"`
+			Sequence     uint32        `json:"sequence"`
+			PrivacySpend *PrivacySpend `json:"privacySpend"`
+		} `json:"vin"`
+	}
+	if err = json.Unmarshal(got, &tx); err != nil {
+		t.Fatal(err)
+	}
+	if tx.Txid != testTx2.Txid || len(tx.Vin) != 1 {
+		t.Fatalf("DecodePrivacySpendsJSON() unexpected tx %+v", tx)
+	}
+	want := &PrivacySpend{
+		Type:         "zerocoinspend",
+		Denomination: "50",
+		Serial:       "21eecf480a569bb1170b4d81ccf2beafceead157fa53ba63696982717dd22dce",
+	}
+	if !reflect.DeepEqual(tx.Vin[0].PrivacySpend, want) {
+		t.Errorf("DecodePrivacySpendsJSON() privacySpend = %+v, want %+v", tx.Vin[0].PrivacySpend, want)
+	}
+	if tx.Vin[0].Coinbase != nil || tx.Vin[0].Sequence != 2 {
+		t.Errorf("DecodePrivacySpendsJSON() unexpected vin %+v", tx.Vin[0])
+	}
+
+	// the undecodable spend is rendered only with its type
+	var m map[string]interface{}
+	if err = json.Unmarshal(jsonTx, &m); err != nil {
+		t.Fatal(err)
+	}
+	vin := m["vin"].([]interface{})[0].(map[string]interface{})
+	vin["scriptSig"] = map[string]interface{}{"hex": testTx2.Vin[0].ScriptSig.Hex[:40]}
+	truncated, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err = parser.DecodePrivacySpendsJSON(truncated); err != nil {
+		t.Fatalf("DecodePrivacySpendsJSON() of the truncated spend error = %v", err)
+	}
+	tx.Vin = nil
+	if err = json.Unmarshal(got, &tx); err != nil {
+		t.Fatal(err)
+	}
+	if want := (&PrivacySpend{Type: "zerocoinspend"}); len(tx.Vin) != 1 || !reflect.DeepEqual(tx.Vin[0].PrivacySpend, want) {
+		t.Errorf("DecodePrivacySpendsJSON() of the truncated spend vin = %+v, want privacySpend %+v", tx.Vin, want)
+	}
+
+	// transparent transaction is returned unchanged
+	transparent := json.RawMessage(`{"txid":"96ae951083651f141d1fb2719c76d47e5a3ad421b81905f679c0edb60f2de0ff","vin":[{"txid":"448ccfd9c3f375be8701b86aff355a230dbe240334233f2ed476fcae6abd295d","vout":1}],"vout":[{"value":420,"n":0,"scriptPubKey":{"hex":"76a91429bef7962c5c65a2f0f4f7d9ec791866c54f851688ac"}}]}`)
+	got, err = parser.DecodePrivacySpendsJSON(transparent)
+	if err != nil {
+		t.Fatalf("DecodePrivacySpendsJSON() error = %v", err)
+	}
+	if !bytes.Equal(got, transparent) {
+		t.Errorf("DecodePrivacySpendsJSON() changed transparent transaction")
+	}
+}
+
+func TestDumpBlock(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	b, _ := hex.DecodeString(rawBlock2)
+	block, err := parser.ParseBlock(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := parser.DumpBlock(block)
+	if err != nil {
+		t.Fatalf("DumpBlock() error = %v", err)
+	}
+	var got struct {
+		Time int64 `json:"time"`
+		Txs  []struct {
+			Privacy bool   `json:"privacy"`
+			Spent   string `json:"spent"`
+			Vin     []struct {
+				PrivacySpend *PrivacySpend `json:"privacySpend"`
+			} `json:"vin"`
+			Vout []struct {
+				ValueSat string `json:"valueSat"`
+			} `json:"vout"`
+		} `json:"tx"`
+	}
+	if err = json.Unmarshal([]byte(d), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Time != 1482107572 || len(got.Txs) != 4 {
+		t.Fatalf("DumpBlock() unexpected block %+v", got)
+	}
+	if got.Txs[0].Privacy || got.Txs[0].Vout[0].ValueSat != "4002000000" {
+		t.Errorf("DumpBlock() unexpected coinbase tx %+v", got.Txs[0])
+	}
+	spends := 0
+	for _, tx := range got.Txs {
+		for _, vin := range tx.Vin {
+			if vin.PrivacySpend != nil {
+				spends++
+				if !tx.Privacy || vin.PrivacySpend.Type != "zerocoinspend" || tx.Spent == "" {
+					t.Errorf("DumpBlock() unexpected spend tx %+v", tx)
+				}
+			}
+		}
+	}
+	if spends == 0 {
+		t.Errorf("DumpBlock() no privacy spend in the dump")
+	}
+}
+
+func TestIsPrivacyMintAddrDesc(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	tests := []struct {
+		name     string
+		addrDesc string
+		want     bool
+	}{
+		{name: "sigmamint", addrDesc: "c3" + strings.Repeat("00", 34), want: true},
+		{name: "zeromint", addrDesc: "c1" + strings.Repeat("00", 34), want: true},
+		{name: "sigmaspend", addrDesc: "c4", want: false},
+		{name: "p2pkh", addrDesc: "76a914a7f3e9e1e9a1d0fc43d84ee5dbd1e6e7c2306b0b88ac", want: false},
+		{name: "empty", addrDesc: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ad, _ := hex.DecodeString(tt.addrDesc)
+			if got := parser.IsPrivacyMintAddrDesc(ad); got != tt.want {
+				t.Errorf("IsPrivacyMintAddrDesc() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKernelHash(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	posBlock := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{Time: 1547120622},
+		Txs: []bchain.Tx{
+			{Vin: []bchain.Vin{{Coinbase: "03a1ed01"}}, Vout: []bchain.Vout{{ValueSat: *big.NewInt(0)}}},
+			{
+				Vin: []bchain.Vin{{Txid: "a1075db55d416d3ca199f55b6084e2115b9345e16c5cf302fc80e9d5fbf5d48d", Vout: 1}},
+				Vout: []bchain.Vout{
+					{ValueSat: *big.NewInt(0)},
+					{ValueSat: *big.NewInt(1000000000), ScriptPubKey: bchain.ScriptPubKey{Hex: "76a914a7f3e9e1e9a1d0fc43d84ee5dbd1e6e7c2306b0b88ac"}},
+				},
+			},
+		},
+	}
+	got, err := parser.KernelHash(posBlock, 0x1122334455667788, 1547000000)
+	if err != nil {
+		t.Fatalf("KernelHash() error = %v", err)
+	}
+	if want := "d2f5c525e9b3534c3aa54096c0183d750f6ad7b641b71f146d952580594dc3db"; got != want {
+		t.Errorf("KernelHash() = %v, want %v", got, want)
+	}
+
+	b, _ := hex.DecodeString(rawBlock1)
+	powBlock, err := parser.ParseBlock(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err = parser.KernelHash(powBlock, 0x1122334455667788, 1547000000); err != ErrNotProofOfStake || got != "" {
+		t.Errorf("KernelHash() of proof-of-work block = %v, %v", got, err)
+	}
+}
+
+func TestGetPrivacySpendAddrDesc(t *testing.T) {
+	tests := []struct {
+		name          string
+		vin           bchain.Vin
+		want          bchain.AddressDescriptor
+		wantIndexable bool
+	}{
+		{
+			name:          "zerocoin spend",
+			vin:           testTx2.Vin[0],
+			want:          bchain.AddressDescriptor{OpZeroCoinSpend},
+			wantIndexable: false,
+		},
+		{
+			name:          "sigma spend as coinbase",
+			vin:           bchain.Vin{Coinbase: "c4000102"},
+			want:          bchain.AddressDescriptor{OpSigmaSpend},
+			wantIndexable: false,
+		},
+		{
+			name: "coinbase",
+			vin:  testTx4.Vin[0],
+			want: nil,
+		},
+		{
+			name: "transparent input",
+			vin:  testTx3.Vin[0],
+			want: nil,
+		},
+	}
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parser.GetPrivacySpendAddrDesc(&tt.vin)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetPrivacySpendAddrDesc() = %v, want %v", got, tt.want)
+			}
+			if got != nil {
+				if i := parser.IsAddrDescIndexable(got); i != tt.wantIndexable {
+					t.Errorf("IsAddrDescIndexable() = %v, want %v", i, tt.wantIndexable)
+				}
+			}
+		})
+	}
+}
+
+func TestPackTx(t *testing.T) {
+	type args struct {
+		tx        bchain.Tx
+		height    uint32
+		blockTime int64
+		parser    *ZcoinParser
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "xzc-1",
+			args: args{
+				tx:        testTx1,
+				height:    100002,
+				blockTime: 1533980594,
+				parser:    NewZcoinParser(testChainParams(), &btc.Configuration{}),
+			},
+			want:    testTxPacked1,
+			wantErr: false,
+		},
+		// FIXME: work around handle zerocoin spend as coinbase
+		// {
+		// 	name: "xzc-2",
+		// 	args: args{
+		// 		tx:        testTx2,
+		// 		height:    11002,
+		// 		blockTime: 1481277009,
+		// 		parser:    NewZcoinParser(testChainParams(), &btc.Configuration{}),
+		// 	},
+		// 	want:    testTxPacked2,
+		// 	wantErr: true,
+		// },
+		{
+			name: "xzc-3",
+			args: args{
+				tx:        testTx3,
+				height:    126202,
+				blockTime: 1547091829,
+				parser:    NewZcoinParser(testChainParams(), &btc.Configuration{}),
+			},
+			want:    testTxPacked3,
+			wantErr: false,
+		},
+		{
+			name: "xzc-coinbase",
+			args: args{
+				tx:        testTx4,
+				height:    100001,
+				blockTime: 1533977563,
+				parser:    NewZcoinParser(testChainParams(), &btc.Configuration{}),
+			},
+			want:    testTxPacked4,
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.args.parser.PackTx(&tt.args.tx, tt.args.height, tt.args.blockTime)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("packTx() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr {
+				h := hex.EncodeToString(got)
+				if !reflect.DeepEqual(h, tt.want) {
+					t.Errorf("packTx() = %v, want %v", h, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUnpackTx(t *testing.T) {
+	type args struct {
+		packedTx string
+		parser   *ZcoinParser
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *bchain.Tx
+		want1   uint32
+		wantErr bool
+	}{
+		{
+			name: "xzc-1",
+			args: args{
+				packedTx: testTxPacked1,
+				parser:   NewZcoinParser(testChainParams(), &btc.Configuration{}),
+			},
+			want:    &testTx1,
+			want1:   100002,
+			wantErr: false,
+		},
+		// FIXME: work around handle zerocoin spend as coinbase
+		// {
+		// 	name: "xzc-2",
+		// 	args: args{
+		// 		packedTx: testTxPacked2,
+		// 		parser:   NewZcoinParser(testChainParams(), &btc.Configuration{}),
+		// 	},
+		// 	want:    &testTx2,
+		// 	want1:   11002,
+		// 	wantErr: true,
+		// },
+		{
+			name: "xzc-3",
+			args: args{
+				packedTx: testTxPacked3,
+				parser:   NewZcoinParser(testChainParams(), &btc.Configuration{}),
+			},
+			want:    &testTx3,
+			want1:   126202,
+			wantErr: false,
+		},
+		{
+			name: "xzc-coinbase",
+			args: args{
+				packedTx: testTxPacked4,
+				parser:   NewZcoinParser(testChainParams(), &btc.Configuration{}),
+			},
+			want:    &testTx4,
+			want1:   100001,
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := hex.DecodeString(tt.args.packedTx)
+			got, got1, err := tt.args.parser.UnpackTx(b)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unpackTx() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr {
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("unpackTx() got = %v, want %v", got, tt.want)
+				}
+				if got1 != tt.want1 {
+					t.Errorf("unpackTx() got1 = %v, want %v", got1, tt.want1)
+				}
+			}
+		})
+	}
+}
+
+// newUint32TxCountParser returns parser of a fork which stores the number of transactions as uint32 little endian
+func newUint32TxCountParser() *ZcoinParser {
+	p := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	p.TxCountReader = func(r io.Reader) (uint64, error) {
+		var n uint32
+		err := binary.Read(r, binary.LittleEndian, &n)
+		return uint64(n), err
+	}
+	return p
+}
+
+func TestCoinShortcutAndDecimals(t *testing.T) {
+	for _, shortcut := range []string{"XZC", "tXZC"} {
+		parser := NewZcoinParser(testChainParams(), &btc.Configuration{CoinShortcut: shortcut})
+		if got := parser.CoinShortcut(); got != shortcut {
+			t.Errorf("CoinShortcut() = %v, want %v", got, shortcut)
+		}
+		if got := parser.AmountDecimals(); got != 8 {
+			t.Errorf("AmountDecimals() = %v, want 8", got)
+		}
+	}
+}
+
+func TestParseBlockTxOutCount(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	header := rawBlock2[:160]
+	// one transaction with one input without script, followed by the declared number of outputs and a single output
+	tx := "01000000" + "01" + strings.Repeat("00", 36) + "00" + "ffffffff"
+	output := "0000000000000000" + "00"
+	tests := []struct {
+		name    string
+		vout    string
+		wantErr string
+	}{
+		{
+			name: "plausible",
+			vout: "01" + output + "00000000",
+		},
+		{
+			name:    "declared count over remaining data",
+			vout:    "fdffff" + output + "00000000",
+			wantErr: "Tx 0: Number of outputs 65535",
+		},
+		{
+			name:    "declared count over maximum",
+			vout:    "ffffffffffffffff0f" + output + "00000000",
+			wantErr: "Tx 0: Number of outputs 1152921504606846975",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := hex.DecodeString(header + "01" + tx + tt.vout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = parser.ParseBlock(b)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ParseBlock() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("ParseBlock() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseBlockConsumesAllData(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	tests := []struct {
+		name     string
+		rawBlock string
+		wantErr  string
+	}{
+		{name: "trailing data", rawBlock: rawBlockTrailing, wantErr: "Trailing data, 4 bytes after 4 transactions"},
+		{name: "short block", rawBlock: rawBlockShort, wantErr: "Short block, tx 4 of 5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := hex.DecodeString(tt.rawBlock)
+			_, err := parser.ParseBlock(b)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("ParseBlock() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseBlockVersion(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	// proof-of-stake block with the coinbase and the coinstake transaction, signaling bits 0 and 1
+	coinbase := "01000000" + "01" + strings.Repeat("00", 32) + "ffffffff" + "0403a1ed01" + "ffffffff" +
+		"01" + "0000000000000000" + "00" + "00000000"
+	coinstake := "01000000" + "01" + "8dd4f5fbd5e980fc02f35c6ce145935b11e284605bf599a13c6d415db55d07a1" + "01000000" + "00" + "ffffffff" +
+		"02" + "0000000000000000" + "00" + "00ca9a3b00000000" + "1976a914a7f3e9e1e9a1d0fc43d84ee5dbd1e6e7c2306b0b88ac" + "00000000"
+	rawPosBlock := "03000020" + rawBlock2[8:160] + "02" + coinbase + coinstake
+	tests := []struct {
+		name      string
+		rawBlock  string
+		want      int32
+		coinstake bool
+	}{
+		{name: "proof-of-work MTP block", rawBlock: rawBlock1, want: 0x20001000},
+		{name: "proof-of-work block", rawBlock: rawBlock2, want: 0x00010002},
+		{name: "proof-of-stake block", rawBlock: rawPosBlock, want: 0x20000003, coinstake: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := hex.DecodeString(tt.rawBlock)
+			got, err := parser.ParseBlock(b)
+			if err != nil {
+				t.Fatalf("ParseBlock() error = %v", err)
+			}
+			if got.Version != tt.want {
+				t.Errorf("ParseBlock() version = %#x, want %#x", got.Version, tt.want)
+			}
+			if coinstake := len(got.Txs) > 1 && bchain.IsCoinstakeTx(&got.Txs[1]); coinstake != tt.coinstake {
+				t.Errorf("ParseBlock() coinstake = %v, want %v", coinstake, tt.coinstake)
+			}
+		})
+	}
+}
+
+func TestTxSizes(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	// segwit transaction spending P2WPKH output: base 82 bytes, witness 109 bytes, weight 437
+	mtx := wire.NewMsgTx(1)
+	mtx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: 1},
+		Sequence:         wire.MaxTxInSequenceNum,
+		Witness:          wire.TxWitness{make([]byte, 71), make([]byte, 33)},
+	})
+	mtx.AddTxOut(wire.NewTxOut(100000, make([]byte, 22)))
+	var buf bytes.Buffer
+	if err := mtx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	tx := bchain.Tx{Hex: hex.EncodeToString(buf.Bytes())}
+	parser.setTxSizesFromHex(&tx)
+	if tx.BaseSize != 82 || tx.WitnessSize != 109 || tx.VSize != 110 {
+		t.Errorf("segwit tx sizes = %d, %d, %d, want 82, 109, 110", tx.BaseSize, tx.WitnessSize, tx.VSize)
+	}
+	if weight := tx.BaseSize*3 + tx.BaseSize + tx.WitnessSize; tx.VSize != (weight+3)/4 {
+		t.Errorf("segwit tx vsize %d does not match weight %d", tx.VSize, weight)
+	}
+
+	// the transactions without witness have vsize equal to the size
+	ptx, err := parser.ParseTxFromJson(jsonTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ptx.BaseSize != 23821 || ptx.WitnessSize != 0 || ptx.VSize != 23821 {
+		t.Errorf("spend tx sizes = %d, %d, %d, want 23821, 0, 23821", ptx.BaseSize, ptx.WitnessSize, ptx.VSize)
+	}
+	// the sizes are not set without the hex
+	if ptx, err = parser.ParseTxFromJson(jsonTransparentTx); err != nil || ptx.VSize != 0 {
+		t.Errorf("transparent tx without hex vsize = %d, error %v, want 0", ptx.VSize, err)
+	}
+	b, _ := hex.DecodeString(rawBlock2)
+	block, err := parser.ParseBlock(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := 0
+	for i := range block.Txs {
+		btx := &block.Txs[i]
+		if btx.VSize == 0 || btx.VSize != btx.BaseSize || btx.WitnessSize != 0 {
+			t.Errorf("block tx %d sizes = %d, %d, %d", i, btx.BaseSize, btx.WitnessSize, btx.VSize)
+		}
+		size += btx.BaseSize
+	}
+	// the block consists of the 80 bytes header, the transaction count and the transactions
+	if size+81 != block.Size {
+		t.Errorf("block tx sizes sum %d does not match block size %d", size, block.Size)
+	}
+}
+
+func TestIsMTPActivated(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	tests := []struct {
+		name      string
+		blockTime int64
+		want      bool
+	}{
+		{name: "genesis", blockTime: GenesisBlockTime, want: false},
+		{name: "before switch", blockTime: SwitchToMTPBlockHeader - 1, want: false},
+		{name: "at switch", blockTime: SwitchToMTPBlockHeader, want: true},
+		{name: "after switch", blockTime: SwitchToMTPBlockHeader + 1, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parser.IsMTPActivated(tt.blockTime); got != tt.want {
+				t.Errorf("IsMTPActivated(%d) = %v, want %v", tt.blockTime, got, tt.want)
+			}
+			h := &wire.BlockHeader{Timestamp: time.Unix(tt.blockTime, 0)}
+			if got := parser.isMTP(h); got != tt.want {
+				t.Errorf("isMTP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSerializeBlock(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	for _, rawBlock := range []string{rawBlock2, rawBlockEmptyVin} {
+		b, _ := hex.DecodeString(rawBlock)
+		block, err := parser.ParseBlock(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := parser.SerializeBlock(block)
+		if err != nil {
+			t.Fatalf("SerializeBlock() error = %v", err)
+		}
+		if !bytes.Equal(got, b) {
+			t.Errorf("SerializeBlock() does not round-trip the block with %d transactions", len(block.Txs))
+		}
+	}
+
+	// MTP block is serialized without the MTP data
+	b, _ := hex.DecodeString(rawBlock1)
+	block, err := parser.ParseBlock(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := parser.SerializeBlock(block)
+	if err != nil {
+		t.Fatalf("SerializeBlock() error = %v", err)
+	}
+	if !bytes.Equal(got[:80], b[:80]) || len(got) >= len(b) {
+		t.Errorf("SerializeBlock() of MTP block, size %d, raw size %d", len(got), len(b))
+	}
+	var mb wire.MsgBlock
+	if err = mb.Deserialize(bytes.NewReader(got)); err != nil {
+		t.Fatalf("Deserialize() of MTP block error = %v", err)
+	}
+	if len(mb.Transactions) != len(block.Txs) || mb.Transactions[0].TxHash().String() != block.Txs[0].Txid {
+		t.Errorf("Deserialize() of MTP block transactions differ")
+	}
+
+	if _, err = parser.SerializeBlock(&bchain.Block{}); err == nil {
+		t.Error("SerializeBlock() without header, want error")
+	}
+}
+
+func TestSpendTxInRoundTrip(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	script, err := hex.DecodeString(rawSigmaSpend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, inputs := range []int{1, 2} {
+		mtx := wire.NewMsgTx(1)
+		for i := 0; i < inputs; i++ {
+			mtx.AddTxIn(&wire.TxIn{
+				PreviousOutPoint: wire.OutPoint{Index: wire.MaxPrevOutIndex},
+				SignatureScript:  script,
+				Sequence:         uint32(i + 1),
+			})
+		}
+		mtx.AddTxOut(wire.NewTxOut(100000000, []byte{0x76, 0xa9, 0x14, 0x0b, 0x4b, 0xfb, 0x25, 0x6e, 0xf4, 0xbf, 0xa3, 0x60, 0xe3, 0xb9, 0xe6, 0x6e, 0x53, 0xa0, 0xbd, 0x84, 0xd1, 0x96, 0xbc, 0x88, 0xac}))
+		var buf bytes.Buffer
+		if err = mtx.Serialize(&buf); err != nil {
+			t.Fatal(err)
+		}
+		tx, err := parser.decodeTx(bytes.NewReader(buf.Bytes()), wire.WitnessEncoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		btx := parser.TxFromMsgTx(tx, false)
+		if err = parser.parseZcoinTx(&btx); err != nil {
+			t.Fatal(err)
+		}
+		for i := range btx.Vin {
+			if btx.Vin[i].Txid != "" {
+				t.Fatalf("%d inputs: spend input %d not parsed as coinbase input, %+v", inputs, i, btx.Vin[i])
+			}
+		}
+		got, err := msgTxFromTx(&btx)
+		if err != nil {
+			t.Fatalf("%d inputs: msgTxFromTx() error = %v", inputs, err)
+		}
+		if got.TxHash() != mtx.TxHash() {
+			t.Errorf("%d inputs: msgTxFromTx() txid = %v, want %v", inputs, got.TxHash(), mtx.TxHash())
+		}
+	}
+}
+
+func TestGenesisParams(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	if g := parser.Genesis(); g != (GenesisParams{Time: GenesisBlockTime}) {
+		t.Errorf("Genesis() = %+v, want default time only", g)
+	}
+	if parser.IsGenesisBlock("") || parser.IsGenesisCoinbase("") {
+		t.Error("IsGenesisBlock or IsGenesisCoinbase true without configured genesis")
+	}
+	fork := NewZcoinParser(testChainParams(), &btc.Configuration{
+		GenesisBlockTime:    1600000000,
+		GenesisBlockHash:    "00000a308cc3b469703a3bc8d35ea2e2b6a4d9e0c1ab7e4e3e6b4d2bd5ea4a32",
+		GenesisCoinbaseTxid: "b79e6b4bd2e5e09ba6e2e79c5a2bd4d5f6f3e3d1c8a4d9e1b7e4e3e6b4d2bd5e",
+	})
+	if !fork.IsGenesisBlock("00000a308cc3b469703a3bc8d35ea2e2b6a4d9e0c1ab7e4e3e6b4d2bd5ea4a32") {
+		t.Error("IsGenesisBlock() of fork genesis = false")
+	}
+	if !fork.IsGenesisCoinbase("b79e6b4bd2e5e09ba6e2e79c5a2bd4d5f6f3e3d1c8a4d9e1b7e4e3e6b4d2bd5e") {
+		t.Error("IsGenesisCoinbase() of fork genesis coinbase = false")
+	}
+	if fork.IsGenesisBlock("4381deb85b1b2c9843c222944b616d997516dcbd6a964e1eaf0def0830695233") {
+		t.Error("IsGenesisBlock() of other hash = true")
+	}
+	// a header after the switch to MTP, but not after the fork genesis, is not MTP header
+	h := &wire.BlockHeader{Timestamp: time.Unix(1550000000, 0)}
+	if !parser.isMTP(h) {
+		t.Error("isMTP() = false, want true")
+	}
+	if fork.isMTP(h) {
+		t.Error("isMTP() of fork before genesis = true, want false")
+	}
+}
+
+// singleTxBlock returns the serialized block with the header of rawBlock2 and its coinbase transaction only,
+// the merkle root of the header is set to root or, if nil, to the hash of the coinbase transaction
+func singleTxBlock(t *testing.T, root *chainhash.Hash) string {
+	b, err := hex.DecodeString(rawBlock2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block wire.MsgBlock
+	if err := block.BtcDecode(bytes.NewReader(b), 0, wire.BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	block.Transactions = block.Transactions[:1]
+	if root == nil {
+		h := block.Transactions[0].TxHash()
+		root = &h
+	}
+	block.Header.MerkleRoot = *root
+	var buf bytes.Buffer
+	if err := block.BtcEncode(&buf, 0, wire.BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(buf.Bytes())
+}
+
+func TestParseBlockMerkleRoot(t *testing.T) {
+	strict := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{StrictMerkleRoot: true})
+	tests := []struct {
+		name     string
+		rawBlock string
+		parser   *ZcoinParser
+		wantTxs  int
+		wantErr  bool
+	}{
+		{name: "mtp block", rawBlock: rawBlock1, parser: strict, wantTxs: 3},
+		{name: "spend block", rawBlock: rawBlock2, parser: strict, wantTxs: 4},
+		{name: "single tx", rawBlock: singleTxBlock(t, nil), parser: strict, wantTxs: 1},
+		{name: "single tx mismatch", rawBlock: singleTxBlock(t, &chainhash.Hash{1}), parser: strict, wantErr: true},
+		{name: "corrupted", rawBlock: rawBlockBadMerkle, parser: strict, wantErr: true},
+		{name: "corrupted not strict", rawBlock: rawBlockBadMerkle, parser: NewZcoinParser(testChainParams(), &btc.Configuration{}), wantTxs: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := hex.DecodeString(tt.rawBlock)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tt.parser.ParseBlock(b)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBlock() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), "Merkle root mismatch") {
+					t.Errorf("ParseBlock() error = %v, want merkle root mismatch", err)
+				}
+				return
+			}
+			if len(got.Txs) != tt.wantTxs {
+				t.Errorf("ParseBlock() number of transactions = %v, want %v", len(got.Txs), tt.wantTxs)
+			}
+		})
+	}
+}
+
+func TestParseBlock(t *testing.T) {
+	type args struct {
+		rawBlock string
+		parser   *ZcoinParser
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *bchain.Block
+		wantTxs int
+		wantErr bool
+	}{
+		{
+			name: "normal-block",
+			args: args{
+				rawBlock: rawBlock1,
+				parser:   NewZcoinParser(testChainParams(), &btc.Configuration{}),
+			},
+			want: &bchain.Block{
+				BlockHeader: bchain.BlockHeader{
+					Size: 200286,
+					Time: 1547120622,
+				},
+			},
+			wantTxs: 3,
+			wantErr: false,
+		},
+		{
+			name: "spend-block",
+			args: args{
+				rawBlock: rawBlock2,
+				parser:   NewZcoinParser(testChainParams(), &btc.Configuration{}),
+			},
+			want: &bchain.Block{
+				BlockHeader: bchain.BlockHeader{
+					Size: 25298,
+					Time: 1482107572,
+				},
+			},
+			wantTxs: 4,
+			wantErr: false,
+		},
+		{
+			name: "normal-block-after-segwit-activation",
+			args: args{
+				rawBlock: rawBlock1,
+				parser:   NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{SegwitActivationTime: 1500000000}),
+			},
+			want: &bchain.Block{
+				BlockHeader: bchain.BlockHeader{
+					Size: 200286,
+					Time: 1547120622,
+				},
+			},
+			wantTxs: 3,
+			wantErr: false,
+		},
+		{
+			name: "block-exceeding-max-block-size",
+			args: args{
+				rawBlock: rawBlock2,
+				parser:   NewZcoinParser(testChainParams(), &btc.Configuration{MaxBlockSize: 20000}),
+			},
+			want:    nil,
+			wantTxs: 0,
+			wantErr: true,
+		},
+		{
+			name: "block-with-uint32-tx-count",
+			args: args{
+				rawBlock: rawBlockTxCount,
+				parser:   newUint32TxCountParser(),
+			},
+			want: &bchain.Block{
+				BlockHeader: bchain.BlockHeader{
+					Size: 25301,
+					Time: 1482107572,
+				},
+			},
+			wantTxs: 4,
+			wantErr: false,
+		},
+		{
+			name: "block-with-uint32-tx-count-default-reader",
+			args: args{
+				rawBlock: rawBlockTxCount,
+				parser:   NewZcoinParser(testChainParams(), &btc.Configuration{}),
+			},
+			want:    nil,
+			wantTxs: 0,
+			wantErr: true,
+		},
+		{
+			name: "normal-block-coinbase-time-source",
+			args: args{
+				rawBlock: rawBlock1,
+				parser:   NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{BlockTimeSource: BlockTimeSourceCoinbase}),
+			},
+			// the coinbase timestamp matches the header timestamp
+			want: &bchain.Block{
+				BlockHeader: bchain.BlockHeader{
+					Size: 200286,
+					Time: 1547120622,
+				},
+			},
+			wantTxs: 3,
+			wantErr: false,
+		},
+		{
+			name: "spend-block-coinbase-time-source",
+			args: args{
+				rawBlock: rawBlock2,
+				parser:   NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{BlockTimeSource: BlockTimeSourceCoinbase}),
+			},
+			// the coinbase timestamp differs from the header timestamp
+			want: &bchain.Block{
+				BlockHeader: bchain.BlockHeader{
+					Size: 25298,
+					Time: 1482107578,
+				},
+				HeaderTime: 1482107572,
+			},
+			wantTxs: 4,
+			wantErr: false,
+		},
+		{
+			name: "spend-block-coinbase-time-source-fork-genesis",
+			args: args{
+				rawBlock: rawBlock2,
+				parser: NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{GenesisBlockTime: 1500000000},
+					&Configuration{BlockTimeSource: BlockTimeSourceCoinbase}),
+			},
+			// the coinbase timestamp before the genesis of the fork is ignored
+			want: &bchain.Block{
+				BlockHeader: bchain.BlockHeader{
+					Size: 25298,
+					Time: 1482107572,
+				},
+			},
+			wantTxs: 4,
+			wantErr: false,
+		},
+		{
+			name: "empty-vin-block-witness-encoding",
+			args: args{
+				rawBlock: rawBlockEmptyVin,
+				parser:   NewZcoinParser(testChainParams(), &btc.Configuration{}),
+			},
+			want: &bchain.Block{
+				BlockHeader: bchain.BlockHeader{
+					Size: 418,
+					Time: 1482107572,
+				},
+			},
+			wantTxs: 2,
+			wantErr: false,
+		},
+		{
+			name: "empty-vin-block-strict-witness-version",
+			args: args{
+				rawBlock: rawBlockEmptyVin,
+				parser:   NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{SegwitTxVersions: []int32{1}}),
+			},
+			want:    nil,
+			wantTxs: 0,
+			wantErr: true,
+		},
+		{
+			name: "empty-vin-block-base-encoding-fallback",
+			args: args{
+				rawBlock: rawBlockEmptyVin,
+				parser:   NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{SegwitTxVersions: []int32{2}}),
+			},
+			want: &bchain.Block{
+				BlockHeader: bchain.BlockHeader{
+					Size: 418,
+					Time: 1482107572,
+				},
+			},
+			wantTxs: 2,
+			wantErr: false,
+		},
+		{
+			name: "empty-vin-block-segwit-tx-version",
+			args: args{
+				rawBlock: rawBlockEmptyVin,
+				parser:   NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{SegwitTxVersions: []int32{1, 2}}),
+			},
+			want:    nil,
+			wantTxs: 0,
+			wantErr: true,
+		},
+		{
+			name: "spend-block-segwit-tx-versions",
+			args: args{
+				rawBlock: rawBlock2,
+				parser:   NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{SegwitTxVersions: []int32{1, 2}}),
+			},
+			want: &bchain.Block{
+				BlockHeader: bchain.BlockHeader{
+					Size: 25298,
+					Time: 1482107572,
+				},
+			},
+			wantTxs: 4,
+			wantErr: false,
+		},
+		{
+			name: "spend-block-before-segwit-activation",
+			args: args{
+				rawBlock: rawBlock2,
+				parser:   NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{SegwitActivationTime: 1500000000}),
+			},
+			want: &bchain.Block{
+				BlockHeader: bchain.BlockHeader{
+					Size: 25298,
+					Time: 1482107572,
+				},
 			},
 			wantTxs: 4,
 			wantErr: false,
@@ -614,21 +2684,1252 @@ func TestParseBlock(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			b, _ := hex.DecodeString(tt.args.rawBlock)
-			got, err := tt.args.parser.ParseBlock(b)
+			b, _ := hex.DecodeString(tt.args.rawBlock)
+			got, err := tt.args.parser.ParseBlock(b)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseBlock() error = %+v", err)
+			}
+
+			if got != nil {
+
+				if !reflect.DeepEqual(got.BlockHeader, tt.want.BlockHeader) {
+					t.Errorf("parseBlock() got = %v, want %v", got.BlockHeader, tt.want.BlockHeader)
+				}
+
+				if got.HeaderTime != tt.want.HeaderTime {
+					t.Errorf("parseBlock() header time got = %d, want %d", got.HeaderTime, tt.want.HeaderTime)
+				}
+
+				if len(got.Txs) != tt.wantTxs {
+					t.Errorf("parseBlock() txs length got = %d, want %d", len(got.Txs), tt.wantTxs)
+				}
+
+				for i := range got.Txs {
+					if got.Txs[i].BlockPosition != i {
+						t.Errorf("parseBlock() tx %d position got = %d", i, got.Txs[i].BlockPosition)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParseBlockSegwitMarker(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *Configuration
+		wantVin  int
+		wantVout int
+	}{
+		{
+			name:     "before-segwit-activation",
+			config:   &Configuration{SegwitActivationTime: 1500000000},
+			wantVin:  0,
+			wantVout: 1,
+		},
+		{
+			// the block after the activation is decoded in the witness encoding,
+			// the marker and flag are read from the zero input count and the output count
+			name:     "after-segwit-activation",
+			config:   &Configuration{SegwitActivationTime: 1400000000},
+			wantVin:  0,
+			wantVout: 2,
+		},
+	}
+	b, _ := hex.DecodeString(rawBlockSegwitMarker)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, tt.config)
+			block, err := parser.ParseBlock(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(block.Txs) != 2 {
+				t.Fatalf("ParseBlock() txs length got = %d, want 2", len(block.Txs))
+			}
+			// the coinbase is not affected by the encoding
+			if got := len(block.Txs[0].Vout); got != 6 {
+				t.Errorf("coinbase vout length got = %d, want 6", got)
+			}
+			tx := block.Txs[1]
+			if len(tx.Vin) != tt.wantVin || len(tx.Vout) != tt.wantVout {
+				t.Errorf("tx 1 vin, vout length got = %d, %d, want %d, %d", len(tx.Vin), len(tx.Vout), tt.wantVin, tt.wantVout)
+			}
+		})
+	}
+	parser := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{SegwitActivationTime: 1500000000})
+	block, err := parser.ParseBlock(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vout := block.Txs[1].Vout[0]
+	if vout.ValueSat.Int64() != 512 || vout.ScriptPubKey.Hex != "6a00071122334455667700" {
+		t.Errorf("tx 1 vout = %v %v, want 512 6a00071122334455667700", vout.ValueSat.String(), vout.ScriptPubKey.Hex)
+	}
+}
+
+// the benchmarks compare the parsing of the same transactions from the verbose JSON returned by the backend
+// and from the raw data as in ParseBlock, the raw decoding (without the output addresses) is expected to be
+// about twice as fast for both transparent transactions and Sigma spends
+// run by go test -tags unittest -bench ParseTxFrom ./bchain/coins/xzc
+var benchmarkTxs = []struct {
+	name string
+	json *json.RawMessage
+	raw  *bchain.Tx
+}{
+	{name: "transparent", json: &jsonTransparentTx, raw: &testTx3},
+	{name: "sigma-spend", json: &jsonTx, raw: &testTx2},
+	{name: "sigma-spend-500-inputs", json: &jsonSpendTx500, raw: &testSpendTx500},
+}
+
+var (
+	jsonSpendTx500 json.RawMessage
+	testSpendTx500 bchain.Tx
+)
+
+// spendTxWithInputs returns the sigma spend transaction with its spend input repeated to n inputs,
+// both as the JSON message and as the tx with the raw hex
+func spendTxWithInputs(n int) (json.RawMessage, bchain.Tx) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(jsonTx, &m); err != nil {
+		panic(err)
+	}
+	vin := m["vin"].([]interface{})
+	vins := make([]interface{}, n)
+	for i := range vins {
+		vins[i] = vin[0]
+	}
+	m["vin"] = vins
+	msg, err := json.Marshal(m)
+	if err != nil {
+		panic(err)
+	}
+	raw, err := hex.DecodeString(testTx2.Hex)
+	if err != nil {
+		panic(err)
+	}
+	tx := wire.MsgTx{}
+	if err = tx.BtcDecode(bytes.NewReader(raw), 0, wire.WitnessEncoding); err != nil {
+		panic(err)
+	}
+	txIn := make([]*wire.TxIn, n)
+	for i := range txIn {
+		txIn[i] = tx.TxIn[0]
+	}
+	tx.TxIn = txIn
+	var buf bytes.Buffer
+	if err = tx.BtcEncode(&buf, 0, wire.WitnessEncoding); err != nil {
+		panic(err)
+	}
+	return msg, bchain.Tx{Hex: hex.EncodeToString(buf.Bytes())}
+}
+
+func BenchmarkParseTxFromJson(b *testing.B) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	for _, bt := range benchmarkTxs {
+		b.Run(bt.name, func(b *testing.B) {
+			b.SetBytes(int64(len(*bt.json)))
+			for i := 0; i < b.N; i++ {
+				if _, err := parser.ParseTxFromJson(*bt.json); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParseTxFromRaw(b *testing.B) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	for _, bt := range benchmarkTxs {
+		raw, err := hex.DecodeString(bt.raw.Hex)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(bt.name, func(b *testing.B) {
+			b.SetBytes(int64(len(raw)))
+			for i := 0; i < b.N; i++ {
+				tx, err := parser.decodeTx(bytes.NewReader(raw), wire.WitnessEncoding)
+				if err != nil {
+					b.Fatal(err)
+				}
+				btx := parser.TxFromMsgTx(tx, false)
+				if err = parser.parseZcoinTx(&btx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestFinalityConfirmations(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	if got := parser.FinalityConfirmations(); got != FinalityConfirmations {
+		t.Errorf("FinalityConfirmations() = %v, want %v", got, FinalityConfirmations)
+	}
+	parser = NewZcoinParser(testChainParams(), &btc.Configuration{FinalityConfirmations: 30})
+	if got := parser.FinalityConfirmations(); got != 30 {
+		t.Errorf("FinalityConfirmations() = %v, want 30", got)
+	}
+}
+
+func Test_isCoinbaseTx(t *testing.T) {
+	spend := testTx2.Vin[0].ScriptSig.Hex
+	tests := []struct {
+		name string
+		tx   bchain.Tx
+		want bool
+	}{
+		{name: "coinbase", tx: testTx4, want: true},
+		{name: "transparent", tx: testTx1},
+		// the single input privacy spend is converted to the input without prevout
+		{name: "zerocoin spend", tx: bchain.Tx{Vin: []bchain.Vin{{Coinbase: SpendTxID, ScriptSig: bchain.ScriptSig{Hex: spend}}}}},
+		{name: "spend decoded as coinbase", tx: bchain.Tx{Vin: []bchain.Vin{{Coinbase: spend, ScriptSig: bchain.ScriptSig{Hex: spend}}}}},
+		{name: "sigma spend", tx: bchain.Tx{Vin: []bchain.Vin{{Coinbase: "c4"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isCoinbaseTx(&tt.tx); got != tt.want {
+				t.Errorf("isCoinbaseTx() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBlockTxComment(t *testing.T) {
+	b, _ := hex.DecodeString(rawBlockComment)
+	// without the configured comment versions the comment is not expected
+	if _, err := NewZcoinParser(testChainParams(), &btc.Configuration{}).ParseBlock(b); err == nil {
+		t.Error("ParseBlock() without comment versions, want error")
+	}
+	parser := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{CommentTxVersions: []int32{2}})
+	block, err := parser.ParseBlock(b)
+	if err != nil {
+		t.Fatalf("ParseBlock() error = %v", err)
+	}
+	if len(block.Txs) != 2 {
+		t.Fatalf("ParseBlock() number of txs = %d, want 2", len(block.Txs))
+	}
+	if got := block.Txs[0].Comment; got != "IndexChain deposit 42" {
+		t.Errorf("comment of tx 0 = %q, want %q", got, "IndexChain deposit 42")
+	}
+	// the txid of the commented transaction is its merkle leaf, the block is accepted with the strict merkle root check
+	strict := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{CommentTxVersions: []int32{2}, StrictMerkleRoot: true})
+	strictBlock, err := strict.ParseBlock(b)
+	if err != nil {
+		t.Fatalf("ParseBlock() with strict merkle root error = %v", err)
+	}
+	var leaves []chainhash.Hash
+	for i := range strictBlock.Txs {
+		h, err := chainhash.NewHashFromStr(strictBlock.Txs[i].Txid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaves = append(leaves, *h)
+	}
+	var header wire.BlockHeader
+	if err = header.Deserialize(bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+	if root := merkleRoot(leaves); root != header.MerkleRoot {
+		t.Errorf("merkle root of the txids = %v, want %v", root, header.MerkleRoot)
+	}
+	if block.Txs[0].Txid != strictBlock.Txs[0].Txid {
+		t.Errorf("txid of tx 0 = %v, with strict merkle root %v", block.Txs[0].Txid, strictBlock.Txs[0].Txid)
+	}
+	// the transaction following the commented one is aligned
+	if got := block.Txs[1].Comment; got != "" {
+		t.Errorf("comment of tx 1 = %q, want empty", got)
+	}
+	b2, _ := hex.DecodeString(rawBlockEmptyVin)
+	plain, err := parser.ParseBlock(b2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(block.Txs[1], plain.Txs[1]) {
+		t.Errorf("tx 1 = %+v, want %+v", block.Txs[1], plain.Txs[1])
+	}
+	// the comment is counted in the size of the transaction
+	if got, want := block.Txs[0].VSize, plain.Txs[0].VSize+22; got != want {
+		t.Errorf("vsize of tx 0 = %d, want %d", got, want)
+	}
+	got, err := parser.SerializeBlock(block)
+	if err != nil {
+		t.Fatalf("SerializeBlock() error = %v", err)
+	}
+	if !bytes.Equal(got, b) {
+		t.Error("SerializeBlock() does not round-trip the block with the comment")
+	}
+}
+
+func TestAnonymitySetSize(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	tally := MintTally{}
+	tally.AddEvents([]bchain.PrivacyEvent{
+		{Type: "zerocoinmint", Value: "5000000000"},
+		{Type: "zerocoinmint", Value: "5000000000"},
+		{Type: "zerocoinmint", Value: "1000000000"},
+		{Type: "sigmamint", Value: "5000000000"},
+		{Type: "zerocoinspend", Value: "5000000000"},
+	})
+	tally.AddEvents([]bchain.PrivacyEvent{{Type: "zerocoinmint", Value: "5000000000"}})
+	sigmaSpend := bchain.Vin{Coinbase: "c4"}
+	tests := []struct {
+		name         string
+		vin          bchain.Vin
+		denomination *big.Int
+		want         int
+		wantErr      bool
+	}{
+		{name: "zerocoin spend of 50", vin: testTx2.Vin[0], want: 3},
+		{name: "zerocoin spend ignores denomination", vin: testTx2.Vin[0], denomination: big.NewInt(100000000), want: 3},
+		{name: "sigma spend of 50", vin: sigmaSpend, denomination: big.NewInt(5000000000), want: 1},
+		{name: "sigma spend of 10", vin: sigmaSpend, denomination: big.NewInt(1000000000), want: 0},
+		{name: "sigma spend without denomination", vin: sigmaSpend, wantErr: true},
+		{name: "transparent input", vin: testTx3.Vin[0], denomination: big.NewInt(5000000000), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.AnonymitySetSize(&tt.vin, tt.denomination, tally)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AnonymitySetSize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("AnonymitySetSize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBlockSegwitSigmaSpend(t *testing.T) {
+	parser := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{SpendWitness: true})
+	b, _ := hex.DecodeString(rawBlockSegwitSigma)
+	block, err := parser.ParseBlock(b)
+	if err != nil {
+		t.Fatalf("ParseBlock() error = %v", err)
+	}
+	if len(block.Txs) != 2 {
+		t.Fatalf("ParseBlock() number of txs = %d, want 2", len(block.Txs))
+	}
+	// segwit coinbase with the witness commitment output
+	coinbase := &block.Txs[0]
+	if len(coinbase.Vin) != 1 || coinbase.Vin[0].Coinbase == "" || len(coinbase.Vout) != 2 {
+		t.Fatalf("coinbase = %+v", coinbase)
+	}
+	if !strings.HasPrefix(coinbase.Vout[1].ScriptPubKey.Hex, "6a24aa21a9ed") || coinbase.Vout[1].ValueSat.Sign() != 0 {
+		t.Errorf("coinbase witness commitment output = %+v", coinbase.Vout[1])
+	}
+	if coinbase.WitnessSize != 36 {
+		t.Errorf("coinbase witness size = %d, want 36", coinbase.WitnessSize)
+	}
+	// Sigma spend with the proof in the witness
+	spend := &block.Txs[1]
+	if len(spend.Vin) != 1 || len(spend.Vout) != 1 || spend.Vout[0].ValueSat.Int64() != 4999000000 {
+		t.Fatalf("spend = %+v", spend)
+	}
+	ps, err := parser.ParsePrivacySpend(&spend.Vin[0])
+	if err != nil || ps == nil || ps.Type != "sigmaspend" {
+		t.Errorf("ParsePrivacySpend() = %+v, %v, want sigmaspend", ps, err)
+	}
+	if len(spend.Vin[0].Witness) != 1 || len(spend.Vin[0].Witness[0]) != 192 {
+		t.Errorf("spend witness = %d items, want 1 item of 192 bytes", len(spend.Vin[0].Witness))
+	}
+	if spend.BaseSize+spend.WitnessSize != 346 || spend.WitnessSize != 196 {
+		t.Errorf("spend sizes = %d, %d, want 150, 196", spend.BaseSize, spend.WitnessSize)
+	}
+}
+
+func TestIsConsolidationTx(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	consolidation, err := parser.ParseTxFromJson(jsonConsolidationTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migration, err := parser.ParseTxFromJson(jsonMigrationTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		config Configuration
+		tx     *bchain.Tx
+		want   bool
+	}{
+		{name: "six spends, default limit", tx: consolidation, want: true},
+		{name: "six spends, limit 6", config: Configuration{ConsolidationSpends: 6}, tx: consolidation, want: false},
+		{name: "six spends, limit 2", config: Configuration{ConsolidationSpends: 2}, tx: consolidation, want: true},
+		{name: "single spend", config: Configuration{ConsolidationSpends: 1}, tx: migration, want: false},
+		{name: "transparent", config: Configuration{ConsolidationSpends: 1}, tx: &testTx3, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &tt.config)
+			if got := p.IsConsolidationTx(tt.tx); got != tt.want {
+				t.Errorf("IsConsolidationTx() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := parser.PrivacySpendCount(consolidation); got != 6 {
+		t.Errorf("PrivacySpendCount() = %v, want 6", got)
+	}
+}
+
+func TestParseBlockTruncatedMTPData(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	raw, _ := hex.DecodeString(rawBlock1)
+	// in the fixed part of the MTP data and in the proof
+	for _, l := range []int{80 + 100, 80 + int(mtpDataSize) + 10} {
+		if _, err := parser.ParseBlock(raw[:l]); err != io.ErrUnexpectedEOF {
+			t.Errorf("ParseBlock() of %d bytes error = %v, want %v", l, err, io.ErrUnexpectedEOF)
+		}
+	}
+}
+
+func BenchmarkParseBlockMTP(b *testing.B) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	raw, err := hex.DecodeString(rawBlock1)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(raw)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := parser.ParseBlock(raw); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestIsValidAddress(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	bech32Parser := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{Bech32HRP: "xz"})
+	testParams, err := GetChainParams("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testParser := NewZcoinParser(testParams, &btc.Configuration{})
+	address := func(p *ZcoinParser, script string) string {
+		s, _ := hex.DecodeString(script)
+		a, _, err := p.GetAddressesFromAddrDesc(s)
+		if err != nil || len(a) != 1 {
+			t.Fatalf("GetAddressesFromAddrDesc(%v) = %v, %v", script, a, err)
+		}
+		return a[0]
+	}
+	p2sh := address(parser, "a914b9e262e30df03e88ccea312652bc83ca7290c8fc87")
+	bech32 := address(bech32Parser, "0014751e76e8199196d454941c45d1b3a323f1433bd6")
+	testnet := address(testParser, "76a914b9e262e30df03e88ccea312652bc83ca7290c8fc88ac")
+	testnetP2SH := address(testParser, "a914b9e262e30df03e88ccea312652bc83ca7290c8fc87")
+	tests := []struct {
+		name     string
+		parser   *ZcoinParser
+		address  string
+		want     bool
+		wantType string
+	}{
+		{name: "p2pkh", parser: parser, address: "aHfKwzFZMiSxDuNL4jts819nh57t2yJG1h", want: true, wantType: AddressTypeP2PKH},
+		{name: "p2sh", parser: parser, address: p2sh, want: true, wantType: AddressTypeP2SH},
+		{name: "bech32", parser: bech32Parser, address: bech32, want: true, wantType: AddressTypeBech32},
+		{name: "bech32 without configured prefix", parser: parser, address: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"},
+		{name: "testnet address on mainnet", parser: parser, address: testnet},
+		{name: "testnet p2sh address on mainnet", parser: parser, address: testnetP2SH},
+		{name: "mainnet address on testnet", parser: testParser, address: "aHfKwzFZMiSxDuNL4jts819nh57t2yJG1h"},
+		{name: "testnet address on testnet", parser: testParser, address: testnet, want: true, wantType: AddressTypeP2PKH},
+		{name: "bitcoin address", parser: parser, address: "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"},
+		{name: "invalid checksum", parser: parser, address: "aHfKwzFZMiSxDuNL4jts819nh57t2yJG1i"},
+		{name: "garbage", parser: parser, address: "not an address"},
+		{name: "empty", parser: parser, address: ""},
+		{name: "pseudo address", parser: parser, address: "Sigmamint"},
+		{name: "pseudo address with network", parser: testParser, address: "Zerospend-test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotType := tt.parser.IsValidAddress(tt.address)
+			if got != tt.want || gotType != tt.wantType {
+				t.Errorf("IsValidAddress(%q) = %v, %q, want %v, %q", tt.address, got, gotType, tt.want, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestValidateAddresses(t *testing.T) {
+	parser := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{Bech32HRP: "xz"})
+	bech32, _, err := parser.GetAddressesFromAddrDesc(bchain.AddressDescriptor{0, 20, 0x75, 0x1e, 0x76, 0xe8, 0x19, 0x91, 0x96, 0xd4, 0x54, 0x94, 0x1c, 0x45, 0xd1, 0xb3, 0xa3, 0x23, 0xf1, 0x43, 0x3b, 0xd6})
+	if err != nil || len(bech32) != 1 {
+		t.Fatalf("GetAddressesFromAddrDesc() = %v, %v", bech32, err)
+	}
+	addrs := []string{
+		"aHfKwzFZMiSxDuNL4jts819nh57t2yJG1h",
+		bech32[0],
+		"Sigmamint",
+		"aHfKwzFZMiSxDuNL4jts819nh57t2yJG1i",
+		"aHfKwzFZMiSxDuNL4jts819nh57t2yJG1h",
+		"",
+	}
+	got := parser.ValidateAddresses(addrs)
+	if len(got) != len(addrs) {
+		t.Fatalf("ValidateAddresses() returned %v results, want %v", len(got), len(addrs))
+	}
+	for i, a := range addrs {
+		valid, typ := parser.IsValidAddress(a)
+		want := AddressValidation{Address: a, Valid: valid, Type: typ}
+		if got[i] != want {
+			t.Errorf("ValidateAddresses()[%v] = %+v, want %+v", i, got[i], want)
+		}
+	}
+	if !got[0].Valid || got[0].Type != AddressTypeP2PKH || got[1].Type != AddressTypeBech32 || got[2].Valid {
+		t.Errorf("ValidateAddresses() = %+v", got)
+	}
+	if got := parser.ValidateAddresses(nil); len(got) != 0 {
+		t.Errorf("ValidateAddresses(nil) = %+v, want empty", got)
+	}
+}
+
+func BenchmarkValidateAddresses(b *testing.B) {
+	parser := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{Bech32HRP: "xz"})
+	addrs := make([]string, 0, 750)
+	for i := 0; i < 250; i++ {
+		addrs = append(addrs, "aHfKwzFZMiSxDuNL4jts819nh57t2yJG1h", "Sigmamint", fmt.Sprint("invalid", i))
+	}
+	b.Run("IsValidAddress", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, a := range addrs {
+				parser.IsValidAddress(a)
+			}
+		}
+	})
+	b.Run("ValidateAddresses", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			parser.ValidateAddresses(addrs)
+		}
+	})
+}
+
+func TestGetSigmaSpendDenominations(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		want       []int64
+		wantSingle int64
+		wantErr    bool
+	}{
+		{name: "single", script: rawSigmaSpend, want: []int64{1000000000}, wantSingle: 1000000000},
+		{name: "batched", script: rawSigmaSpendBatched, want: []int64{100000000, 1000000000, 10000000000}},
+		{name: "truncated batched", script: rawSigmaSpendBatched[:30], wantErr: true},
+		{name: "zerocoin spend", script: vinScript(&testTx2.Vin[0]), wantErr: true},
+		{name: "short", script: "c4", wantErr: true},
+		{name: "negative", script: "c4010affffffffffffffff", wantErr: true},
+		{name: "no denominations", script: "c4010a000000000000000000", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetSigmaSpendDenominations(tt.script)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetSigmaSpendDenominations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetSigmaSpendDenominations() = %v, want %v", got, tt.want)
+			}
+			single, err := GetSigmaSpendDenomination(tt.script)
+			if (err != nil) != (tt.wantSingle == 0) || single != tt.wantSingle {
+				t.Errorf("GetSigmaSpendDenomination() = %v, %v, want %v", single, err, tt.wantSingle)
+			}
+		})
+	}
+
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	block := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{Height: 1000},
+		Txs: []bchain.Tx{{
+			Txid: "batched",
+			Vin:  []bchain.Vin{{Coinbase: rawSigmaSpendBatched}},
+		}},
+	}
+	want := []bchain.PrivacyEvent{{Txid: "batched", Height: 1000, Type: "sigmaspend", Value: "11100000000", Serial: "997a7f012a272dc170824c9f44f01e1b635fb2d0e43c51801c0b88b55b04c694"}}
+	if got := parser.GetPrivacyEvents(block); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetPrivacyEvents() = %+v, want %+v", got, want)
+	}
+	tally := MintTally{{Type: "sigmamint", Value: 1000000000}: 7}
+	if n, err := parser.AnonymitySetSize(&bchain.Vin{Coinbase: rawSigmaSpend}, nil, tally); err != nil || n != 7 {
+		t.Errorf("AnonymitySetSize() = %v, %v, want 7", n, err)
+	}
+}
+
+func TestParseBlockAtHeightProgress(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	b, _ := hex.DecodeString(rawBlockEmptyVin)
+	// progress is not reported by default
+	block, err := parser.ParseBlockAtHeight(b, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Height != 100 {
+		t.Errorf("ParseBlockAtHeight() height = %v, want 100", block.Height)
+	}
+	type progress struct {
+		height uint32
+		txs    int
+	}
+	var got []progress
+	parser.ParseProgress = func(height uint32, txs int) {
+		got = append(got, progress{height, txs})
+	}
+	for _, h := range []uint32{101, 102} {
+		if _, err = parser.ParseBlockAtHeight(b, h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// failed parse is not reported
+	if _, err = parser.ParseBlockAtHeight(b[:100], 103); err == nil {
+		t.Error("ParseBlockAtHeight() of truncated block, want error")
+	}
+	want := []progress{{101, 2}, {102, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseProgress calls = %v, want %v", got, want)
+	}
+}
+
+func TestPrivacySpendUnlockHeight(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	timelocked, err := parser.ParseTxFromJson(jsonTimelockedSpendTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	final := *timelocked
+	final.Vin = []bchain.Vin{timelocked.Vin[0]}
+	final.Vin[0].Sequence = wire.MaxTxInSequenceNum
+	byTime := *timelocked
+	byTime.LockTime = 1600000000
+	noLockTime := *timelocked
+	noLockTime.LockTime = 0
+	transparent := testTx3
+	transparent.LockTime = 250000
+	tests := []struct {
+		name string
+		tx   *bchain.Tx
+		want uint32
+	}{
+		{name: "timelocked spend", tx: timelocked, want: 250001},
+		{name: "final inputs", tx: &final},
+		{name: "lock time by timestamp", tx: &byTime},
+		{name: "no lock time", tx: &noLockTime},
+		{name: "transparent", tx: &transparent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parser.PrivacySpendUnlockHeight(tt.tx); got != tt.want {
+				t.Errorf("PrivacySpendUnlockHeight() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBlockDenominations(t *testing.T) {
+	b, _ := hex.DecodeString(rawBlockDenominations)
+	// the tally is opt-in
+	block, err := NewZcoinParser(testChainParams(), &btc.Configuration{}).ParseBlock(b)
+	if err != nil {
+		t.Fatalf("ParseBlock() error = %v", err)
+	}
+	if block.Denominations != nil {
+		t.Errorf("ParseBlock() without denomination_tally, denominations = %v, want nil", block.Denominations)
+	}
+	parser := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{DenominationTally: true})
+	block, err = parser.ParseBlock(b)
+	if err != nil {
+		t.Fatalf("ParseBlock() error = %v", err)
+	}
+	// 2 Sigma mints of 1 XZC, Sigma and Zerocoin mint of 10 XZC, Sigma spend of 10 XZC
+	// and batched Sigma spend of 1, 10 and 100 XZC
+	want := map[string]bchain.DenominationCount{
+		"100000000":   {Mints: 2, Spends: 1},
+		"1000000000":  {Mints: 2, Spends: 2},
+		"10000000000": {Spends: 1},
+	}
+	if !reflect.DeepEqual(block.Denominations, want) {
+		t.Errorf("ParseBlock() denominations = %v, want %v", block.Denominations, want)
+	}
+	if got := parser.BlockDenominations(&bchain.Block{Txs: []bchain.Tx{testTx3}}); got != nil {
+		t.Errorf("BlockDenominations() of transparent tx = %v, want nil", got)
+	}
+}
+
+func TestCoinstakeTx(t *testing.T) {
+	parser := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{InputValues: true})
+	msg, err := ioutil.ReadFile("./testdata/coinstaketx.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := parser.ParseTxFromJson(msg)
+	if err != nil {
+		t.Fatalf("ParseTxFromJson() error = %v", err)
+	}
+	if !bchain.IsCoinstakeTx(tx) {
+		t.Error("IsCoinstakeTx() = false, want true")
+	}
+	if tx.Vin[0].ValueSat == nil || tx.Vin[0].ValueSat.Int64() != 107300000000 {
+		t.Errorf("stake input value = %v, want 107300000000", tx.Vin[0].ValueSat)
+	}
+	if parser.IsPrivacyTx(tx) {
+		t.Error("IsPrivacyTx() = true, want false")
+	}
+	// the coinstake creates the reward, the fee cannot be computed
+	if _, err := parser.ComputeFee(tx, []*big.Int{tx.Vin[0].ValueSat}); err == nil {
+		t.Error("ComputeFee() of coinstake, want error")
+	}
+	transparent, err := parser.ParseTxFromJson(jsonTransparentTx)
+	if err != nil {
+		t.Fatalf("ParseTxFromJson() error = %v", err)
+	}
+	if bchain.IsCoinstakeTx(transparent) {
+		t.Error("IsCoinstakeTx() of transparent tx = true, want false")
+	}
+}
+
+func TestIsChurnTx(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	tests := []struct {
+		name string
+		file string
+		want bool
+	}{
+		// Sigma spend of 10 XZC minted again as 10 XZC
+		{name: "churn", file: "./testdata/churntx.json", want: true},
+		// batched Sigma spend of 1, 10 and 100 XZC minted as 1, 10 and 4 times 25 XZC
+		{name: "denomination change", file: "./testdata/redenominationtx.json", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := ioutil.ReadFile(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tx, err := parser.ParseTxFromJson(msg)
+			if err != nil {
+				t.Fatalf("ParseTxFromJson() error = %v", err)
+			}
+			if got := parser.IsChurnTx(tx); got != tt.want {
+				t.Errorf("IsChurnTx() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	for _, tx := range []bchain.Tx{testTx1, testTx2, testTx3} {
+		if parser.IsChurnTx(&tx) {
+			t.Errorf("IsChurnTx() of tx %v = true, want false", tx.Txid)
+		}
+	}
+}
+
+func TestGetPseudoAddressType(t *testing.T) {
+	parser := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{PseudoAddressNetwork: true})
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{address: "Zeromint-test", want: "zerocoinmint"},
+		{address: "Zerospend-test", want: "zerocoinspend"},
+		{address: "Sigmamint-test", want: "sigmamint"},
+		{address: "Sigmaspend", want: "sigmaspend"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			addrDesc, err := parser.GetAddrDescFromAddress(tt.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := parser.GetPseudoAddressType(addrDesc); got != tt.want {
+				t.Errorf("GetPseudoAddressType() = %v, want %v", got, tt.want)
+			}
+			// the descriptor converts back to the pseudo address of the network
+			addresses, searchable, err := parser.GetAddressesFromAddrDesc(addrDesc)
+			if err != nil || searchable || len(addresses) != 1 || !isPseudoAddress(addresses[0]) {
+				t.Errorf("GetAddressesFromAddrDesc() = %v, %v, %v", addresses, searchable, err)
+			}
+		})
+	}
+	p2pkh, _ := hex.DecodeString("76a914b9e262e30df03e88ccea312652bc83ca7290c8fc88ac")
+	if got := parser.GetPseudoAddressType(p2pkh); got != "" {
+		t.Errorf("GetPseudoAddressType(p2pkh) = %v, want empty", got)
+	}
+}
+
+func TestGetSpendAccumulatorHeight(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	heights := map[string]uint32{
+		"c0e09f8723f85467f7a23639f3f1787a5ef53811b2dd9c83272aa897858f1595": 150000,
+		"0713efb52fd7dce7ebeddf15690730631ce29c531c9bc5306a02340c7f0347b6": 200000,
+		"e7135a67da17785d00f30bd0e060b7f474a1ded0be8bb94fb10aba40184a6081": 210000,
+	}
+	blockHeight := func(hash string) (uint32, error) {
+		h, found := heights[hash]
+		if !found {
+			return 0, bchain.ErrBlockNotFound
+		}
+		return h, nil
+	}
+	tests := []struct {
+		name    string
+		script  string
+		want    uint32
+		wantErr bool
+	}{
+		{name: "zerocoin", script: accumulatorSpends[0], want: 150000},
+		{name: "sigma", script: accumulatorSpends[1], want: 200000},
+		{name: "batched sigma", script: accumulatorSpends[2], want: 210000},
+		{name: "unknown block", script: accumulatorSpends[1][:len(accumulatorSpends[1])-2] + "00", wantErr: true},
+		{name: "zero hash", script: accumulatorSpends[1][:len(accumulatorSpends[1])-64] + strings.Repeat("00", 32), wantErr: true},
+		{name: "short proof", script: accumulatorSpends[1][:56], wantErr: true},
+		{name: "malformed zerocoin", script: accumulatorSpends[0][:20], wantErr: true},
+		{name: "not a spend", script: "03a0bb0d", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.GetSpendAccumulatorHeight(&bchain.Vin{Coinbase: tt.script}, blockHeight)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parseBlock() error = %+v", err)
+				t.Fatalf("GetSpendAccumulatorHeight() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetSpendAccumulatorHeight() = %v, want %v", got, tt.want)
 			}
+		})
+	}
+}
 
-			if got != nil {
+func TestTransparentVolume(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	b, _ := hex.DecodeString(rawBlockMixed)
+	block, err := parser.ParseBlock(b)
+	if err != nil {
+		t.Fatalf("ParseBlock() error = %v", err)
+	}
+	if len(block.Txs) != 5 || !bchain.IsCoinstakeTx(&block.Txs[1]) {
+		t.Fatalf("ParseBlock() unexpected block %+v", block)
+	}
+	// transparent tx 3 + 1.5 XZC, change 0.5 XZC of the mint of 10 XZC and 9.99 XZC from the Sigma spend,
+	// the coinbase and the coinstake of 1000.5 XZC are not counted
+	if got, want := parser.TransparentVolume(block), big.NewInt(1499000000); got.Cmp(want) != 0 {
+		t.Errorf("TransparentVolume() = %v, want %v", got, want)
+	}
+	if got := parser.TransparentVolume(&bchain.Block{}); got.Sign() != 0 {
+		t.Errorf("TransparentVolume() of empty block = %v, want 0", got)
+	}
+}
 
-				if !reflect.DeepEqual(got.BlockHeader, tt.want.BlockHeader) {
-					t.Errorf("parseBlock() got = %v, want %v", got.BlockHeader, tt.want.BlockHeader)
+func TestBech32HRP(t *testing.T) {
+	tests := []struct {
+		chain  string
+		config Configuration
+		want   string
+	}{
+		{chain: "main", want: ""},
+		{chain: "test", want: ""},
+		{chain: "regtest", want: ""},
+		{chain: "main", config: Configuration{Bech32HRP: "fx"}, want: "fx"},
+		{chain: "test", config: Configuration{Bech32HRP: "tfx"}, want: "tfx"},
+		{chain: "regtest", config: Configuration{Bech32HRP: "fxrt"}, want: "fxrt"},
+		// taproot without the configured prefix uses the prefix of the chain params
+		{chain: "test", config: Configuration{Taproot: true}, want: "tb"},
+	}
+	for _, tt := range tests {
+		params, err := GetChainParams(tt.chain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config := tt.config
+		parser := NewZcoinParserWithConfig(params, &btc.Configuration{}, &config)
+		if got := parser.Bech32HRP(); got != tt.want {
+			t.Errorf("%v %+v: Bech32HRP() = %q, want %q", tt.chain, tt.config, got, tt.want)
+		}
+	}
+}
+
+func TestTaprootAddress(t *testing.T) {
+	// BIP86 test vector, the first receiving address of the "abandon ... about" mnemonic
+	const p2trScript = "5120a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c"
+	const p2trAddress = "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr"
+	parser := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{Bech32HRP: "bc", Taproot: true})
+	script, _ := hex.DecodeString(p2trScript)
+	addresses, searchable, err := parser.GetAddressesFromAddrDesc(script)
+	if err != nil || !searchable || !reflect.DeepEqual(addresses, []string{p2trAddress}) {
+		t.Errorf("GetAddressesFromAddrDesc() = %v, %v, %v, want %v", addresses, searchable, err, p2trAddress)
+	}
+	got, err := parser.GetAddrDescFromAddress(p2trAddress)
+	if err != nil || hex.EncodeToString(got) != p2trScript {
+		t.Errorf("GetAddrDescFromAddress() = %x, %v, want %v", got, err, p2trScript)
+	}
+	if valid, typ := parser.IsValidAddress(p2trAddress); !valid || typ != AddressTypeP2TR {
+		t.Errorf("IsValidAddress() = %v, %v, want true, %v", valid, typ, AddressTypeP2TR)
+	}
+	// the witness v0 address is still bech32, the bech32m checksum is not accepted for it
+	v0, _ := hex.DecodeString("0014751e76e8199196d454941c45d1b3a323f1433bd6")
+	data, _ := convertBits(v0[2:], 8, 5, true)
+	if _, err = parser.GetAddrDescFromAddress(bech32mEncode("bc", append([]byte{0}, data...))); err == nil {
+		t.Error("GetAddrDescFromAddress() of the bech32m witness v0 address, want error")
+	}
+	data, _ = convertBits(script[2:], 8, 5, true)
+	if _, err = parser.GetAddrDescFromAddress(bech32Encode("bc", append([]byte{1}, data...))); err == nil {
+		t.Error("GetAddrDescFromAddress() of the bech32 taproot address, want error")
+	}
+	// the privacy opcodes take precedence
+	mint, _ := hex.DecodeString("c3" + p2trScript)
+	if addresses, _, _ = parser.GetAddressesFromAddrDesc(mint); !reflect.DeepEqual(addresses, []string{"Sigmamint"}) {
+		t.Errorf("GetAddressesFromAddrDesc() of mint = %v, want Sigmamint", addresses)
+	}
+	// without taproot enabled the outputs are not decoded and the addresses are rejected
+	disabled := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{Bech32HRP: "bc"})
+	if addresses, _, _ = disabled.GetAddressesFromAddrDesc(script); reflect.DeepEqual(addresses, []string{p2trAddress}) {
+		t.Errorf("GetAddressesFromAddrDesc() without taproot = %v", addresses)
+	}
+	if _, err = disabled.GetAddrDescFromAddress(p2trAddress); err == nil {
+		t.Error("GetAddrDescFromAddress() without taproot, want error")
+	}
+}
+
+func TestIsReplayVulnerable(t *testing.T) {
+	parser := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{ReplayProtectionHeight: 200000})
+	unprotected, err := parser.ParseTxFromJson(jsonTransparentTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	protected, err := parser.ParseTxFromJson(jsonForkIDTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spend, err := parser.ParseTxFromJson(jsonTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mixed := *protected
+	mixed.Vin = []bchain.Vin{protected.Vin[0], unprotected.Vin[0]}
+	tests := []struct {
+		name   string
+		parser *ZcoinParser
+		tx     *bchain.Tx
+		height uint32
+		want   bool
+	}{
+		{name: "unprotected", parser: parser, tx: unprotected, height: 200000, want: true},
+		{name: "unprotected before split", parser: parser, tx: unprotected, height: 199999},
+		{name: "protected", parser: parser, tx: protected, height: 200000},
+		{name: "one unprotected input", parser: parser, tx: &mixed, height: 250000, want: true},
+		{name: "privacy spend", parser: parser, tx: spend, height: 250000},
+		{name: "coinbase", parser: parser, tx: &bchain.Tx{Vin: []bchain.Vin{{Coinbase: "03a08601"}}}, height: 250000},
+		{name: "protection not configured", parser: NewZcoinParser(testChainParams(), &btc.Configuration{}), tx: unprotected, height: 250000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.parser.IsReplayVulnerable(tt.tx, tt.height); got != tt.want {
+				t.Errorf("IsReplayVulnerable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBlockFile(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	var file bytes.Buffer
+	writeBlock := func(raw string, truncate int) {
+		b, _ := hex.DecodeString(raw)
+		binary.Write(&file, binary.LittleEndian, uint32(MainnetMagic))
+		binary.Write(&file, binary.LittleEndian, uint32(len(b)))
+		file.Write(b[:len(b)-truncate])
+	}
+	writeBlock(rawBlock1, 0)
+	// zero padding between the blocks
+	file.Write(make([]byte, 13))
+	writeBlock(rawBlock2, 0)
+	writeBlock(rawBlockEmptyVin, 100)
+	var got []int
+	err := parser.ParseBlockFile(bytes.NewReader(file.Bytes()), MainnetMagic, func(block *bchain.Block) error {
+		got = append(got, len(block.Txs))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ParseBlockFile() error = %v", err)
+	}
+	b1, _ := hex.DecodeString(rawBlock1)
+	block1, _ := parser.ParseBlock(b1)
+	b2, _ := hex.DecodeString(rawBlock2)
+	block2, _ := parser.ParseBlock(b2)
+	if want := []int{len(block1.Txs), len(block2.Txs)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseBlockFile() parsed blocks with %v txs, want %v", got, want)
+	}
+	// the blocks of other network are not found
+	got = nil
+	if err = parser.ParseBlockFile(bytes.NewReader(file.Bytes()), TestnetMagic, func(block *bchain.Block) error {
+		got = append(got, len(block.Txs))
+		return nil
+	}); err != nil || len(got) != 0 {
+		t.Errorf("ParseBlockFile() of other network = %v, %v, want no blocks", got, err)
+	}
+	// the error of the callback stops the parsing
+	errStop := errors.New("stop")
+	if err = parser.ParseBlockFile(bytes.NewReader(file.Bytes()), MainnetMagic, func(block *bchain.Block) error {
+		return errStop
+	}); err != errStop {
+		t.Errorf("ParseBlockFile() error = %v, want %v", err, errStop)
+	}
+}
+
+func TestDifficulty(t *testing.T) {
+	pos := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{PosLimitBits: 0x1e0fffff})
+	tests := []struct {
+		name         string
+		parser       *ZcoinParser
+		bits         uint32
+		proofOfStake bool
+		want         float64
+	}{
+		{name: "pow block", parser: pos, bits: 0x1b0e8e18, want: 4502.559818845138},
+		{name: "pow block with low target", parser: pos, bits: 0x1e00a50c, want: 0.00605879233527407},
+		{name: "pos block", parser: pos, bits: 0x1e00a50c, proofOfStake: true, want: 24.81716841806305},
+		{name: "pos block at limit", parser: pos, bits: 0x1e0fffff, proofOfStake: true, want: 1},
+		{name: "pos block without pos limit", parser: NewZcoinParser(testChainParams(), &btc.Configuration{}), bits: 0x1e00a50c, proofOfStake: true, want: 0.00605879233527407},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.parser.Difficulty(tt.bits, tt.proofOfStake)
+			if math.Abs(got-tt.want) > 1e-9*math.Max(1, tt.want) {
+				t.Errorf("Difficulty() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequiredConfirmations(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{MinimumCoinbaseConfirmations: 100})
+	stakeParser := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{MinimumCoinbaseConfirmations: 100}, &Configuration{StakeMaturity: 500})
+	tests := []struct {
+		name       string
+		parser     *ZcoinParser
+		isCoinbase bool
+		isStake    bool
+		want       int
+	}{
+		{name: "normal", parser: parser, want: 1},
+		{name: "coinbase", parser: parser, isCoinbase: true, want: 100},
+		{name: "stake", parser: parser, isStake: true, want: 100},
+		{name: "normal with stake maturity", parser: stakeParser, want: 1},
+		{name: "coinbase with stake maturity", parser: stakeParser, isCoinbase: true, want: 100},
+		{name: "stake with stake maturity", parser: stakeParser, isStake: true, want: 500},
+		{name: "coinbase without maturity", parser: NewZcoinParser(testChainParams(), &btc.Configuration{}), isCoinbase: true, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.parser.RequiredConfirmations(1, tt.isCoinbase, tt.isStake); got != tt.want {
+				t.Errorf("RequiredConfirmations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBlockIntraBlockSpends(t *testing.T) {
+	b, err := hex.DecodeString(rawBlockIntraSpend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		parser *ZcoinParser
+		want   map[int][]uint32
+	}{
+		{
+			name:   "intra block spends",
+			parser: NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{IntraBlockSpends: true, StrictMerkleRoot: true}),
+			want:   map[int][]uint32{2: {0}, 3: {1}},
+		},
+		{
+			name:   "not enabled",
+			parser: NewZcoinParser(testChainParams(), &btc.Configuration{}),
+			want:   map[int][]uint32{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block, err := tt.parser.ParseBlock(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(block.Txs) != 5 {
+				t.Fatalf("ParseBlock() number of transactions = %v, want 5", len(block.Txs))
+			}
+			got := make(map[int][]uint32)
+			for i := range block.Txs {
+				for _, vout := range block.Txs[i].Vout {
+					if vout.SpentInBlock {
+						got[i] = append(got[i], vout.N)
+					}
 				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBlock() outputs spent in block = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
 
-				if len(got.Txs) != tt.wantTxs {
-					t.Errorf("parseBlock() txs length got = %d, want %d", len(got.Txs), tt.wantTxs)
+func TestTxWeight(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	// segwit transaction spending P2WPKH output: base 82 bytes, witness 109 bytes
+	mtx := wire.NewMsgTx(1)
+	mtx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: 1},
+		Sequence:         wire.MaxTxInSequenceNum,
+		Witness:          wire.TxWitness{make([]byte, 71), make([]byte, 33)},
+	})
+	mtx.AddTxOut(wire.NewTxOut(100000, make([]byte, 22)))
+	var buf bytes.Buffer
+	if err := mtx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	spendTx, err := parser.ParseTxFromJson(jsonTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		tx   *bchain.Tx
+		want int
+	}{
+		{name: "segwit from hex", tx: &bchain.Tx{Hex: hex.EncodeToString(buf.Bytes())}, want: 437},
+		{name: "spend", tx: spendTx, want: 4 * 23821},
+		{name: "spend from hex", tx: &bchain.Tx{Hex: spendTx.Hex}, want: 4 * 23821},
+		{name: "without hex", tx: &bchain.Tx{}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parser.TxWeight(tt.tx); got != tt.want {
+				t.Errorf("TxWeight() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBlockSpendProofs(t *testing.T) {
+	parser := NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{SpendProofStats: true})
+	tests := []struct {
+		name     string
+		rawBlock string
+		parser   *ZcoinParser
+		want     map[string]bchain.SpendProofSize
+	}{
+		{
+			name:     "large spends",
+			rawBlock: rawBlockSpendProofs,
+			parser:   parser,
+			want: map[string]bchain.SpendProofSize{
+				"zerocoinspend": {Spends: 4, TotalSize: 4 * 24383, MaxSize: 24383},
+				"sigmaspend":    {Spends: 1, TotalSize: 259, MaxSize: 259},
+			},
+		},
+		{
+			name:     "sigma spends",
+			rawBlock: rawBlockDenominations,
+			parser:   parser,
+			want: map[string]bchain.SpendProofSize{
+				"sigmaspend": {Spends: 2, TotalSize: 241, MaxSize: 133},
+			},
+		},
+		{
+			name:     "no spends",
+			rawBlock: rawBlock1,
+			parser:   parser,
+		},
+		{
+			name:     "not enabled",
+			rawBlock: rawBlockSpendProofs,
+			parser:   NewZcoinParser(testChainParams(), &btc.Configuration{}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := hex.DecodeString(tt.rawBlock)
+			if err != nil {
+				t.Fatal(err)
+			}
+			block, err := tt.parser.ParseBlock(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(block.SpendProofs, tt.want) {
+				t.Errorf("ParseBlock() SpendProofs = %+v, want %+v", block.SpendProofs, tt.want)
+			}
+		})
+	}
+	s := bchain.SpendProofSize{Spends: 4, TotalSize: 1000}
+	if avg := s.AvgSize(); avg != 250 {
+		t.Errorf("AvgSize() = %v, want 250", avg)
+	}
+	if avg := (&bchain.SpendProofSize{}).AvgSize(); avg != 0 {
+		t.Errorf("AvgSize() of no spends = %v, want 0", avg)
+	}
+}
+
+func TestTxPrivacyFlags(t *testing.T) {
+	parser := NewZcoinParser(testChainParams(), &btc.Configuration{})
+	migration, err := parser.ParseTxFromJson(jsonMigrationTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transparent, err := parser.ParseTxFromJson(jsonTransparentTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		tx   *bchain.Tx
+		want bchain.TxFlags
+	}{
+		{name: "mint", tx: &testTx1, want: bchain.TxFlagPrivacyMint},
+		{name: "spend", tx: &testTx2, want: bchain.TxFlagPrivacySpend},
+		{name: "migration", tx: migration, want: bchain.TxFlagPrivacySpend | bchain.TxFlagPrivacyMint | bchain.TxFlagRemint},
+		{name: "transparent", tx: transparent, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parser.TxPrivacyFlags(tt.tx); got != tt.want {
+				t.Errorf("TxPrivacyFlags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBlockAtHeightCoinbaseHeight(t *testing.T) {
+	// the BIP34 activation height of Zcoin is above the heights of the test blocks
+	params := *testChainParams()
+	params.BIP0034Height = 1
+	strict := NewZcoinParserWithConfig(&params, &btc.Configuration{}, &Configuration{StrictCoinbaseHeight: true})
+	tests := []struct {
+		name     string
+		rawBlock string
+		height   uint32
+		parser   *ZcoinParser
+		wantErr  bool
+	}{
+		{name: "mtp block", rawBlock: rawBlock1, height: 126311, parser: strict},
+		{name: "mtp block wrong height", rawBlock: rawBlock1, height: 126312, parser: strict, wantErr: true},
+		{name: "spend block", rawBlock: rawBlock2, height: 12451, parser: strict},
+		{name: "spend block wrong height", rawBlock: rawBlock2, height: 12450, parser: strict, wantErr: true},
+		{name: "bip34 not active", rawBlock: rawBlock1, height: 126312, parser: NewZcoinParserWithConfig(testChainParams(), &btc.Configuration{}, &Configuration{StrictCoinbaseHeight: true})},
+		{name: "not strict", rawBlock: rawBlock1, height: 126312, parser: NewZcoinParserWithConfig(&params, &btc.Configuration{}, &Configuration{})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := hex.DecodeString(tt.rawBlock)
+			if err != nil {
+				t.Fatal(err)
+			}
+			block, err := tt.parser.ParseBlockAtHeight(b, tt.height)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBlockAtHeight() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), "does not match block height") {
+					t.Errorf("ParseBlockAtHeight() error = %v, want coinbase height mismatch", err)
 				}
+				return
+			}
+			if block.Height != tt.height {
+				t.Errorf("ParseBlockAtHeight() height = %v, want %v", block.Height, tt.height)
 			}
 		})
 	}
@@ -0,0 +1,62 @@
+package xzc
+
+import (
+	"blockbook/bchain"
+	"encoding/hex"
+
+	"github.com/martinboehm/btcutil/txscript"
+)
+
+// SigHashForkID is the sighash flag committing the signature to the fork (SIGHASH_FORKID)
+const SigHashForkID = 0x40
+
+// IsReplayVulnerable returns true if the transaction to be included in the block of the given height can be replayed
+// on the other side of the chain split, i.e. the fork has configured replay protection height, the height is at or
+// after it and some input has a signature without the replay protection sighash flag
+// only the signatures in the input scripts are inspected, the inputs without signatures (coinbase, privacy spends,
+// witness inputs) are not considered vulnerable
+func (p *ZcoinParser) IsReplayVulnerable(tx *bchain.Tx, height uint32) bool {
+	if p.config.ReplayProtectionHeight == 0 || height < p.config.ReplayProtectionHeight {
+		return false
+	}
+	flag := byte(p.config.ReplayProtectionSighash)
+	if flag == 0 {
+		flag = SigHashForkID
+	}
+	for i := range tx.Vin {
+		if isSpendScript(vinScript(&tx.Vin[i])) {
+			continue
+		}
+		for _, sighash := range scriptSigHashTypes(tx.Vin[i].ScriptSig.Hex) {
+			if sighash&flag == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scriptSigHashTypes returns the sighash types of the DER encoded signatures pushed by the input script
+func scriptSigHashTypes(script string) []byte {
+	b, err := hex.DecodeString(script)
+	if err != nil || len(b) == 0 {
+		return nil
+	}
+	pushes, err := txscript.PushedData(b)
+	if err != nil {
+		return nil
+	}
+	var r []byte
+	for _, d := range pushes {
+		if isDERSignature(d) {
+			r = append(r, d[len(d)-1])
+		}
+	}
+	return r
+}
+
+// isDERSignature returns true if the data has the structure of the DER encoded signature followed by the sighash type
+func isDERSignature(d []byte) bool {
+	// sequence tag, length of the sequence, the sighash byte follows the sequence
+	return len(d) >= 9 && len(d) <= 73 && d[0] == 0x30 && int(d[1]) == len(d)-3 && d[2] == 0x02
+}
@@ -12,6 +12,81 @@ import (
 
 type ZcoinRPC struct {
 	*btc.BitcoinRPC
+	ZcoinConfig *Configuration
+}
+
+// Configuration contains Zcoin specific configuration, read from the same json file as btc.Configuration
+type Configuration struct {
+	// SegwitActivationTime is the block time since which the transactions are decoded with witness encoding,
+	// zero means that the witness encoding is used for all blocks
+	SegwitActivationTime int64 `json:"segwit_activation_time,omitempty"`
+	// StrictMintValidation makes the parsing of a transaction fail if a mint output decodes also to a standard address,
+	// otherwise such an output is only logged
+	StrictMintValidation bool `json:"strict_mint_validation,omitempty"`
+	// StrictSpendValidation makes the parsing of a transaction fail if an input classified as a privacy spend
+	// references a real prevout, an input with the zero prevout txid is not a privacy spend or a Zerocoin spend
+	// is structurally invalid (see ValidateZerocoinSpend), otherwise it is only logged
+	StrictSpendValidation bool `json:"strict_spend_validation,omitempty"`
+	// StrictMerkleRoot makes the parsing of a block fail if the merkle root computed from the hashes of the decoded
+	// transactions (without witness data) differs from the merkle root of the block header, which detects blocks
+	// corrupted by a misbehaving backend
+	StrictMerkleRoot bool `json:"strict_merkle_root,omitempty"`
+	// SpendWitness attaches the raw witness data to the privacy spend inputs, the data can be large
+	SpendWitness bool `json:"spend_witness,omitempty"`
+	// BlockTimeSource selects the source of the block time, "header" (default) for the block header timestamp
+	// or "coinbase" for the timestamp pushed to the coinbase script by the mining or staking software
+	BlockTimeSource string `json:"block_time_source,omitempty"`
+	// SegwitTxVersions lists the transaction versions which are always decoded with witness encoding,
+	// transactions of other versions (all versions if the list is empty) fall back to the base encoding
+	// if the witness decoding fails, which is needed for the transactions without inputs
+	SegwitTxVersions []int32 `json:"segwit_tx_versions,omitempty"`
+	// CoinbaseSpendVout sets the prevout index of the privacy spend inputs, which are converted to coinbase inputs,
+	// to 0xffffffff (the prevout index of the coinbase input in the serialized transaction), by default it is set to 0
+	CoinbaseSpendVout bool `json:"coinbase_spend_vout,omitempty"`
+	// AddressChecksum selects the base58 address checksum of the forks, "sha256d" (default) or "sha256"
+	AddressChecksum string `json:"address_checksum,omitempty"`
+	// Bech32HRP overrides the human readable part of the bech32 segwit addresses of the forks
+	Bech32HRP string `json:"bech32_hrp,omitempty"`
+	// Taproot enables the segwit v1 (P2TR) outputs of the forks adopting taproot, they are encoded as bech32m addresses
+	// with the prefix given by Bech32HRP or, if not set, by the chain params
+	Taproot bool `json:"taproot,omitempty"`
+	// ReplayProtectionHeight is the height of the chain split of the fork since which the signatures must commit
+	// to the fork by the ReplayProtectionSighash flag, zero disables the replay protection checks
+	ReplayProtectionHeight uint32 `json:"replay_protection_height,omitempty"`
+	// ReplayProtectionSighash is the sighash flag of the fork, SigHashForkID if not set
+	ReplayProtectionSighash uint8 `json:"replay_protection_sighash,omitempty"`
+	// InputValues reads the values of the spent outputs of the transparent inputs from the value field of the JSON
+	// returned by the backend (if present), which saves the lookups of the spent outputs
+	InputValues bool `json:"input_values,omitempty"`
+	// PseudoAddressNetwork appends the network name to the privacy pseudo addresses of the networks other than mainnet,
+	// for example "Sigmamint-test", to distinguish them when the data of multiple networks are aggregated
+	PseudoAddressNetwork bool `json:"pseudo_address_network,omitempty"`
+	// CommentTxVersions lists the transaction versions which carry the comment (variable length string)
+	// serialized after the lock time, used by the forks supporting the transaction comments
+	CommentTxVersions []int32 `json:"comment_tx_versions,omitempty"`
+	// ConsolidationSpends is the number of privacy spend inputs a transaction must exceed to be tagged
+	// as a consolidation of mints, DefaultConsolidationSpends if not set
+	ConsolidationSpends int `json:"consolidation_spends,omitempty"`
+	// DenominationTally makes ParseBlock count the privacy mints and spends of the block by denomination,
+	// it is off by default to save the decoding of the spend scripts
+	DenominationTally bool `json:"denomination_tally,omitempty"`
+	// PosLimitBits is the compact target limit of the proof-of-stake blocks of the forks, the difficulty of the proof-of-stake
+	// blocks is computed relative to it, if not set the difficulty is computed in the same way as of the proof-of-work blocks
+	PosLimitBits uint32 `json:"pos_limit_bits,omitempty"`
+	// AccumulatorHeights resolves the accumulator block hashes referenced by the privacy spends of the fetched blocks
+	// to the heights (AccumulatorHeight of the spend inputs), it costs a backend call per spend
+	AccumulatorHeights bool `json:"accumulator_heights,omitempty"`
+	// StakeMaturity is the number of confirmations the outputs of the coinstake transactions of the forks
+	// must have before they can be spent, if not set the coinbase maturity (minimumCoinbaseConfirmations) applies
+	StakeMaturity int `json:"stake_maturity,omitempty"`
+	// IntraBlockSpends makes ParseBlock mark the outputs spent by other transactions of the same block (SpentInBlock of the outputs)
+	IntraBlockSpends bool `json:"intra_block_spends,omitempty"`
+	// SpendProofStats makes ParseBlock tally the size of the proofs (scriptSig and witness) of the privacy spends
+	// of the block by the type of the spend
+	SpendProofStats bool `json:"spend_proof_stats,omitempty"`
+	// StrictCoinbaseHeight makes ParseBlockAtHeight verify that the height serialized in the coinbase (BIP34)
+	// matches the height of the block, the check applies from the BIP34 activation height of the chain params
+	StrictCoinbaseHeight bool `json:"strict_coinbase_height,omitempty"`
 }
 
 func NewZcoinRPC(config json.RawMessage, pushHandler func(bchain.NotificationType)) (bchain.BlockChain, error) {
@@ -21,9 +96,19 @@ func NewZcoinRPC(config json.RawMessage, pushHandler func(bchain.NotificationTyp
 		return nil, err
 	}
 
+	var c Configuration
+	err = json.Unmarshal(config, &c)
+	if err != nil {
+		return nil, errors.Annotatef(err, "Invalid configuration file")
+	}
+	if err = checkAddressChecksum(c.AddressChecksum); err != nil {
+		return nil, errors.Annotatef(err, "Invalid configuration file")
+	}
+
 	// init zcoin implementation
 	zc := &ZcoinRPC{
-		BitcoinRPC: bc.(*btc.BitcoinRPC),
+		BitcoinRPC:  bc.(*btc.BitcoinRPC),
+		ZcoinConfig: &c,
 	}
 
 	zc.ChainConfig.Parse = true
@@ -42,10 +127,13 @@ func (zc *ZcoinRPC) Initialize() error {
 	}
 	chainName := ci.Chain
 
-	params := GetChainParams(chainName)
+	params, err := GetChainParams(chainName)
+	if err != nil {
+		return err
+	}
 
 	// always create parser
-	zc.Parser = NewZcoinParser(params, zc.ChainConfig)
+	zc.Parser = NewZcoinParserWithConfig(params, zc.ChainConfig, zc.ZcoinConfig)
 
 	// parameters for getInfo request
 	if params.Net == MainnetMagic {
@@ -86,12 +174,18 @@ func (zc *ZcoinRPC) GetBlock(hash string, height uint32) (*bchain.Block, error)
 		return nil, err
 	}
 
-	block, err := zc.Parser.ParseBlock(data)
+	block, err := zc.Parser.(*ZcoinParser).ParseBlockAtHeight(data, header.Height)
 	if err != nil {
 		return nil, errors.Annotatef(err, "hash %v", hash)
 	}
 
+	// keep the block time from the configured time source
+	t := block.Time
 	block.BlockHeader = *header
+	if block.HeaderTime != 0 {
+		block.Time = t
+	}
+	zc.setAccumulatorHeights(block)
 
 	return block, nil
 }
@@ -123,17 +217,47 @@ func (zc *ZcoinRPC) GetBlockWithoutHeader(hash string, height uint32) (*bchain.B
 		return nil, err
 	}
 
-	block, err := zc.Parser.ParseBlock(data)
+	block, err := zc.Parser.(*ZcoinParser).ParseBlockAtHeight(data, height)
 	if err != nil {
 		return nil, errors.Annotatef(err, "%v %v", height, hash)
 	}
 
 	block.BlockHeader.Hash = hash
-	block.BlockHeader.Height = height
+	zc.setAccumulatorHeights(block)
 
 	return block, nil
 }
 
+// setAccumulatorHeights sets the heights of the accumulators referenced by the privacy spends of the block
+// if enabled by the accumulator_heights option, the spends which cannot be resolved are logged and skipped
+func (zc *ZcoinRPC) setAccumulatorHeights(block *bchain.Block) {
+	if !zc.ZcoinConfig.AccumulatorHeights {
+		return
+	}
+	parser := zc.Parser.(*ZcoinParser)
+	blockHeight := func(hash string) (uint32, error) {
+		h, err := zc.GetBlockHeader(hash)
+		if err != nil {
+			return 0, err
+		}
+		return h.Height, nil
+	}
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		for j := range tx.Vin {
+			if !isSpendScript(vinScript(&tx.Vin[j])) {
+				continue
+			}
+			h, err := parser.GetSpendAccumulatorHeight(&tx.Vin[j], blockHeight)
+			if err != nil {
+				glog.Warning("txid ", tx.Txid, ", vin ", j, ": accumulator height: ", err)
+				continue
+			}
+			tx.Vin[j].AccumulatorHeight = h
+		}
+	}
+}
+
 func (zc *ZcoinRPC) GetBlockRaw(hash string) ([]byte, error) {
 	glog.V(1).Info("rpc: getblock (verbosity=false) ", hash)
 
@@ -190,19 +314,28 @@ func (zc *ZcoinRPC) GetTransaction(txid string) (*bchain.Tx, error) {
 	}
 
 	tx, err := zc.Parser.ParseTxFromJson(r)
-	tx.CoinSpecificData = r
 	if err != nil {
 		return nil, errors.Annotatef(err, "txid %v", txid)
 	}
+	tx.CoinSpecificData = r
 
 	return tx, nil
 }
 
+// GetTransactionSpecific returns json as returned by backend, with decoded privacy spend inputs
 func (zc *ZcoinRPC) GetTransactionSpecific(tx *bchain.Tx) (json.RawMessage, error) {
-	if csd, ok := tx.CoinSpecificData.(json.RawMessage); ok {
-		return csd, nil
+	csd, ok := tx.CoinSpecificData.(json.RawMessage)
+	if !ok {
+		var err error
+		csd, err = zc.getRawTransaction(tx.Txid)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if zp, ok := zc.Parser.(*ZcoinParser); ok {
+		return zp.DecodePrivacySpendsJSON(csd)
 	}
-	return zc.getRawTransaction(tx.Txid)
+	return csd, nil
 }
 
 func (zc *ZcoinRPC) getRawTransaction(txid string) (json.RawMessage, error) {
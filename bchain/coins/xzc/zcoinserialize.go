@@ -0,0 +1,110 @@
+package xzc
+
+import (
+	"blockbook/bchain"
+	"bytes"
+	"encoding/hex"
+
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcd/chaincfg/chainhash"
+	"github.com/martinboehm/btcd/wire"
+)
+
+// SerializeBlock serializes the block parsed by ParseBlock to the standard Bitcoin block format,
+// the standard 80 bytes header followed by the transactions, so that it can be processed by Bitcoin tools
+// the MTP data of the block header are not serialized, therefore MTP blocks cannot be parsed back by ParseBlock
+// the transactions with the hex are serialized from the hex, others are reconstructed from the parsed data
+// the privacy spend inputs, which are parsed as coinbase inputs, are reconstructed by spendTxIn
+// the witness data are serialized only if kept by the parser (spend_witness option), the comment is serialized
+// after the transactions of the configured comment versions
+// the standard blocks round-trip with ParseBlock
+func (p *ZcoinParser) SerializeBlock(b *bchain.Block) ([]byte, error) {
+	if len(b.RawHeader) != wire.MaxBlockHeaderPayload {
+		return nil, errors.New("Block header not available")
+	}
+	var buf bytes.Buffer
+	buf.Write(b.RawHeader)
+	if err := wire.WriteVarInt(&buf, 0, uint64(len(b.Txs))); err != nil {
+		return nil, err
+	}
+	for i := range b.Txs {
+		tx := &b.Txs[i]
+		if tx.Hex != "" {
+			h, err := hex.DecodeString(tx.Hex)
+			if err != nil {
+				return nil, errors.Annotatef(err, "tx %v", tx.Txid)
+			}
+			buf.Write(h)
+			continue
+		}
+		mtx, err := msgTxFromTx(tx)
+		if err != nil {
+			return nil, errors.Annotatef(err, "tx %v", tx.Txid)
+		}
+		if err = mtx.Serialize(&buf); err != nil {
+			return nil, errors.Annotatef(err, "tx %v", tx.Txid)
+		}
+		if _, has := p.commentTxVersions[tx.Version]; has {
+			if err = wire.WriteVarString(&buf, 0, tx.Comment); err != nil {
+				return nil, errors.Annotatef(err, "tx %v", tx.Txid)
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// msgTxFromTx reconstructs the wire transaction from the parsed transaction
+func msgTxFromTx(tx *bchain.Tx) (*wire.MsgTx, error) {
+	mtx := wire.NewMsgTx(tx.Version)
+	mtx.LockTime = tx.LockTime
+	for i := range tx.Vin {
+		vin := &tx.Vin[i]
+		var in *wire.TxIn
+		var err error
+		if vin.Txid == "" {
+			// coinbase input or privacy spend parsed as coinbase input
+			in, err = spendTxIn(vin)
+		} else {
+			var h *chainhash.Hash
+			if h, err = chainhash.NewHashFromStr(vin.Txid); err != nil {
+				return nil, err
+			}
+			in = &wire.TxIn{
+				PreviousOutPoint: wire.OutPoint{Hash: *h, Index: vin.Vout},
+				Sequence:         vin.Sequence,
+				Witness:          vin.Witness,
+			}
+			in.SignatureScript, err = hex.DecodeString(vin.ScriptSig.Hex)
+		}
+		if err != nil {
+			return nil, err
+		}
+		mtx.AddTxIn(in)
+	}
+	for i := range tx.Vout {
+		vout := &tx.Vout[i]
+		script, err := hex.DecodeString(vout.ScriptPubKey.Hex)
+		if err != nil {
+			return nil, err
+		}
+		mtx.AddTxOut(wire.NewTxOut(vout.ValueSat.Int64(), script))
+	}
+	return mtx, nil
+}
+
+// spendTxIn reverses the conversion of the privacy spend input to the coinbase input done by the parser,
+// it returns the input with the original all-zero prevout txid, the prevout index 0xffffffff, the spend script
+// as scriptSig and the original sequence, so that the txid of the reconstructed transaction is preserved,
+// the coinbase input is reconstructed in the same way because it has the same null prevout
+func spendTxIn(vin *bchain.Vin) (*wire.TxIn, error) {
+	script, err := hex.DecodeString(vinScript(vin))
+	if err != nil {
+		return nil, err
+	}
+	return &wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		SignatureScript:  script,
+		Sequence:         vin.Sequence,
+		Witness:          vin.Witness,
+	}, nil
+}
@@ -1,30 +1,55 @@
 package bchain
 
 import (
+	"strings"
 	"time"
 
 	"github.com/golang/glog"
 )
 
+// privacySerialRetention is the time for which the first seen times of the privacy spend serials are kept
+const privacySerialRetention = 7 * 24 * time.Hour
+
 // MempoolBitcoinType is mempool handle.
 type MempoolBitcoinType struct {
 	BaseMempool
 	chanTxid            chan string
 	chanAddrIndex       chan txidio
 	AddrDescForOutpoint AddrDescForOutpointFunc
+	expiry              time.Duration
+	// expired contains transactions removed from the mempool view due to expiry which are still in the backend mempool
+	expired map[string]struct{}
+	rbf     bool
+	// if rbf is disabled, spentOutpoints maps the outpoints spent by the mempool transactions to the spending txid,
+	// txInputs contains the spent outpoints of the transactions and rejected maps the transactions rejected
+	// due to a conflict to the first seen transaction kept in the view
+	spentOutpoints map[Outpoint]string
+	txInputs       map[string][]Outpoint
+	rejected       map[string]string
 }
 
 // NewMempoolBitcoinType creates new mempool handler.
 // For now there is no cleanup of sync routines, the expectation is that the mempool is created only once per process
-func NewMempoolBitcoinType(chain BlockChain, workers int, subworkers int) *MempoolBitcoinType {
+// Transactions are removed from the mempool view after expiry even if the backend still keeps them, zero expiry disables it
+// If rbf is false, a transaction spending an input of a transaction already in the view is not added to the view
+// and the first seen transaction is kept even if the backend replaces it
+func NewMempoolBitcoinType(chain BlockChain, workers int, subworkers int, expiry time.Duration, rbf bool) *MempoolBitcoinType {
 	m := &MempoolBitcoinType{
 		BaseMempool: BaseMempool{
 			chain:        chain,
 			txEntries:    make(map[string]txEntry),
 			addrDescToTx: make(map[string][]Outpoint),
+			serialTimes:  make(map[string]uint32),
+			privacyTxs:   make(map[string]*MempoolPrivacySummary),
 		},
-		chanTxid:      make(chan string, 1),
-		chanAddrIndex: make(chan txidio, 1),
+		chanTxid:       make(chan string, 1),
+		chanAddrIndex:  make(chan txidio, 1),
+		expiry:         expiry,
+		expired:        make(map[string]struct{}),
+		rbf:            rbf,
+		spentOutpoints: make(map[Outpoint]string),
+		txInputs:       make(map[string][]Outpoint),
+		rejected:       make(map[string]string),
 	}
 	for i := 0; i < workers; i++ {
 		go func(i int) {
@@ -39,15 +64,15 @@ func NewMempoolBitcoinType(chain BlockChain, workers int, subworkers int) *Mempo
 				}(j)
 			}
 			for txid := range m.chanTxid {
-				io, ok := m.getTxAddrs(txid, chanInput, chanResult)
+				io, privacy, inputs, ok := m.getTxAddrs(txid, chanInput, chanResult)
 				if !ok {
 					io = []addrIndex{}
 				}
-				m.chanAddrIndex <- txidio{txid, io}
+				m.chanAddrIndex <- txidio{txid, io, privacy, inputs}
 			}
 		}(i)
 	}
-	glog.Info("mempool: starting with ", workers, "*", subworkers, " sync workers")
+	glog.Info("mempool: starting with ", workers, "*", subworkers, " sync workers, expiry ", expiry)
 	return m
 }
 
@@ -76,13 +101,16 @@ func (m *MempoolBitcoinType) getInputAddress(input Outpoint) *addrIndex {
 
 }
 
-func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan Outpoint, chanResult chan *addrIndex) ([]addrIndex, bool) {
+// getTxAddrs returns the addresses of the outputs and inputs of the mempool transaction, its privacy mints and spends
+// and the outpoints spent by its inputs
+func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan Outpoint, chanResult chan *addrIndex) ([]addrIndex, *MempoolPrivacySummary, []Outpoint, bool) {
 	tx, err := m.chain.GetTransactionForMempool(txid)
 	if err != nil {
 		glog.Error("cannot get transaction ", txid, ": ", err)
-		return nil, false
+		return nil, nil, nil, false
 	}
 	glog.V(2).Info("mempool: gettxaddrs ", txid, ", ", len(tx.Vin), " inputs")
+	m.addPrivacySerials(tx, uint32(time.Now().Unix()))
 	io := make([]addrIndex, 0, len(tx.Vout)+len(tx.Vin))
 	for _, output := range tx.Vout {
 		addrDesc, err := m.chain.GetChainParser().GetAddrDescFromVout(&output)
@@ -98,11 +126,14 @@ func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan Outpoint, ch
 		}
 	}
 	dispatched := 0
-	for _, input := range tx.Vin {
-		if input.Coinbase != "" {
+	inputs := make([]Outpoint, 0, len(tx.Vin))
+	for i := range tx.Vin {
+		input := &tx.Vin[i]
+		if !hasPrevout(m.chain.GetChainParser(), input) {
 			continue
 		}
 		o := Outpoint{input.Txid, int32(input.Vout)}
+		inputs = append(inputs, o)
 	loop:
 		for {
 			select {
@@ -125,7 +156,42 @@ func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan Outpoint, ch
 			io = append(io, *ai)
 		}
 	}
-	return io, true
+	return io, txPrivacySummary(m.chain.GetChainParser(), tx), inputs, true
+}
+
+// hasPrevout returns false for the inputs which do not spend a real outpoint, the coinbase and privacy spend inputs,
+// the privacy spends of all transactions have the same null prevout and must not be taken as conflicting
+func hasPrevout(parser BlockChainParser, input *Vin) bool {
+	return input.Coinbase == "" && parser.GetPrivacySpendAddrDesc(input) == nil && strings.TrimLeft(input.Txid, "0") != ""
+}
+
+// conflictingTx returns the txid of the transaction in the view spending an outpoint spent also by the inputs,
+// empty string if there is no such transaction. The caller is responsible for locking!
+func (m *MempoolBitcoinType) conflictingTx(txid string, inputs []Outpoint) string {
+	for _, o := range inputs {
+		if spending, found := m.spentOutpoints[o]; found && spending != txid {
+			return spending
+		}
+	}
+	return ""
+}
+
+// addTxInputs records the outpoints spent by the transaction. The caller is responsible for locking!
+func (m *MempoolBitcoinType) addTxInputs(txid string, inputs []Outpoint) {
+	m.txInputs[txid] = inputs
+	for _, o := range inputs {
+		m.spentOutpoints[o] = txid
+	}
+}
+
+// removeTxInputs removes the outpoints spent by the transaction. The caller is responsible for locking!
+func (m *MempoolBitcoinType) removeTxInputs(txid string) {
+	for _, o := range m.txInputs[txid] {
+		if m.spentOutpoints[o] == txid {
+			delete(m.spentOutpoints, o)
+		}
+	}
+	delete(m.txInputs, txid)
 }
 
 // Resync gets mempool transactions and maps outputs to transactions.
@@ -139,13 +205,23 @@ func (m *MempoolBitcoinType) Resync() (int, error) {
 		return 0, err
 	}
 	glog.V(2).Info("mempool: resync ", len(txs), " txs")
-	onNewEntry := func(txid string, entry txEntry) {
+	onNewEntry := func(tio txidio, entry txEntry) {
 		if len(entry.addrIndexes) > 0 {
 			m.mux.Lock()
-			m.txEntries[txid] = entry
+			if !m.rbf {
+				if first := m.conflictingTx(tio.txid, tio.inputs); first != "" {
+					m.rejected[tio.txid] = first
+					m.mux.Unlock()
+					glog.Info("mempool: tx ", tio.txid, " conflicts with first seen tx ", first, ", not added")
+					return
+				}
+				m.addTxInputs(tio.txid, tio.inputs)
+			}
+			m.txEntries[tio.txid] = entry
 			for _, si := range entry.addrIndexes {
-				m.addrDescToTx[si.addrDesc] = append(m.addrDescToTx[si.addrDesc], Outpoint{txid, si.n})
+				m.addrDescToTx[si.addrDesc] = append(m.addrDescToTx[si.addrDesc], Outpoint{tio.txid, si.n})
 			}
+			m.addPrivacyTx(tio.txid, tio.privacy)
 			m.mux.Unlock()
 		}
 	}
@@ -156,13 +232,15 @@ func (m *MempoolBitcoinType) Resync() (int, error) {
 	for _, txid := range txs {
 		txsMap[txid] = struct{}{}
 		_, exists := m.txEntries[txid]
-		if !exists {
+		_, expired := m.expired[txid]
+		_, rejected := m.rejected[txid]
+		if !exists && !expired && !rejected {
 		loop:
 			for {
 				select {
 				// store as many processed transactions as possible
 				case tio := <-m.chanAddrIndex:
-					onNewEntry(tio.txid, txEntry{tio.io, txTime})
+					onNewEntry(tio, txEntry{tio.io, txTime})
 					dispatched--
 				// send transaction to be processed
 				case m.chanTxid <- txid:
@@ -174,16 +252,52 @@ func (m *MempoolBitcoinType) Resync() (int, error) {
 	}
 	for i := 0; i < dispatched; i++ {
 		tio := <-m.chanAddrIndex
-		onNewEntry(tio.txid, txEntry{tio.io, txTime})
+		onNewEntry(tio, txEntry{tio.io, txTime})
 	}
 
+	var expiryTime uint32
+	if m.expiry > 0 {
+		expiryTime = txTime - uint32(m.expiry/time.Second)
+	}
+	// the first seen transactions replaced in the backend by the rejected transactions are kept in the view
+	replaced := make(map[string]struct{})
+	for txid, first := range m.rejected {
+		if _, exists := txsMap[txid]; exists {
+			replaced[first] = struct{}{}
+		}
+	}
 	for txid, entry := range m.txEntries {
-		if _, exists := txsMap[txid]; !exists {
+		_, exists := txsMap[txid]
+		if _, r := replaced[txid]; r {
+			exists = true
+		}
+		if exists && entry.time < expiryTime {
+			m.expired[txid] = struct{}{}
+			exists = false
+		}
+		if !exists {
 			m.mux.Lock()
 			m.removeEntryFromMempool(txid, entry)
+			m.removeTxInputs(txid)
 			m.mux.Unlock()
 		}
 	}
+	// forget the rejected transactions which are no longer in the backend mempool or whose first seen transaction
+	// left the view, the latter are fetched again by the next resync
+	for txid, first := range m.rejected {
+		_, exists := txsMap[txid]
+		_, kept := m.txEntries[first]
+		if !exists || !kept {
+			delete(m.rejected, txid)
+		}
+	}
+	m.removeOldPrivacySerials(txTime - uint32(privacySerialRetention/time.Second))
+	// forget the expired transactions which are no longer in the backend mempool
+	for txid := range m.expired {
+		if _, exists := txsMap[txid]; !exists {
+			delete(m.expired, txid)
+		}
+	}
 	glog.Info("mempool: resync finished in ", time.Since(start), ", ", len(m.txEntries), " transactions in mempool")
 	return len(m.txEntries), nil
 }
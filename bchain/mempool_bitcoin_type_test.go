@@ -0,0 +1,345 @@
+package bchain
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testMempoolParser struct {
+	BlockChainParser
+}
+
+func (p *testMempoolParser) GetAddrDescFromVout(output *Vout) (AddressDescriptor, error) {
+	return hex.DecodeString(output.ScriptPubKey.Hex)
+}
+
+// GetPrivacySpendSerial returns the serial of the test spends, which have the scriptSig "c2" + serial
+func (p *testMempoolParser) GetPrivacySpendSerial(vin *Vin) string {
+	if strings.HasPrefix(vin.ScriptSig.Hex, "c2") {
+		return vin.ScriptSig.Hex[2:]
+	}
+	return ""
+}
+
+// GetPrivacySpendAddrDesc returns the opcode of the test spends as the pseudo address descriptor
+func (p *testMempoolParser) GetPrivacySpendAddrDesc(vin *Vin) AddressDescriptor {
+	if strings.HasPrefix(vin.ScriptSig.Hex, "c2") {
+		return AddressDescriptor{0xc2}
+	}
+	return nil
+}
+
+// IsPrivacyMintAddrDesc returns true for the test mints, which have the script "c1" + txid
+func (p *testMempoolParser) IsPrivacyMintAddrDesc(addrDesc AddressDescriptor) bool {
+	return len(addrDesc) > 0 && addrDesc[0] == 0xc1
+}
+
+// GetShieldedFlows returns the value of the test mints as minted and 1 coin for each test spend as spent
+func (p *testMempoolParser) GetShieldedFlows(tx *Tx) (*big.Int, *big.Int) {
+	minted, spent := big.NewInt(0), big.NewInt(0)
+	for i := range tx.Vin {
+		if p.GetPrivacySpendAddrDesc(&tx.Vin[i]) != nil {
+			spent.Add(spent, big.NewInt(100000000))
+		}
+	}
+	for i := range tx.Vout {
+		if strings.HasPrefix(tx.Vout[i].ScriptPubKey.Hex, "c1") {
+			minted.Add(minted, &tx.Vout[i].ValueSat)
+		}
+	}
+	return minted, spent
+}
+
+type testMempoolChain struct {
+	BlockChain
+	txids   []string
+	serials map[string]string
+	mints   map[string]bool
+	inputs  map[string]Outpoint
+	// spends contains the number of privacy spend inputs of the transaction, all with the null prevout
+	spends map[string]int
+}
+
+func (c *testMempoolChain) GetChainParser() BlockChainParser {
+	return &testMempoolParser{}
+}
+
+func (c *testMempoolChain) GetMempoolTransactions() ([]string, error) {
+	return c.txids, nil
+}
+
+func (c *testMempoolChain) GetTransactionForMempool(txid string) (*Tx, error) {
+	script := "76a914" + txid + "88ac"
+	if c.mints[txid] {
+		script = "c1" + txid
+	}
+	vin := Vin{Coinbase: "00", ScriptSig: ScriptSig{Hex: c.serials[txid]}}
+	if o, found := c.inputs[txid]; found {
+		vin = Vin{Txid: o.Txid, Vout: uint32(o.Vout)}
+	}
+	vins := []Vin{vin}
+	if n := c.spends[txid]; n > 0 {
+		vins = make([]Vin, n)
+		for i := range vins {
+			vins[i] = Vin{
+				Txid:      "0000000000000000000000000000000000000000000000000000000000000000",
+				Vout:      0xffffffff,
+				ScriptSig: ScriptSig{Hex: fmt.Sprintf("c2%s%02x", txid, i)},
+			}
+		}
+	}
+	return &Tx{
+		Txid: txid,
+		Vin:  vins,
+		Vout: []Vout{{N: 0, ValueSat: *big.NewInt(50000000), ScriptPubKey: ScriptPubKey{Hex: script}}},
+	}, nil
+}
+
+func TestMempoolBitcoinType_Expiry(t *testing.T) {
+	chain := &testMempoolChain{txids: []string{"01", "02"}}
+	m := NewMempoolBitcoinType(chain, 1, 1, time.Hour, true)
+	count, err := m.Resync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("Resync() = %d, want 2", count)
+	}
+	// the transaction stays in the mempool view for the expiry duration
+	m.txEntries["01"] = txEntry{m.txEntries["01"].addrIndexes, uint32(time.Now().Add(-59 * time.Minute).Unix())}
+	if count, _ = m.Resync(); count != 2 {
+		t.Fatalf("Resync() before expiry = %d, want 2", count)
+	}
+	// and is removed after it, even if the backend still keeps it
+	m.txEntries["01"] = txEntry{m.txEntries["01"].addrIndexes, uint32(time.Now().Add(-61 * time.Minute).Unix())}
+	if count, _ = m.Resync(); count != 1 {
+		t.Fatalf("Resync() after expiry = %d, want 1", count)
+	}
+	if o, _ := m.GetAddrDescTransactions(AddressDescriptor{0x76, 0xa9, 0x14, 0x01, 0x88, 0xac}); len(o) != 0 {
+		t.Errorf("GetAddrDescTransactions() of expired transaction = %v", o)
+	}
+	if count, _ = m.Resync(); count != 1 {
+		t.Fatalf("Resync() of expired transaction = %d, want 1", count)
+	}
+	// the expired transaction removed from the backend mempool is forgotten
+	chain.txids = []string{"02"}
+	if count, _ = m.Resync(); count != 1 || len(m.expired) != 0 {
+		t.Fatalf("Resync() = %d, expired %v, want 1 and no expired", count, m.expired)
+	}
+}
+
+func TestMempoolBitcoinType_NoExpiry(t *testing.T) {
+	chain := &testMempoolChain{txids: []string{"01"}}
+	m := NewMempoolBitcoinType(chain, 1, 1, 0, true)
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	m.txEntries["01"] = txEntry{m.txEntries["01"].addrIndexes, uint32(time.Now().Add(-30 * 24 * time.Hour).Unix())}
+	if count, _ := m.Resync(); count != 1 {
+		t.Fatalf("Resync() = %d, want 1", count)
+	}
+}
+
+func TestMempoolBitcoinType_FirstSeen(t *testing.T) {
+	chain := &testMempoolChain{txids: []string{"01"}}
+	m := NewMempoolBitcoinType(chain, 1, 1, 0, true)
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if m.GetTransactionTime("01") == 0 {
+		t.Fatal("GetTransactionTime() = 0, want the time of the first resync")
+	}
+	// the first seen time is kept by the following resyncs
+	firstSeen := uint32(time.Now().Add(-10 * time.Minute).Unix())
+	m.txEntries["01"] = txEntry{m.txEntries["01"].addrIndexes, firstSeen}
+	chain.txids = []string{"01", "02"}
+	for i := 0; i < 2; i++ {
+		if _, err := m.Resync(); err != nil {
+			t.Fatal(err)
+		}
+		if got := m.GetTransactionTime("01"); got != firstSeen {
+			t.Fatalf("GetTransactionTime() after resync %d = %v, want %v", i, got, firstSeen)
+		}
+	}
+	// and the time is not known after the transaction leaves the mempool
+	chain.txids = []string{"02"}
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetTransactionTime("01"); got != 0 {
+		t.Errorf("GetTransactionTime() of removed transaction = %v, want 0", got)
+	}
+}
+
+func TestMempoolBitcoinType_PrivacySerialTime(t *testing.T) {
+	chain := &testMempoolChain{txids: []string{"01", "02"}, serials: map[string]string{"01": "c2aa"}}
+	m := NewMempoolBitcoinType(chain, 1, 1, 0, true)
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	first := m.GetPrivacySerialTime("aa")
+	if first == 0 {
+		t.Fatal("GetPrivacySerialTime() of spend in mempool = 0")
+	}
+	if got := m.GetPrivacySerialTime("bb"); got != 0 {
+		t.Errorf("GetPrivacySerialTime() of unknown serial = %d, want 0", got)
+	}
+	// the earliest time is kept if the serial is spent again by another transaction
+	earliest := first - 3600
+	m.serialTimes["aa"] = earliest
+	chain.txids = []string{"02", "03"}
+	chain.serials["03"] = "c2aa"
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetPrivacySerialTime("aa"); got != earliest {
+		t.Errorf("GetPrivacySerialTime() after respend = %d, want %d", got, earliest)
+	}
+	// the time is kept after the spend leaves the mempool
+	chain.txids = []string{"02"}
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetPrivacySerialTime("aa"); got != earliest {
+		t.Errorf("GetPrivacySerialTime() after leaving mempool = %d, want %d", got, earliest)
+	}
+	// and removed after the retention time
+	m.serialTimes["aa"] = uint32(time.Now().Add(-privacySerialRetention - time.Hour).Unix())
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetPrivacySerialTime("aa"); got != 0 {
+		t.Errorf("GetPrivacySerialTime() after retention = %d, want 0", got)
+	}
+}
+
+func TestMempoolBitcoinType_PrivacySummary(t *testing.T) {
+	chain := &testMempoolChain{
+		txids:   []string{"01", "02", "03"},
+		serials: map[string]string{"01": "c2aa"},
+		mints:   map[string]bool{"02": true},
+	}
+	m := NewMempoolBitcoinType(chain, 1, 1, 0, true)
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	got := m.GetPrivacySummary()
+	if got.Mints != 1 || got.MintedSat.Int64() != 50000000 || got.Spends != 1 || got.SpentSat.Int64() != 100000000 {
+		t.Errorf("GetPrivacySummary() = %+v, want 1 mint of 50000000 and 1 spend of 100000000", got)
+	}
+	// the privacy operations are removed with the transactions leaving the mempool
+	chain.txids = []string{"02", "03"}
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	got = m.GetPrivacySummary()
+	if got.Mints != 1 || got.MintedSat.Int64() != 50000000 || got.Spends != 0 || got.SpentSat.Sign() != 0 {
+		t.Errorf("GetPrivacySummary() after spend left = %+v, want 1 mint of 50000000 and no spends", got)
+	}
+	chain.txids = nil
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if got = m.GetPrivacySummary(); got.Mints != 0 || got.MintedSat.Sign() != 0 || len(m.privacyTxs) != 0 {
+		t.Errorf("GetPrivacySummary() of empty mempool = %+v", got)
+	}
+}
+
+func TestBlockPrivacySummary(t *testing.T) {
+	chain := &testMempoolChain{
+		txids:   []string{"01", "02", "03", "04"},
+		serials: map[string]string{"01": "c2aa", "04": "c2bb"},
+		mints:   map[string]bool{"02": true, "03": true},
+	}
+	block := Block{}
+	for _, txid := range chain.txids {
+		tx, err := chain.GetTransactionForMempool(txid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.Txs = append(block.Txs, *tx)
+	}
+	got := BlockPrivacySummary(chain.GetChainParser(), &block)
+	if got.Mints != 2 || got.MintedSat.Int64() != 100000000 || got.Spends != 2 || got.SpentSat.Int64() != 200000000 {
+		t.Errorf("BlockPrivacySummary() = %+v, want 2 mints of 100000000 and 2 spends of 200000000", got)
+	}
+	// the block is accounted in the same way as the same transactions in the mempool
+	m := NewMempoolBitcoinType(chain, 1, 1, 0, true)
+	if _, err := m.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	want := m.GetPrivacySummary()
+	if got.Mints != want.Mints || got.MintedSat.Cmp(&want.MintedSat) != 0 || got.Spends != want.Spends || got.SpentSat.Cmp(&want.SpentSat) != 0 {
+		t.Errorf("BlockPrivacySummary() = %+v, mempool summary %+v", got, want)
+	}
+	if got = BlockPrivacySummary(chain.GetChainParser(), &Block{}); got.Mints != 0 || got.MintedSat.Sign() != 0 || got.Spends != 0 {
+		t.Errorf("BlockPrivacySummary() of empty block = %+v", got)
+	}
+}
+
+func TestMempoolBitcoinType_RBF(t *testing.T) {
+	tests := []struct {
+		name string
+		rbf  bool
+		want string
+	}{
+		{name: "rbf enabled", rbf: true, want: "02"},
+		{name: "rbf disabled", rbf: false, want: "01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the transaction 02 replaces 01 in the backend mempool
+			chain := &testMempoolChain{
+				txids:  []string{"01"},
+				inputs: map[string]Outpoint{"01": {"aa", 0}, "02": {"aa", 0}},
+			}
+			m := NewMempoolBitcoinType(chain, 1, 1, 0, tt.rbf)
+			if _, err := m.Resync(); err != nil {
+				t.Fatal(err)
+			}
+			chain.txids = []string{"02"}
+			count, err := m.Resync()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, found := m.txEntries[tt.want]; count != 1 || !found {
+				t.Fatalf("Resync() = %d, entries %v, want only %v", count, m.txEntries, tt.want)
+			}
+			// the view is not changed by the next resync
+			if count, _ = m.Resync(); count != 1 || m.GetTransactionTime(tt.want) == 0 {
+				t.Fatalf("Resync() again = %d, entries %v, want only %v", count, m.txEntries, tt.want)
+			}
+			// both transactions are forgotten when the replacement leaves the backend mempool
+			chain.txids = nil
+			if count, _ = m.Resync(); count != 0 || len(m.rejected) != 0 || len(m.spentOutpoints) != 0 {
+				t.Fatalf("Resync() = %d, rejected %v, spent outpoints %v, want empty", count, m.rejected, m.spentOutpoints)
+			}
+		})
+	}
+}
+
+func TestMempoolBitcoinType_RBFPrivacySpends(t *testing.T) {
+	// the unrelated privacy spends with two inputs each, all inputs have the same null prevout
+	chain := &testMempoolChain{
+		txids:  []string{"01", "02"},
+		spends: map[string]int{"01": 2, "02": 2},
+	}
+	m := NewMempoolBitcoinType(chain, 1, 1, 0, false)
+	count, err := m.Resync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || len(m.rejected) != 0 {
+		t.Fatalf("Resync() = %d, rejected %v, want 2 and no rejected", count, m.rejected)
+	}
+	if len(m.spentOutpoints) != 0 {
+		t.Errorf("spent outpoints %v, want empty", m.spentOutpoints)
+	}
+	if s := m.GetPrivacySummary(); s.Spends != 4 {
+		t.Errorf("GetPrivacySummary() spends = %d, want 4", s.Spends)
+	}
+}
@@ -0,0 +1,56 @@
+package bchain
+
+import (
+	"encoding/json"
+
+	"github.com/golang/glog"
+	zmq "github.com/pebbe/zmq4"
+)
+
+// privacyEventTopic is the ZeroMQ topic of the privacy event messages
+const privacyEventTopic = "privacy"
+
+// MQPublisher publishes the privacy events as JSON messages with the topic "privacy" to ZeroMQ PUB socket
+type MQPublisher struct {
+	context *zmq.Context
+	socket  *zmq.Socket
+	binding string
+}
+
+// NewMQPublisher creates new ZeroMQ publisher bound to the binding
+func NewMQPublisher(binding string) (*MQPublisher, error) {
+	context, err := zmq.NewContext()
+	if err != nil {
+		return nil, err
+	}
+	socket, err := context.NewSocket(zmq.PUB)
+	if err != nil {
+		return nil, err
+	}
+	if err = socket.Bind(binding); err != nil {
+		return nil, err
+	}
+	glog.Info("MQ publishing privacy events to ", binding)
+	return &MQPublisher{context, socket, binding}, nil
+}
+
+// Publish sends the event to the socket
+func (p *MQPublisher) Publish(event *PrivacyEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = p.socket.SendMessage(privacyEventTopic, b)
+	return err
+}
+
+// Close unbinds and closes the socket
+func (p *MQPublisher) Close() error {
+	if err := p.socket.Unbind(p.binding); err != nil {
+		return err
+	}
+	if err := p.socket.Close(); err != nil {
+		return err
+	}
+	return p.context.Term()
+}
@@ -55,6 +55,14 @@ type Vin struct {
 	ScriptSig ScriptSig `json:"scriptSig"`
 	Sequence  uint32    `json:"sequence"`
 	Addresses []string  `json:"addresses"`
+	// Witness is filled only by parsers which support it, it is not stored in db
+	Witness [][]byte `json:"witness,omitempty"`
+	// ValueSat is the value of the spent output, filled only by parsers which read it from the backend JSON,
+	// nil if not known, it is not stored in db
+	ValueSat *big.Int `json:"-"`
+	// AccumulatorHeight is the height of the accumulator state referenced by the proof of a privacy spend,
+	// filled only by parsers which support it, it is not stored in db
+	AccumulatorHeight uint32 `json:"accumulatorHeight,omitempty"`
 }
 
 // ScriptPubKey contains data about output script
@@ -71,6 +79,9 @@ type Vout struct {
 	JsonValue    json.Number  `json:"value"`
 	N            uint32       `json:"n"`
 	ScriptPubKey ScriptPubKey `json:"scriptPubKey"`
+	// SpentInBlock is set if the output is spent by another transaction of the same block,
+	// set only by the parsers which support it, it is not stored in db
+	SpentInBlock bool `json:"spentInBlock,omitempty"`
 }
 
 // Tx is blockchain transaction
@@ -88,12 +99,96 @@ type Tx struct {
 	Time             int64       `json:"time,omitempty"`
 	Blocktime        int64       `json:"blocktime,omitempty"`
 	CoinSpecificData interface{} `json:"-"`
+	// BlockPosition is the index of the transaction in the block, set only by the parsers which support it
+	BlockPosition int `json:"-"`
+	// BaseSize is the size without the witness data, WitnessSize the size of the witness data including
+	// the segwit marker and flag and VSize the virtual size as defined by BIP141,
+	// set only by the parsers which support it, they are not stored in db
+	BaseSize    int `json:"-"`
+	WitnessSize int `json:"-"`
+	VSize       int `json:"-"`
+	// Comment is the comment (message) attached to the transaction, set only by the parsers which support it,
+	// it is not stored in db
+	Comment string `json:"comment,omitempty"`
+}
+
+// IsCoinstakeTx returns true if the transaction is a proof-of-stake coinstake transaction,
+// coinstake has inputs, at least two outputs and the first output is empty
+func IsCoinstakeTx(tx *Tx) bool {
+	return len(tx.Vin) > 0 && tx.Vin[0].Txid != "" && len(tx.Vout) >= 2 && tx.Vout[0].ValueSat.Sign() == 0 && tx.Vout[0].ScriptPubKey.Hex == ""
+}
+
+// MarkIntraBlockSpends sets SpentInBlock of the outputs of the block which are spent by the inputs
+// of the transactions of the same block, the privacy spends do not reference transparent outputs and do not match
+func MarkIntraBlockSpends(block *Block) {
+	index := make(map[string]int, len(block.Txs))
+	for i := range block.Txs {
+		index[block.Txs[i].Txid] = i
+	}
+	for i := range block.Txs {
+		for j := range block.Txs[i].Vin {
+			vin := &block.Txs[i].Vin[j]
+			if vin.Txid == "" {
+				continue
+			}
+			if k, found := index[vin.Txid]; found && int(vin.Vout) < len(block.Txs[k].Vout) {
+				block.Txs[k].Vout[vin.Vout].SpentInBlock = true
+			}
+		}
+	}
 }
 
 // Block is block header and list of transactions
 type Block struct {
 	BlockHeader
 	Txs []Tx `json:"tx"`
+	// HeaderTime is the timestamp from the block header, set only if Time is taken from a different source
+	HeaderTime int64 `json:"headerTime,omitempty"`
+	// Version is the version from the block header including the soft fork signaling bits,
+	// set only by the parsers which support it
+	Version int32 `json:"version,omitempty"`
+	// RawHeader is the standard 80 bytes block header, set only by the parsers which support it
+	RawHeader []byte `json:"-"`
+	// Denominations counts the privacy mints and spends of the block by the denomination in satoshis,
+	// set only by the parsers which support it and if it is enabled in their configuration
+	Denominations map[string]DenominationCount `json:"denominations,omitempty"`
+	// SpendProofs is the size of the proofs of the privacy spends of the block by the type of the spend,
+	// set only by the parsers which support it and if it is enabled in their configuration
+	SpendProofs map[string]SpendProofSize `json:"spendProofs,omitempty"`
+}
+
+// TxFlags are the flags of the privacy operations of a transaction
+type TxFlags byte
+
+// privacy operation flags of transactions
+const (
+	// TxFlagPrivacyMint is set if the transaction has a privacy mint output
+	TxFlagPrivacyMint TxFlags = 1 << iota
+	// TxFlagPrivacySpend is set if the transaction has a privacy spend input
+	TxFlagPrivacySpend
+	// TxFlagRemint is set if the transaction mints the spent privacy funds again, i.e. it has both privacy spends and mints
+	TxFlagRemint
+)
+
+// DenominationCount is the number of privacy mints and spends of a denomination
+type DenominationCount struct {
+	Mints  int `json:"mints"`
+	Spends int `json:"spends"`
+}
+
+// SpendProofSize is the number of privacy spend inputs and the total and the maximum size in bytes of their proofs
+type SpendProofSize struct {
+	Spends    int `json:"spends"`
+	TotalSize int `json:"totalSize"`
+	MaxSize   int `json:"maxSize"`
+}
+
+// AvgSize returns the average size of the proof of a spend, 0 if there are no spends
+func (s *SpendProofSize) AvgSize() float64 {
+	if s.Spends == 0 {
+		return 0
+	}
+	return float64(s.TotalSize) / float64(s.Spends)
 }
 
 // BlockHeader contains limited data (as needed for indexing) from backend block header
@@ -203,12 +298,34 @@ type MempoolTxidEntry struct {
 // MempoolTxidEntries is array of MempoolTxidEntry
 type MempoolTxidEntries []MempoolTxidEntry
 
+// MempoolPrivacySummary contains the number and the value of the privacy mints and spends of the mempool transactions
+type MempoolPrivacySummary struct {
+	Mints     int
+	MintedSat big.Int
+	Spends    int
+	SpentSat  big.Int
+}
+
 // OnNewBlockFunc is used to send notification about a new block
 type OnNewBlockFunc func(hash string, height uint32)
 
 // OnNewTxAddrFunc is used to send notification about a new transaction/address
 type OnNewTxAddrFunc func(tx *Tx, desc AddressDescriptor)
 
+// PrivacyEvent describes a privacy mint or spend connected to the chain
+type PrivacyEvent struct {
+	Txid   string `json:"txid"`
+	Height uint32 `json:"height"`
+	Type   string `json:"type"`
+	Value  string `json:"value,omitempty"`
+	Serial string `json:"serial,omitempty"`
+}
+
+// PrivacyEventPublisher publishes the privacy events to a message queue, the adapters of the queues implement it
+type PrivacyEventPublisher interface {
+	Publish(event *PrivacyEvent) error
+}
+
 // AddrDescForOutpointFunc defines function that returns address descriptorfor given outpoint or nil if outpoint not found
 type AddrDescForOutpointFunc func(outpoint Outpoint) AddressDescriptor
 
@@ -263,8 +380,27 @@ type BlockChainParser interface {
 	KeepBlockAddresses() int
 	// AmountDecimals returns number of decimal places in coin amounts
 	AmountDecimals() int
+	// CoinShortcut returns the ticker of the coin, as configured for the coin or its fork
+	CoinShortcut() string
 	// MinimumCoinbaseConfirmations returns minimum number of confirmations a coinbase transaction must have before it can be spent
 	MinimumCoinbaseConfirmations() int
+	// RequiredConfirmations returns the number of confirmations the output vout of a transaction must have before
+	// it can be spent, the outputs of the coinbase and coinstake transactions must mature
+	RequiredConfirmations(vout uint32, isCoinbase, isStake bool) int
+	// IsBIP30Exception returns true if the transaction at the height is a known duplicate of the txid of an earlier
+	// transaction (BIP30), such a transaction is not indexed so that the original one is kept
+	IsBIP30Exception(height uint32, txid string) bool
+	// MaxMoneySat returns the maximum money supply of the coin in satoshis, used to validate the parsed values
+	MaxMoneySat() *big.Int
+	// FinalityConfirmations returns number of confirmations after which a transaction is considered final
+	FinalityConfirmations() int
+	// DustThresholdSat returns the value below which an output is considered dust, 0 if the coin does not define dust
+	DustThresholdSat() int64
+	// IsNonStandardOutput returns true if the output script is valid but not standard, for example oversized OP_RETURN output
+	IsNonStandardOutput(addrDesc AddressDescriptor) bool
+	// Confirmations returns number of confirmations of a transaction in block txHeight given the best block tipHeight
+	// the mempool transactions are not in a block, they have 0 confirmations and the callers do not pass them
+	Confirmations(txHeight, tipHeight uint32) int
 	// AmountToDecimalString converts amount in big.Int to string with decimal point in the correct place
 	AmountToDecimalString(a *big.Int) string
 	// AmountToBigInt converts amount in json.Number (string) to big.Int
@@ -282,14 +418,51 @@ type BlockChainParser interface {
 	UnpackTxid(buf []byte) (string, error)
 	ParseTx(b []byte) (*Tx, error)
 	ParseTxFromJson(json.RawMessage) (*Tx, error)
+	// CanonicalTxJSON returns the transaction JSON with sorted object keys and normalized numbers,
+	// transactions differing only in formatting have the same canonical form
+	CanonicalTxJSON(msg json.RawMessage) ([]byte, error)
 	PackTx(tx *Tx, height uint32, blockTime int64) ([]byte, error)
 	UnpackTx(buf []byte) (*Tx, uint32, error)
 	GetAddrDescForUnknownInput(tx *Tx, input int) AddressDescriptor
+	// IsPrivacyTx returns true if the transaction contains privacy (shielded) operations
+	IsPrivacyTx(tx *Tx) bool
+	// GetPrivacySpendAddrDesc returns pseudo address descriptor marking privacy spend input, nil for other inputs
+	GetPrivacySpendAddrDesc(vin *Vin) AddressDescriptor
+	// GetPrivacySpendSerial returns the hex encoded serial of the privacy spend input, empty string if it is not available
+	GetPrivacySpendSerial(vin *Vin) string
+	// PrivacySpendUnlockHeight returns the height from which the transaction with privacy spends can be included
+	// in a block due to its lock time, 0 if the transaction has no privacy spends or is not timelocked by height
+	PrivacySpendUnlockHeight(tx *Tx) uint32
+	// GetShieldedFlows returns the value moved by the transaction to (minted) and from (spent) the shielded pool
+	GetShieldedFlows(tx *Tx) (minted *big.Int, spent *big.Int)
+	// PrivacyScore returns the privacy indicator of the transaction in the range 0 (fully transparent)
+	// to 100 (fully shielded) derived from the shielded part of its inputs, outputs and value
+	PrivacyScore(tx *Tx) int
+	// ComputeFee returns the fee of the transaction, prevouts are the values of the outputs spent by the inputs
+	// of the transaction in the order of tx.Vin, nil for the inputs without spent output (coinbase, privacy spend)
+	ComputeFee(tx *Tx, prevouts []*big.Int) (*big.Int, error)
+	// GetPseudoAddressType returns the type of the privacy operation marked by the pseudo address descriptor,
+	// empty string if the descriptor is not a pseudo address
+	GetPseudoAddressType(addrDesc AddressDescriptor) string
+	// TxPrivacyFlags returns the flags of the privacy operations of the transaction, 0 for transparent transactions
+	TxPrivacyFlags(tx *Tx) TxFlags
+	// IsPrivacyMintAddrDesc returns true if the output address descriptor is a privacy (shielded) mint
+	IsPrivacyMintAddrDesc(addrDesc AddressDescriptor) bool
+	// GetPrivacyEvents returns the privacy mints and spends of the block
+	GetPrivacyEvents(block *Block) []PrivacyEvent
 	// blocks
 	PackBlockHash(hash string) ([]byte, error)
 	UnpackBlockHash(buf []byte) (string, error)
 	ParseBlock(b []byte) (*Block, error)
+	// Difficulty returns the difficulty decoded from the compact target bits of the block header of the proof-of-work
+	// or proof-of-stake block, 0 if the coin does not define the difficulty
+	Difficulty(bits uint32, proofOfStake bool) float64
+	// TxWeight returns the weight of the transaction as defined by BIP141 (3 * base size + total size),
+	// 0 if the sizes of the transaction cannot be determined
+	TxWeight(tx *Tx) int
 	// xpub
+	// XPubGapLimit returns the coin specific default gap of unused addresses in xpub derivation, 0 means the Blockbook default
+	XPubGapLimit() int
 	DerivationBasePath(xpub string) (string, error)
 	DeriveAddressDescriptors(xpub string, change uint32, indexes []uint32) ([]AddressDescriptor, error)
 	DeriveAddressDescriptorsFromTo(xpub string, change uint32, fromIndex uint32, toIndex uint32) ([]AddressDescriptor, error)
@@ -304,4 +477,6 @@ type Mempool interface {
 	GetAddrDescTransactions(addrDesc AddressDescriptor) ([]Outpoint, error)
 	GetAllEntries() MempoolTxidEntries
 	GetTransactionTime(txid string) uint32
+	GetPrivacySerialTime(serial string) uint32
+	GetPrivacySummary() MempoolPrivacySummary
 }
@@ -11,6 +11,7 @@ import (
 	"context"
 	"encoding/json"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"math/rand"
@@ -19,6 +20,7 @@ import (
 	"os"
 	"os/signal"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"syscall"
@@ -55,11 +57,20 @@ var (
 	synchronize = flag.Bool("sync", false, "synchronizes until tip, if together with zeromq, keeps index synchronized")
 	repair      = flag.Bool("repair", false, "repair the database")
 	fixUtxo     = flag.Bool("fixutxo", false, "check and fix utxo db and exit")
+	fixSpends   = flag.Bool("fixprivacyspends", false, "mark privacy spend inputs indexed as coinbase inputs and exit")
+	dumpBlock   = flag.String("dumpblock", "", "print the parsed block of the given height or hash as JSON and exit")
+	exportMints = flag.String("exportmints", "", "export privacy mints in blockheight-blockuntil range as CSV to the given file (- for stdout) and exit")
 	prof        = flag.String("prof", "", "http server binding [address]:port of the interface to profiling data /debug/pprof/ (default no profiling)")
 
 	syncChunk   = flag.Int("chunk", 100, "block chunk size for processing in bulk mode")
 	syncWorkers = flag.Int("workers", 8, "number of workers to process blocks in bulk mode")
 	dryRun      = flag.Bool("dryrun", false, "do not index blocks, only download")
+	orphans     = flag.Bool("orphans", false, "store blocks disconnected from the main chain in a separate orphans store")
+	privacyOnly = flag.Bool("privacyonly", false, "index only transactions with privacy operations (mints and spends), the index is partial")
+
+	minedShielded = flag.Bool("minedshieldedstats", false, "count transactions moving coinbase or coinstake outputs to the shielded pool in the blockbook_mined_shielded_txs metric")
+
+	privacyEventsMQ = flag.String("privacyeventsmq", "", "ZeroMQ binding [address]:port, to which the privacy mints and spends of the connected blocks are published as JSON (default no publishing)")
 
 	debugMode = flag.Bool("debug", false, "debug mode, return more verbose errors, reload templates on each request")
 
@@ -77,6 +88,15 @@ var (
 	computeFeeStatsFlag = flag.Bool("computefeestats", false, "compute fee stats for blocks in blockheight-blockuntil range and exit")
 	dbStatsPeriodHours  = flag.Int("dbstatsperiod", 24, "period of db stats collection in hours, 0 disables stats collection")
 
+	// periodic verification of the privacy spend marks in the last verifySpendsBlocks blocks
+	verifySpendsBlocks        = flag.Int("verifyspendsblocks", 0, "number of last blocks in which the privacy spend marks are periodically verified, 0 disables the verification")
+	verifySpendsPeriodMinutes = flag.Int("verifyspendsperiod", 60, "period of privacy spend marks verification in minutes")
+	verifySpendsDelayMs       = flag.Int("verifyspendsdelay", 100, "delay between the blocks processed by privacy spend marks verification in milliseconds")
+
+	// optional periodic computation of the common-input-ownership clusters of the addresses
+	addressClustersPeriodMinutes = flag.Int("addressclustersperiod", 0, "period of the computation of the address clusters in minutes, 0 disables the computation")
+	addressClustersDelayMs       = flag.Int("addressclustersdelay", 10, "delay between the blocks processed by the computation of the address clusters in milliseconds")
+
 	// resync index at least each resyncIndexPeriodMs (could be more often if invoked by message from ZeroMQ)
 	resyncIndexPeriodMs = flag.Int("resyncindexperiod", 935093, "resync index period in milliseconds")
 
@@ -172,14 +192,34 @@ func mainWithExitCode() int {
 		return exitCodeFatal
 	}
 
+	if *dumpBlock != "" {
+		if err = printBlock(chain, *dumpBlock); err != nil {
+			glog.Error("dumpBlock: ", err)
+			return exitCodeFatal
+		}
+		return exitCodeOK
+	}
+
 	index, err = db.NewRocksDB(*dbPath, *dbCache, *dbMaxOpenFiles, chain.GetChainParser(), metrics)
 	if err != nil {
 		glog.Error("rocksDB: ", err)
 		return exitCodeFatal
 	}
 	defer index.Close()
+	if *minedShielded {
+		index.EnableMinedShieldedStats()
+	}
+	if *privacyEventsMQ != "" {
+		publisher, err := bchain.NewMQPublisher(*privacyEventsMQ)
+		if err != nil {
+			glog.Error("privacyEventsMQ: ", err)
+			return exitCodeFatal
+		}
+		defer publisher.Close()
+		index.SetPrivacyEventPublisher(publisher)
+	}
 
-	internalState, err = newInternalState(coin, coinShortcut, coinLabel, index)
+	internalState, err = newInternalState(coin, coinShortcut, coinLabel, *privacyOnly, index)
 	if err != nil {
 		glog.Error("internalState: ", err)
 		return exitCodeFatal
@@ -195,6 +235,24 @@ func mainWithExitCode() int {
 		internalState.UtxoChecked = true
 	}
 	index.SetInternalState(internalState)
+	if *fixSpends {
+		fixed, err := index.FixPrivacySpends(chain, chanOsSignal)
+		if err != nil {
+			glog.Error("fixPrivacySpends: ", err)
+			return exitCodeFatal
+		}
+		glog.Info("fixPrivacySpends: reclassified ", fixed, " inputs")
+		return exitCodeOK
+	}
+	if *exportMints != "" {
+		exported, err := exportMintsCSV(*exportMints, *blockFrom, *blockUntil)
+		if err != nil {
+			glog.Error("exportMints: ", err)
+			return exitCodeFatal
+		}
+		glog.Info("exportMints: exported ", exported, " mints")
+		return exitCodeOK
+	}
 	if *fixUtxo {
 		err = index.StoreInternalState(internalState)
 		if err != nil {
@@ -233,7 +291,7 @@ func mainWithExitCode() int {
 		return exitCodeOK
 	}
 
-	syncWorker, err = db.NewSyncWorker(index, chain, *syncWorkers, *syncChunk, *blockFrom, *dryRun, chanOsSignal, metrics, internalState)
+	syncWorker, err = db.NewSyncWorker(index, chain, *syncWorkers, *syncChunk, *blockFrom, *dryRun, *orphans, chanOsSignal, metrics, internalState)
 	if err != nil {
 		glog.Errorf("NewSyncWorker %v", err)
 		return exitCodeFatal
@@ -448,6 +506,62 @@ func performRollback() error {
 	return nil
 }
 
+// printBlock prints the block given by height or hash parsed by the chain parser
+// parsers implementing DumpBlock can add chain specific data to the output
+func printBlock(chain bchain.BlockChain, heightOrHash string) error {
+	var height uint32
+	hash := heightOrHash
+	if h, err := strconv.ParseUint(heightOrHash, 10, 32); err == nil {
+		height = uint32(h)
+		if hash, err = chain.GetBlockHash(height); err != nil {
+			return err
+		}
+	}
+	block, err := chain.GetBlock(hash, height)
+	if err != nil {
+		return err
+	}
+	var s string
+	if d, ok := chain.GetChainParser().(interface {
+		DumpBlock(b *bchain.Block) (string, error)
+	}); ok {
+		s, err = d.DumpBlock(block)
+	} else {
+		var b []byte
+		b, err = json.MarshalIndent(block, "", "  ")
+		s = string(b)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(s)
+	return nil
+}
+
+// exportMintsCSV writes the privacy mints in the blockFrom-blockUntil range to the file given by path or to stdout
+// negative blockFrom means the genesis block, negative blockUntil the best block
+func exportMintsCSV(path string, blockFrom, blockUntil int) (int, error) {
+	from, until := uint32(0), ^uint32(0)
+	if blockFrom > 0 {
+		from = uint32(blockFrom)
+	}
+	if blockUntil >= 0 {
+		until = uint32(blockUntil)
+	}
+	if path == "-" {
+		return index.ExportMints(chain, os.Stdout, from, until, chanOsSignal)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	exported, err := index.ExportMints(chain, f, from, until, chanOsSignal)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return exported, err
+}
+
 func blockbookAppInfoMetric(db *db.RocksDB, chain bchain.BlockChain, txCache *db.TxCache, is *common.InternalState, metrics *common.Metrics) error {
 	api, err := api.NewWorker(db, chain, mempool, txCache, is)
 	if err != nil {
@@ -468,7 +582,7 @@ func blockbookAppInfoMetric(db *db.RocksDB, chain bchain.BlockChain, txCache *db
 	return nil
 }
 
-func newInternalState(coin, coinShortcut, coinLabel string, d *db.RocksDB) (*common.InternalState, error) {
+func newInternalState(coin, coinShortcut, coinLabel string, privacyOnly bool, d *db.RocksDB) (*common.InternalState, error) {
 	is, err := d.LoadInternalState(coin)
 	if err != nil {
 		return nil, err
@@ -478,6 +592,17 @@ func newInternalState(coin, coinShortcut, coinLabel string, d *db.RocksDB) (*com
 		coinLabel = coin
 	}
 	is.CoinLabel = coinLabel
+	if is.PrivacyOnlyIndex != privacyOnly {
+		// switching the index mode would result in an index which is neither full nor privacy only
+		_, bestHash, err := d.GetBestBlock()
+		if err != nil {
+			return nil, err
+		}
+		if bestHash != "" {
+			return nil, errors.Errorf("Index mode does not match. DB privacy only index %v, requested %v", is.PrivacyOnlyIndex, privacyOnly)
+		}
+		is.PrivacyOnlyIndex = privacyOnly
+	}
 	name, err := os.Hostname()
 	if err != nil {
 		glog.Error("get hostname ", err)
@@ -577,8 +702,12 @@ func storeInternalStateLoop() {
 		close(chanStoreInternalStateDone)
 	}()
 	signal.Notify(stopCompute, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
-	var computeRunning bool
+	var computeRunning, verifyRunning, clustersRunning bool
 	lastCompute := time.Now()
+	lastVerify := time.Now()
+	verifyPeriod := time.Duration(*verifySpendsPeriodMinutes) * time.Minute
+	lastClusters := time.Now()
+	clustersPeriod := time.Duration(*addressClustersPeriodMinutes) * time.Minute
 	lastAppInfo := time.Now()
 	logAppInfoPeriod := 15 * time.Minute
 	// randomize the duration between ComputeInternalStateColumnStats to avoid peaks after reboot of machine with multiple blockbooks
@@ -600,6 +729,28 @@ func storeInternalStateLoop() {
 				computeRunning = false
 			}()
 		}
+		if *verifySpendsBlocks > 0 && internalState.SyncMode && !verifyRunning && lastVerify.Add(verifyPeriod).Before(time.Now()) {
+			verifyRunning = true
+			go func() {
+				_, err := index.VerifyPrivacySpends(chain, *verifySpendsBlocks, time.Duration(*verifySpendsDelayMs)*time.Millisecond, stopCompute)
+				if err != nil {
+					glog.Error("verifyPrivacySpends error: ", err)
+				}
+				lastVerify = time.Now()
+				verifyRunning = false
+			}()
+		}
+		if *addressClustersPeriodMinutes > 0 && internalState.SyncMode && !clustersRunning && lastClusters.Add(clustersPeriod).Before(time.Now()) {
+			clustersRunning = true
+			go func() {
+				_, err := index.ComputeAddressClusters(chain, time.Duration(*addressClustersDelayMs)*time.Millisecond, stopCompute)
+				if err != nil {
+					glog.Error("computeAddressClusters error: ", err)
+				}
+				lastClusters = time.Now()
+				clustersRunning = false
+			}()
+		}
 		if err := index.StoreInternalState(internalState); err != nil {
 			glog.Error("storeInternalStateLoop ", errors.ErrorStack(err))
 		}
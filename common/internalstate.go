@@ -55,6 +55,12 @@ type InternalState struct {
 	DbColumns []InternalStateColumn `json:"dbColumns"`
 
 	UtxoChecked bool `json:"utxoChecked"`
+
+	// true if only the transactions with privacy operations are indexed, the index is partial
+	PrivacyOnlyIndex bool `json:"privacyOnlyIndex"`
+
+	// the last block height processed by the computation of the address clusters
+	AddressClustersHeight uint32 `json:"addressClustersHeight"`
 }
 
 // StartedSync signals start of synchronization
@@ -118,6 +124,20 @@ func (is *InternalState) GetMempoolSyncState() (bool, time.Time, int) {
 	return is.IsMempoolSynchronized, is.LastMempoolSync, is.MempoolSize
 }
 
+// GetAddressClustersHeight returns the last block height processed by the computation of the address clusters
+func (is *InternalState) GetAddressClustersHeight() uint32 {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	return is.AddressClustersHeight
+}
+
+// SetAddressClustersHeight sets the last block height processed by the computation of the address clusters
+func (is *InternalState) SetAddressClustersHeight(height uint32) {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	is.AddressClustersHeight = height
+}
+
 // AddDBColumnStats adds differences in column statistics to column stats
 func (is *InternalState) AddDBColumnStats(c int, rowsDiff int64, keyBytesDiff int64, valueBytesDiff int64) {
 	is.mux.Lock()
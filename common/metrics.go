@@ -27,6 +27,7 @@ type Metrics struct {
 	DbColumnRows          *prometheus.GaugeVec
 	DbColumnSize          *prometheus.GaugeVec
 	BlockbookAppInfo      *prometheus.GaugeVec
+	MinedShieldedTxs      prometheus.Counter
 }
 
 // Labels represents a collection of label name -> value mappings.
@@ -187,6 +188,13 @@ func GetMetrics(coin string) (*Metrics, error) {
 		},
 		[]string{"blockbook_version", "blockbook_commit", "blockbook_buildtime", "backend_version", "backend_subversion", "backend_protocol_version"},
 	)
+	metrics.MinedShieldedTxs = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name:        "blockbook_mined_shielded_txs",
+			Help:        "Number of indexed transactions moving coinbase or coinstake outputs to the shielded pool",
+			ConstLabels: Labels{"coin": coin},
+		},
+	)
 
 	v := reflect.ValueOf(metrics)
 	for i := 0; i < v.NumField(); i++ {
@@ -0,0 +1,231 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"bytes"
+	"os"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// addressClusters is the union-find structure of the common-input-ownership clusters of the addresses,
+// parents contains the loaded and updated links to the parent addresses, the addresses without a link are roots
+// the root of the cluster is its smallest address descriptor, which makes the clusters independent of the order of the transactions
+type addressClusters struct {
+	parents map[string]string
+	updated map[string]struct{}
+	load    func(addrDesc string) (string, error)
+}
+
+func newAddressClusters(load func(addrDesc string) (string, error)) *addressClusters {
+	return &addressClusters{
+		parents: make(map[string]string),
+		updated: make(map[string]struct{}),
+		load:    load,
+	}
+}
+
+// find returns the root of the cluster of the address, the path to the root is compressed
+func (c *addressClusters) find(addrDesc string) (string, error) {
+	var path []string
+	root := addrDesc
+	for {
+		parent, found := c.parents[root]
+		if !found {
+			var err error
+			if parent, err = c.load(root); err != nil {
+				return "", err
+			}
+			if parent == "" {
+				parent = root
+			}
+			c.parents[root] = parent
+		}
+		if parent == root {
+			break
+		}
+		path = append(path, root)
+		root = parent
+	}
+	// the last address of the path already links to the root
+	for i := 0; i < len(path)-1; i++ {
+		c.parents[path[i]] = root
+		c.updated[path[i]] = struct{}{}
+	}
+	return root, nil
+}
+
+// union merges the clusters of the addresses
+func (c *addressClusters) union(a, b string) error {
+	ra, err := c.find(a)
+	if err != nil {
+		return err
+	}
+	rb, err := c.find(b)
+	if err != nil {
+		return err
+	}
+	if ra == rb {
+		return nil
+	}
+	if rb < ra {
+		ra, rb = rb, ra
+	}
+	c.parents[rb] = ra
+	c.updated[rb] = struct{}{}
+	return nil
+}
+
+// clusterableInputs returns the distinct input addresses of the transaction for the common-input-ownership heuristic,
+// nil if the transaction must not be clustered, i.e. the coinbase transactions, the transactions with privacy spend
+// inputs (the spender is unrelated to the other inputs) and the transactions with inputs of unknown address
+func (d *RocksDB) clusterableInputs(ta *TxAddresses) []string {
+	inputs := make([]string, 0, len(ta.Inputs))
+	seen := make(map[string]struct{}, len(ta.Inputs))
+	for i := range ta.Inputs {
+		ad := ta.Inputs[i].AddrDesc
+		if len(ad) == 0 || d.chainParser.GetPseudoAddressType(ad) != "" {
+			return nil
+		}
+		if _, found := seen[string(ad)]; !found {
+			seen[string(ad)] = struct{}{}
+			inputs = append(inputs, string(ad))
+		}
+	}
+	return inputs
+}
+
+// addTx merges the clusters of the input addresses of the transaction, returns true if the transaction was clustered
+func (c *addressClusters) addTx(d *RocksDB, ta *TxAddresses) (bool, error) {
+	inputs := d.clusterableInputs(ta)
+	if len(inputs) < 2 {
+		return false, nil
+	}
+	for i := 1; i < len(inputs); i++ {
+		if err := c.union(inputs[0], inputs[i]); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (d *RocksDB) loadAddressClusterParent(addrDesc string) (string, error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfAddressClusters], []byte(addrDesc))
+	if err != nil {
+		return "", err
+	}
+	defer val.Free()
+	return string(val.Data()), nil
+}
+
+// storeAddressClusters writes the updated links of the clusters to db
+func (d *RocksDB) storeAddressClusters(c *addressClusters) error {
+	if len(c.updated) == 0 {
+		return nil
+	}
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	for ad := range c.updated {
+		wb.PutCF(d.cfh[cfAddressClusters], []byte(ad), []byte(c.parents[ad]))
+	}
+	if err := d.db.Write(d.wo, wb); err != nil {
+		return err
+	}
+	c.updated = make(map[string]struct{})
+	return nil
+}
+
+// maxAddressClustersCache is the number of addresses after which the cached links are released
+const maxAddressClustersCache = 1000000
+
+// ComputeAddressClusters extends the common-input-ownership clusters of the addresses by the blocks indexed since
+// the last computation, the addresses spent together in the inputs of a transaction are considered to have the same owner
+// only the blocks with at least finality confirmations are processed, the clusters are not reverted by a rollback
+// the blocks are fetched from the backend with blockDelay between the blocks to limit the load of the backend
+// returns the number of clustered transactions
+func (d *RocksDB) ComputeAddressClusters(chain bchain.BlockChain, blockDelay time.Duration, stop chan os.Signal) (int, error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType || d.is == nil {
+		return 0, nil
+	}
+	bestHeight, _, err := d.GetBestBlock()
+	if err != nil {
+		return 0, err
+	}
+	finality := uint32(d.chainParser.FinalityConfirmations())
+	if bestHeight < finality {
+		return 0, nil
+	}
+	toHeight := bestHeight - finality
+	fromHeight := d.is.GetAddressClustersHeight() + 1
+	start := time.Now()
+	c := newAddressClusters(d.loadAddressClusterParent)
+	var clustered int
+	for height := fromHeight; height <= toHeight; height++ {
+		select {
+		case <-stop:
+			return clustered, errors.New("Interrupted")
+		case <-time.After(blockDelay):
+		}
+		hash, err := d.GetBlockHash(height)
+		if err != nil {
+			return clustered, err
+		}
+		if hash == "" {
+			continue
+		}
+		block, err := chain.GetBlock(hash, height)
+		if err != nil {
+			return clustered, errors.Annotatef(err, "GetBlock %v %v", height, hash)
+		}
+		for i := range block.Txs {
+			ta, err := d.GetTxAddresses(block.Txs[i].Txid)
+			if err != nil {
+				return clustered, err
+			}
+			// the transaction may not be indexed, for example in the privacy only index mode
+			if ta == nil {
+				continue
+			}
+			added, err := c.addTx(d, ta)
+			if err != nil {
+				return clustered, errors.Annotatef(err, "block %v %v", height, hash)
+			}
+			if added {
+				clustered++
+			}
+		}
+		if err = d.storeAddressClusters(c); err != nil {
+			return clustered, err
+		}
+		d.is.SetAddressClustersHeight(height)
+		if len(c.parents) > maxAddressClustersCache {
+			c = newAddressClusters(d.loadAddressClusterParent)
+		}
+		if height%10000 == 0 {
+			glog.Info("ComputeAddressClusters: height ", height, ", clustered txs ", clustered)
+		}
+	}
+	if fromHeight <= toHeight {
+		glog.Info("ComputeAddressClusters: blocks ", fromHeight, "-", toHeight, ", clustered txs ", clustered, ", finished in ", time.Since(start))
+	}
+	return clustered, nil
+}
+
+// GetAddressCluster returns the root address of the common-input-ownership cluster of the address, which identifies
+// the cluster, the address which was not clustered is the root of its own cluster
+func (d *RocksDB) GetAddressCluster(addrDesc bchain.AddressDescriptor) (bchain.AddressDescriptor, error) {
+	root := addrDesc
+	for {
+		parent, err := d.loadAddressClusterParent(string(root))
+		if err != nil {
+			return nil, err
+		}
+		if parent == "" || bytes.Equal(root, []byte(parent)) {
+			return root, nil
+		}
+		root = bchain.AddressDescriptor(parent)
+	}
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"bytes"
+)
+
+// GetBlockTxsForAddrDesc returns the indexes of the transactions of the block in which addrDesc appears in the outputs
+// or in the spent outputs of the inputs, the privacy spend inputs match their pseudo address descriptors
+// the spent outputs are looked up in the block itself and then in the txAddresses column
+func (d *RocksDB) GetBlockTxsForAddrDesc(block *bchain.Block, addrDesc bchain.AddressDescriptor) ([]int, error) {
+	return blockTxsForAddrDesc(d.chainParser, block, addrDesc, d.getTxAddresses)
+}
+
+// blockTxsForAddrDesc implements GetBlockTxsForAddrDesc, getTxAddresses returns the stored addresses of the transaction
+func blockTxsForAddrDesc(parser bchain.BlockChainParser, block *bchain.Block, addrDesc bchain.AddressDescriptor,
+	getTxAddresses func(btxID []byte) (*TxAddresses, error)) ([]int, error) {
+	blockTxs := make(map[string]*bchain.Tx, len(block.Txs))
+	for i := range block.Txs {
+		blockTxs[block.Txs[i].Txid] = &block.Txs[i]
+	}
+	var txs []int
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		found := false
+		for j := range tx.Vout {
+			ad, err := parser.GetAddrDescFromVout(&tx.Vout[j])
+			if err == nil && bytes.Equal(ad, addrDesc) {
+				found = true
+				break
+			}
+		}
+		for j := 0; j < len(tx.Vin) && !found; j++ {
+			ad, err := inputAddrDesc(parser, tx, j, blockTxs, getTxAddresses)
+			if err != nil {
+				return nil, err
+			}
+			found = bytes.Equal(ad, addrDesc) && len(ad) > 0
+		}
+		if found {
+			txs = append(txs, i)
+		}
+	}
+	return txs, nil
+}
+
+// inputAddrDesc returns the address descriptor of the output spent by the input of the transaction,
+// the pseudo address descriptor of the privacy spend or nil if it cannot be determined
+func inputAddrDesc(parser bchain.BlockChainParser, tx *bchain.Tx, input int, blockTxs map[string]*bchain.Tx,
+	getTxAddresses func(btxID []byte) (*TxAddresses, error)) (bchain.AddressDescriptor, error) {
+	vin := &tx.Vin[input]
+	if ad := parser.GetPrivacySpendAddrDesc(vin); ad != nil {
+		return ad, nil
+	}
+	if vin.Txid == "" {
+		return nil, nil
+	}
+	if itx, found := blockTxs[vin.Txid]; found {
+		if int(vin.Vout) >= len(itx.Vout) {
+			return nil, nil
+		}
+		ad, _ := parser.GetAddrDescFromVout(&itx.Vout[vin.Vout])
+		return ad, nil
+	}
+	btxID, err := parser.PackTxid(vin.Txid)
+	if err != nil {
+		if err == bchain.ErrTxidMissing {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ita, err := getTxAddresses(btxID)
+	if err != nil {
+		return nil, err
+	}
+	if ita == nil {
+		return parser.GetAddrDescForUnknownInput(tx, input), nil
+	}
+	if int(vin.Vout) >= len(ita.Outputs) {
+		return nil, nil
+	}
+	return ita.Outputs[vin.Vout].AddrDesc, nil
+}
@@ -171,7 +171,18 @@ func (b *BulkConnect) storeBulkAddresses(wb *gorocksdb.WriteBatch) error {
 
 func (b *BulkConnect) connectBlockBitcoinType(block *bchain.Block, storeBlockTxs bool) error {
 	addresses := make(addressesMap)
-	if err := b.d.processAddressesBitcoinType(block, addresses, b.txAddressesMap, b.balances); err != nil {
+	// the running total of the shielded supply depends on the previous block, it is written immediately
+	if err := b.d.storeShieldedSupply(block); err != nil {
+		return err
+	}
+	if err := b.d.storeMints(block); err != nil {
+		return err
+	}
+	if err := b.d.storeTxFlags(block); err != nil {
+		return err
+	}
+	ib := b.d.indexedBlock(block)
+	if err := b.d.processAddressesBitcoinType(ib, addresses, b.txAddressesMap, b.balances); err != nil {
 		return err
 	}
 	var storeAddressesChan, storeBalancesChan chan error
@@ -210,7 +221,7 @@ func (b *BulkConnect) connectBlockBitcoinType(block *bchain.Block, storeBlockTxs
 			}
 		}
 		if storeBlockTxs {
-			if err := b.d.storeAndCleanupBlockTxs(wb, block); err != nil {
+			if err := b.d.storeAndCleanupBlockTxs(wb, ib); err != nil {
 				return err
 			}
 		}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"blockbook/bchain"
+
+	"github.com/golang/glog"
+)
+
+// EnableMinedShieldedStats enables detection of transactions moving freshly mined or staked coins to the shielded pool
+// the detected transactions are counted in the MinedShieldedTxs metric
+func (d *RocksDB) EnableMinedShieldedStats() {
+	d.minedShieldedStats = true
+}
+
+// isMinedTxAddresses returns true if the transaction is a coinbase or a coinstake transaction
+// coinbase has one input without address and value, coinstake has the first output empty
+func isMinedTxAddresses(ta *TxAddresses) bool {
+	if len(ta.Inputs) == 1 && len(ta.Inputs[0].AddrDesc) == 0 && ta.Inputs[0].ValueSat.Sign() == 0 {
+		return true
+	}
+	return len(ta.Inputs) > 0 && len(ta.Outputs) >= 2 && len(ta.Outputs[0].AddrDesc) == 0 && ta.Outputs[0].ValueSat.Sign() == 0
+}
+
+// isMinedShieldedTx returns true if the transaction ta spending outputs of the transactions inputTxs creates a privacy mint
+// and at least one of the spent transactions is a coinbase or coinstake
+func isMinedShieldedTx(parser bchain.BlockChainParser, ta *TxAddresses, inputTxs []*TxAddresses) bool {
+	mint := false
+	for i := range ta.Outputs {
+		if parser.IsPrivacyMintAddrDesc(ta.Outputs[i].AddrDesc) {
+			mint = true
+			break
+		}
+	}
+	if !mint {
+		return false
+	}
+	for _, ita := range inputTxs {
+		if ita != nil && isMinedTxAddresses(ita) {
+			return true
+		}
+	}
+	return false
+}
+
+// countMinedShieldedTx increments the MinedShieldedTxs metric if the transaction moves freshly mined coins to the shielded pool
+func (d *RocksDB) countMinedShieldedTx(tx *bchain.Tx, ta *TxAddresses, inputTxs []*TxAddresses) {
+	if d.metrics != nil && isMinedShieldedTx(d.chainParser, ta, inputTxs) {
+		glog.V(1).Info("rocksdb: tx ", tx.Txid, " moves mined coins to the shielded pool")
+		d.metrics.MinedShieldedTxs.Inc()
+	}
+}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"bytes"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// GetMintsCallback is called by GetMints for each found privacy mint output
+type GetMintsCallback func(txid string, vout int32, height uint32) error
+
+// denominationMints contains the privacy mint outputs of one denomination in a block
+type denominationMints struct {
+	denomination big.Int
+	outpoints    []bchain.Outpoint
+}
+
+// packMintKey packs the denomination and the height as binary complement to achieve ordering from newest to oldest block
+func packMintKey(denomination *big.Int, height uint32) []byte {
+	buf := make([]byte, maxPackedBigintBytes+packedHeightBytes)
+	l := packBigint(denomination, buf)
+	binary.BigEndian.PutUint32(buf[l:], ^height)
+	return buf[:l+packedHeightBytes]
+}
+
+func unpackMintKey(key []byte) (big.Int, uint32, error) {
+	d, l := unpackBigint(key)
+	if len(key) != l+packedHeightBytes {
+		return d, 0, errors.New("Invalid mint key")
+	}
+	return d, ^binary.BigEndian.Uint32(key[l:]), nil
+}
+
+func (d *RocksDB) packMints(outpoints []bchain.Outpoint) ([]byte, error) {
+	buf := make([]byte, 0, len(outpoints)*(d.chainParser.PackedTxidLen()+1))
+	varBuf := make([]byte, maxPackedBigintBytes)
+	for _, o := range outpoints {
+		btxID, err := d.chainParser.PackTxid(o.Txid)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, btxID...)
+		l := packVaruint(uint(o.Vout), varBuf)
+		buf = append(buf, varBuf[:l]...)
+	}
+	return buf, nil
+}
+
+// blockMintsByDenomination groups the privacy mint outputs of the block by the denomination (value of the output)
+// in the order of the first occurrence of the denomination in the block
+func blockMintsByDenomination(parser bchain.BlockChainParser, block *bchain.Block) []denominationMints {
+	var mints []denominationMints
+	index := make(map[string]int)
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		for j := range tx.Vout {
+			vout := &tx.Vout[j]
+			ad, err := parser.GetAddrDescFromVout(vout)
+			if err != nil || !parser.IsPrivacyMintAddrDesc(ad) {
+				continue
+			}
+			s := vout.ValueSat.String()
+			k, found := index[s]
+			if !found {
+				k = len(mints)
+				index[s] = k
+				mints = append(mints, denominationMints{denomination: vout.ValueSat})
+			}
+			mints[k].outpoints = append(mints[k].outpoints, bchain.Outpoint{Txid: tx.Txid, Vout: int32(vout.N)})
+		}
+	}
+	return mints
+}
+
+// writeMints stores the privacy mint outputs of the block under the keys by the denomination and the block height
+func (d *RocksDB) writeMints(wb *gorocksdb.WriteBatch, block *bchain.Block) error {
+	for _, m := range blockMintsByDenomination(d.chainParser, block) {
+		val, err := d.packMints(m.outpoints)
+		if err != nil {
+			return err
+		}
+		wb.PutCF(d.cfh[cfMints], packMintKey(&m.denomination, block.Height), val)
+	}
+	return nil
+}
+
+// storeMints writes the privacy mint outputs of the block directly to db
+func (d *RocksDB) storeMints(block *bchain.Block) error {
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	if err := d.writeMints(wb, block); err != nil {
+		return err
+	}
+	return d.db.Write(d.wo, wb)
+}
+
+// deleteMints removes the privacy mints of the disconnected block, the denominations are taken
+// from the mint outputs of the stored transactions of the block
+func (d *RocksDB) deleteMints(wb *gorocksdb.WriteBatch, height uint32, txAddresses []*TxAddresses) {
+	deleted := make(map[string]struct{})
+	for _, ta := range txAddresses {
+		if ta == nil {
+			continue
+		}
+		for i := range ta.Outputs {
+			o := &ta.Outputs[i]
+			if !d.chainParser.IsPrivacyMintAddrDesc(o.AddrDesc) {
+				continue
+			}
+			key := packMintKey(&o.ValueSat, height)
+			if _, found := deleted[string(key)]; !found {
+				deleted[string(key)] = struct{}{}
+				wb.DeleteCF(d.cfh[cfMints], key)
+			}
+		}
+	}
+}
+
+// GetMints finds the privacy mint outputs of the denomination in the blocks in the range lower-higher
+// the mints are passed to callback function in the order from newest block to the oldest, the first skip mints
+// are not passed to the callback, which can stop the iteration by returning StopIteration
+func (d *RocksDB) GetMints(denomination *big.Int, lower uint32, higher uint32, skip int, fn GetMintsCallback) error {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return errors.New("GetMints: applicable only for bitcoin type coins")
+	}
+	txidUnpackedLen := d.chainParser.PackedTxidLen()
+	startKey := packMintKey(denomination, higher)
+	stopKey := packMintKey(denomination, lower)
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfMints])
+	defer it.Close()
+	for it.Seek(startKey); it.Valid(); it.Next() {
+		key := it.Key().Data()
+		if bytes.Compare(key, stopKey) > 0 {
+			break
+		}
+		_, height, err := unpackMintKey(key)
+		if err != nil {
+			return err
+		}
+		val := it.Value().Data()
+		for len(val) > txidUnpackedLen {
+			btxID := val[:txidUnpackedLen]
+			vout, l := unpackVaruint(val[txidUnpackedLen:])
+			val = val[txidUnpackedLen+l:]
+			if skip > 0 {
+				skip--
+				continue
+			}
+			txid, err := d.chainParser.UnpackTxid(btxID)
+			if err != nil {
+				return err
+			}
+			if err := fn(txid, int32(vout), height); err != nil {
+				if _, ok := err.(*StopIteration); ok {
+					return nil
+				}
+				return err
+			}
+		}
+		if len(val) != 0 {
+			return errors.Errorf("Invalid mints data of key %x", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+var mintsCSVHeader = []string{"height", "txid", "type", "value"}
+
+// ExportMints writes the privacy mint outputs of the indexed blocks in the range fromHeight-toHeight as CSV to w
+// the rows are ordered by height, position of the transaction in the block and output index, the value is in satoshis
+// the blocks are fetched from the backend one by one and the output is flushed after each block
+// returns the number of exported mints
+func (d *RocksDB) ExportMints(chain bchain.BlockChain, w io.Writer, fromHeight, toHeight uint32, stop chan os.Signal) (int, error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return 0, errors.New("ExportMints: applicable only for bitcoin type coins")
+	}
+	bestHeight, _, err := d.GetBestBlock()
+	if err != nil {
+		return 0, err
+	}
+	if toHeight > bestHeight {
+		toHeight = bestHeight
+	}
+	cw := csv.NewWriter(w)
+	if err = cw.Write(mintsCSVHeader); err != nil {
+		return 0, err
+	}
+	var exported int
+	for height := fromHeight; height <= toHeight; height++ {
+		select {
+		case <-stop:
+			return exported, ErrOperationInterrupted
+		default:
+		}
+		hash, err := d.GetBlockHash(height)
+		if err != nil {
+			return exported, err
+		}
+		if hash == "" {
+			continue
+		}
+		block, err := chain.GetBlock(hash, height)
+		if err != nil {
+			return exported, errors.Annotatef(err, "GetBlock %v %v", height, hash)
+		}
+		n, err := writeBlockMints(d.chainParser, cw, block)
+		if err != nil {
+			return exported, errors.Annotatef(err, "block %v %v", height, hash)
+		}
+		exported += n
+		if height%10000 == 0 {
+			glog.Info("ExportMints: height ", height, ", exported mints ", exported)
+		}
+	}
+	return exported, nil
+}
+
+// writeBlockMints writes a CSV row for each privacy mint output of the block and flushes the writer
+func writeBlockMints(parser bchain.BlockChainParser, cw *csv.Writer, block *bchain.Block) (int, error) {
+	var n int
+	height := strconv.FormatUint(uint64(block.Height), 10)
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		for j := range tx.Vout {
+			vout := &tx.Vout[j]
+			ad, err := parser.GetAddrDescFromVout(vout)
+			if err != nil || !parser.IsPrivacyMintAddrDesc(ad) {
+				continue
+			}
+			var t string
+			if a, _, err := parser.GetAddressesFromAddrDesc(ad); err == nil && len(a) == 1 {
+				t = a[0]
+			}
+			if err = cw.Write([]string{height, tx.Txid, t, vout.ValueSat.String()}); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	cw.Flush()
+	return n, cw.Error()
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"blockbook/bchain"
+
+	"github.com/golang/glog"
+)
+
+// OrphanBlock contains information about a block which was received but was disconnected from the main chain
+type OrphanBlock struct {
+	Hash           string `json:"hash"`
+	Height         uint32 `json:"height"`
+	Time           int64  `json:"time,omitempty"`
+	Txs            uint32 `json:"txCount"`
+	Size           uint32 `json:"size"`
+	Staker         string `json:"staker,omitempty"`
+	DisconnectTime int64  `json:"disconnectTime"`
+}
+
+func (d *RocksDB) packOrphanBlockKey(height uint32, hash string) ([]byte, error) {
+	b, err := d.chainParser.PackBlockHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	return append(packUint(height), b...), nil
+}
+
+func packOrphanBlock(ob *OrphanBlock) []byte {
+	buf := make([]byte, 0, 32+len(ob.Staker))
+	varBuf := make([]byte, maxPackedBigintBytes)
+	buf = append(buf, packUint(uint32(ob.Time))...)
+	buf = append(buf, packUint(uint32(ob.DisconnectTime))...)
+	l := packVaruint(uint(ob.Txs), varBuf)
+	buf = append(buf, varBuf[:l]...)
+	l = packVaruint(uint(ob.Size), varBuf)
+	buf = append(buf, varBuf[:l]...)
+	l = packVaruint(uint(len(ob.Staker)), varBuf)
+	buf = append(buf, varBuf[:l]...)
+	buf = append(buf, ob.Staker...)
+	return buf
+}
+
+func (d *RocksDB) unpackOrphanBlock(key, buf []byte) (*OrphanBlock, error) {
+	if len(key) < packedHeightBytes || len(buf) < 8+3 {
+		return nil, nil
+	}
+	hash, err := d.chainParser.UnpackBlockHash(key[packedHeightBytes:])
+	if err != nil {
+		return nil, err
+	}
+	ob := OrphanBlock{
+		Hash:           hash,
+		Height:         unpackUint(key),
+		Time:           int64(unpackUint(buf)),
+		DisconnectTime: int64(unpackUint(buf[4:])),
+	}
+	l := 8
+	txs, ll := unpackVaruint(buf[l:])
+	l += ll
+	size, ll := unpackVaruint(buf[l:])
+	l += ll
+	sl, ll := unpackVaruint(buf[l:])
+	l += ll
+	ob.Txs = uint32(txs)
+	ob.Size = uint32(size)
+	if len(buf) >= l+int(sl) {
+		ob.Staker = string(buf[l : l+int(sl)])
+	}
+	return &ob, nil
+}
+
+// StoreOrphanBlock stores information about a block disconnected from the main chain
+// the orphans column is not used for indexing, it is only informative
+func (d *RocksDB) StoreOrphanBlock(ob *OrphanBlock) error {
+	key, err := d.packOrphanBlockKey(ob.Height, ob.Hash)
+	if err != nil {
+		return err
+	}
+	return d.db.PutCF(d.wo, d.cfh[cfOrphans], key, packOrphanBlock(ob))
+}
+
+// GetOrphanBlocks returns stored orphan blocks ordered from the highest to the lowest height
+func (d *RocksDB) GetOrphanBlocks() ([]OrphanBlock, error) {
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfOrphans])
+	defer it.Close()
+	obs := make([]OrphanBlock, 0)
+	for it.SeekToLast(); it.Valid(); it.Prev() {
+		ob, err := d.unpackOrphanBlock(it.Key().Data(), it.Value().Data())
+		if err != nil {
+			return nil, err
+		}
+		if ob == nil {
+			glog.Warning("rocksdb: orphans contain incorrect data ", it.Key().Data())
+			continue
+		}
+		obs = append(obs, *ob)
+	}
+	return obs, nil
+}
+
+// GetCoinstakeStaker returns the address of the staker of a proof-of-stake block, taken from the coinstake transaction
+// coinstake is the second transaction in the block, its first output is empty and the second output pays the staker
+// empty string is returned if the block does not contain coinstake transaction
+func GetCoinstakeStaker(parser bchain.BlockChainParser, block *bchain.Block) string {
+	if len(block.Txs) < 2 {
+		return ""
+	}
+	cs := &block.Txs[1]
+	if !bchain.IsCoinstakeTx(cs) {
+		return ""
+	}
+	ad, err := parser.GetAddrDescFromVout(&cs.Vout[1])
+	if err != nil {
+		return ""
+	}
+	a, _, err := parser.GetAddressesFromAddrDesc(ad)
+	if err != nil || len(a) == 0 {
+		return ""
+	}
+	return a[0]
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"blockbook/bchain"
+
+	"github.com/golang/glog"
+)
+
+// privacyEventsQueueSize is the number of privacy events waiting for publishing, further events are dropped
+const privacyEventsQueueSize = 10000
+
+// SetPrivacyEventPublisher starts publishing of the privacy mints and spends of the connected blocks by the publisher
+// the events are published asynchronously, a slow or failing publisher does not block the sync
+func (d *RocksDB) SetPrivacyEventPublisher(publisher bchain.PrivacyEventPublisher) {
+	d.privacyEvents = make(chan bchain.PrivacyEvent, privacyEventsQueueSize)
+	go func(events chan bchain.PrivacyEvent) {
+		for e := range events {
+			if err := publisher.Publish(&e); err != nil {
+				glog.Error("PrivacyEventPublisher: tx ", e.Txid, ": ", err)
+			}
+		}
+	}(d.privacyEvents)
+}
+
+// queuePrivacyEvents queues the privacy events of the block for publishing, if the queue is full the events are dropped
+func (d *RocksDB) queuePrivacyEvents(block *bchain.Block) {
+	if d.privacyEvents == nil {
+		return
+	}
+	events := d.chainParser.GetPrivacyEvents(block)
+	for i := range events {
+		select {
+		case d.privacyEvents <- events[i]:
+		default:
+			glog.Warning("PrivacyEventPublisher: queue full, dropped ", len(events)-i, " events of block ", block.Height)
+			return
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"os"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// FixPrivacySpends marks the privacy spend inputs in the txAddresses column, which were indexed before the parser
+// supported the privacy spend pseudo address descriptors and are therefore indistinguishable from coinbase inputs
+// the blocks are fetched from the backend, the fix can be run repeatedly, already marked inputs are skipped
+// returns the number of reclassified inputs
+func (d *RocksDB) FixPrivacySpends(chain bchain.BlockChain, stop chan os.Signal) (int, error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		glog.Info("FixPrivacySpends: applicable only for bitcoin type coins")
+		return 0, nil
+	}
+	bestHeight, _, err := d.GetBestBlock()
+	if err != nil {
+		return 0, err
+	}
+	glog.Info("FixPrivacySpends: starting, best height ", bestHeight)
+	var fixed int
+	for height := uint32(0); height <= bestHeight; height++ {
+		select {
+		case <-stop:
+			return fixed, errors.New("Interrupted")
+		default:
+		}
+		hash, err := d.GetBlockHash(height)
+		if err != nil {
+			return fixed, err
+		}
+		if hash == "" {
+			continue
+		}
+		block, err := chain.GetBlock(hash, height)
+		if err != nil {
+			return fixed, errors.Annotatef(err, "GetBlock %v %v", height, hash)
+		}
+		f, err := d.fixBlockPrivacySpends(block, false)
+		if err != nil {
+			return fixed, errors.Annotatef(err, "block %v %v", height, hash)
+		}
+		fixed += f
+		if height%10000 == 0 {
+			glog.Info("FixPrivacySpends: height ", height, ", reclassified inputs ", fixed)
+		}
+	}
+	glog.Info("FixPrivacySpends: finished, reclassified inputs ", fixed)
+	return fixed, nil
+}
+
+// VerifyPrivacySpends checks the privacy spend marks of the inputs in the last blocks of the index against the parser,
+// corrects the missing and extra marks, which could be caused for example by a crash during the sync, and logs them
+// the blocks are fetched from the backend with blockDelay between the blocks to limit the load of the backend
+// returns the number of corrected inputs
+func (d *RocksDB) VerifyPrivacySpends(chain bchain.BlockChain, blocks int, blockDelay time.Duration, stop chan os.Signal) (int, error) {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType || blocks <= 0 {
+		return 0, nil
+	}
+	bestHeight, _, err := d.GetBestBlock()
+	if err != nil {
+		return 0, err
+	}
+	var fromHeight uint32
+	if bestHeight >= uint32(blocks) {
+		fromHeight = bestHeight - uint32(blocks) + 1
+	}
+	start := time.Now()
+	var fixed int
+	for height := fromHeight; height <= bestHeight; height++ {
+		select {
+		case <-stop:
+			return fixed, errors.New("Interrupted")
+		case <-time.After(blockDelay):
+		}
+		hash, err := d.GetBlockHash(height)
+		if err != nil {
+			return fixed, err
+		}
+		if hash == "" {
+			continue
+		}
+		block, err := chain.GetBlock(hash, height)
+		if err != nil {
+			return fixed, errors.Annotatef(err, "GetBlock %v %v", height, hash)
+		}
+		// skip the block if it was disconnected in the meantime
+		if h, err := d.GetBlockHash(height); err != nil || h != hash {
+			continue
+		}
+		f, err := d.fixBlockPrivacySpends(block, true)
+		if err != nil {
+			return fixed, errors.Annotatef(err, "block %v %v", height, hash)
+		}
+		fixed += f
+	}
+	glog.Info("VerifyPrivacySpends: blocks ", fromHeight, "-", bestHeight, ", corrected inputs ", fixed, ", finished in ", time.Since(start))
+	return fixed, nil
+}
+
+// fixBlockPrivacySpends marks the privacy spend inputs of the block which are not marked
+// and removes the marks from the inputs without prevout which are not privacy spends
+func (d *RocksDB) fixBlockPrivacySpends(block *bchain.Block, logFixes bool) (int, error) {
+	txAddressesMap := make(map[string]*TxAddresses)
+	var fixed int
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		for j := range tx.Vin {
+			ad := d.chainParser.GetPrivacySpendAddrDesc(&tx.Vin[j])
+			if ad == nil && tx.Vin[j].Txid != "" {
+				continue
+			}
+			btxID, err := d.chainParser.PackTxid(tx.Txid)
+			if err != nil {
+				return fixed, err
+			}
+			ta, found := txAddressesMap[string(btxID)]
+			if !found {
+				ta, err = d.getTxAddresses(btxID)
+				if err != nil {
+					return fixed, err
+				}
+				// the transaction may not be indexed, for example in the privacy only index mode
+				if ta == nil {
+					break
+				}
+			}
+			if j >= len(ta.Inputs) {
+				continue
+			}
+			if marked := len(ta.Inputs[j].AddrDesc) > 0; marked == (ad != nil) {
+				continue
+			}
+			if logFixes {
+				if ad != nil {
+					glog.Warning("Privacy spend mark missing: block ", block.Height, ", tx ", tx.Txid, ", input ", j)
+				} else {
+					glog.Warning("Privacy spend mark extra: block ", block.Height, ", tx ", tx.Txid, ", input ", j)
+				}
+			}
+			ta.Inputs[j].AddrDesc = ad
+			txAddressesMap[string(btxID)] = ta
+			fixed++
+		}
+	}
+	if len(txAddressesMap) == 0 {
+		return 0, nil
+	}
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	if err := d.storeTxAddresses(wb, txAddressesMap); err != nil {
+		return fixed, err
+	}
+	return fixed, d.db.Write(d.wo, wb)
+}
@@ -98,6 +98,10 @@ type RocksDB struct {
 	cache        *gorocksdb.Cache
 	maxOpenFiles int
 	cbs          connectBlockStats
+	// minedShieldedStats enables counting of the transactions moving mined coins to the shielded pool
+	minedShieldedStats bool
+	// privacyEvents is the queue of the privacy events waiting for publishing, nil if the publishing is disabled
+	privacyEvents chan bchain.PrivacyEvent
 }
 
 const (
@@ -107,19 +111,24 @@ const (
 	cfBlockTxs
 	cfTransactions
 	cfFiatRates
+	cfOrphans
 	// BitcoinType
 	cfAddressBalance
 	cfTxAddresses
+	cfShieldedSupply
+	cfMints
+	cfTxFlags
+	cfAddressClusters
 	// EthereumType
 	cfAddressContracts = cfAddressBalance
 )
 
 // common columns
 var cfNames []string
-var cfBaseNames = []string{"default", "height", "addresses", "blockTxs", "transactions", "fiatRates"}
+var cfBaseNames = []string{"default", "height", "addresses", "blockTxs", "transactions", "fiatRates", "orphans"}
 
 // type specific columns
-var cfNamesBitcoinType = []string{"addressBalance", "txAddresses"}
+var cfNamesBitcoinType = []string{"addressBalance", "txAddresses", "shieldedSupply", "mints", "txFlags", "addressClusters"}
 var cfNamesEthereumType = []string{"addressContracts"}
 
 func openDB(path string, c *gorocksdb.Cache, openFiles int) (*gorocksdb.DB, []*gorocksdb.ColumnFamilyHandle, error) {
@@ -128,8 +137,8 @@ func openDB(path string, c *gorocksdb.Cache, openFiles int) (*gorocksdb.DB, []*g
 	// opts for addresses without bloom filter
 	// from documentation: if most of your queries are executed using iterators, you shouldn't set bloom filter
 	optsAddresses := createAndSetDBOptions(0, c, openFiles)
-	// default, height, addresses, blockTxids, transactions
-	cfOptions := []*gorocksdb.Options{opts, opts, optsAddresses, opts, opts, opts}
+	// default, height, addresses, blockTxids, transactions, fiatRates, orphans
+	cfOptions := []*gorocksdb.Options{opts, opts, optsAddresses, opts, opts, opts, opts}
 	// append type specific options
 	count := len(cfNames) - len(cfOptions)
 	for i := 0; i < count; i++ {
@@ -164,7 +173,7 @@ func NewRocksDB(path string, cacheSize, maxOpenFiles int, parser bchain.BlockCha
 	}
 	wo := gorocksdb.NewDefaultWriteOptions()
 	ro := gorocksdb.NewDefaultReadOptions()
-	return &RocksDB{path, db, wo, ro, cfh, parser, nil, metrics, c, maxOpenFiles, connectBlockStats{}}, nil
+	return &RocksDB{path, db, wo, ro, cfh, parser, nil, metrics, c, maxOpenFiles, connectBlockStats{}, false, nil}, nil
 }
 
 func (d *RocksDB) closeDB() error {
@@ -453,7 +462,8 @@ func (d *RocksDB) ConnectBlock(block *bchain.Block) error {
 	if chainType == bchain.ChainBitcoinType {
 		txAddressesMap := make(map[string]*TxAddresses)
 		balances := make(map[string]*AddrBalance)
-		if err := d.processAddressesBitcoinType(block, addresses, txAddressesMap, balances); err != nil {
+		ib := d.indexedBlock(block)
+		if err := d.processAddressesBitcoinType(ib, addresses, txAddressesMap, balances); err != nil {
 			return err
 		}
 		if err := d.storeTxAddresses(wb, txAddressesMap); err != nil {
@@ -462,7 +472,16 @@ func (d *RocksDB) ConnectBlock(block *bchain.Block) error {
 		if err := d.storeBalances(wb, balances); err != nil {
 			return err
 		}
-		if err := d.storeAndCleanupBlockTxs(wb, block); err != nil {
+		if err := d.storeAndCleanupBlockTxs(wb, ib); err != nil {
+			return err
+		}
+		if err := d.writeShieldedSupply(wb, block); err != nil {
+			return err
+		}
+		if err := d.writeMints(wb, block); err != nil {
+			return err
+		}
+		if err := d.writeTxFlags(wb, block); err != nil {
 			return err
 		}
 	} else if chainType == bchain.ChainEthereumType {
@@ -487,6 +506,7 @@ func (d *RocksDB) ConnectBlock(block *bchain.Block) error {
 		return err
 	}
 	d.is.AppendBlockTime(uint32(block.Time))
+	d.queuePrivacyEvents(block)
 	return nil
 }
 
@@ -677,6 +697,32 @@ func (d *RocksDB) GetAndResetConnectBlockStats() string {
 	return s
 }
 
+// indexedBlock returns block with the transactions which are to be indexed
+// in the privacy only index mode, only the transactions with privacy operations are indexed
+// the known duplicate coinbase transactions (BIP30) are not indexed, the original transaction is kept
+// and the disconnect of the block of the duplicate does not remove it
+func (d *RocksDB) indexedBlock(block *bchain.Block) *bchain.Block {
+	privacyOnly := d.is != nil && d.is.PrivacyOnlyIndex
+	duplicate := len(block.Txs) > 0 && d.chainParser.IsBIP30Exception(block.Height, block.Txs[0].Txid)
+	if !privacyOnly && !duplicate {
+		return block
+	}
+	txs := make([]bchain.Tx, 0, len(block.Txs))
+	for i := range block.Txs {
+		if i == 0 && duplicate {
+			glog.Info("indexedBlock: height ", block.Height, ", skipping duplicate coinbase ", block.Txs[0].Txid)
+			continue
+		}
+		if !privacyOnly || d.chainParser.IsPrivacyTx(&block.Txs[i]) {
+			txs = append(txs, block.Txs[i])
+		}
+	}
+	return &bchain.Block{
+		BlockHeader: block.BlockHeader,
+		Txs:         txs,
+	}
+}
+
 func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses addressesMap, txAddressesMap map[string]*TxAddresses, balances map[string]*AddrBalance) error {
 	blockTxIDs := make([][]byte, len(block.Txs))
 	blockTxAddresses := make([]*TxAddresses, len(block.Txs))
@@ -745,12 +791,17 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 		ta := blockTxAddresses[txi]
 		ta.Inputs = make([]TxInput, len(tx.Vin))
 		logged := false
+		var inputTxs []*TxAddresses
+		if d.minedShieldedStats {
+			inputTxs = make([]*TxAddresses, 0, len(tx.Vin))
+		}
 		for i, input := range tx.Vin {
 			tai := &ta.Inputs[i]
 			btxID, err := d.chainParser.PackTxid(input.Txid)
 			if err != nil {
-				// do not process inputs without input txid
+				// do not process inputs without input txid, only mark privacy spends
 				if err == bchain.ErrTxidMissing {
+					tai.AddrDesc = d.chainParser.GetPrivacySpendAddrDesc(&tx.Vin[i])
 					continue
 				}
 				return err
@@ -772,6 +823,9 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 			} else {
 				d.cbs.txAddressesHit++
 			}
+			if d.minedShieldedStats {
+				inputTxs = append(inputTxs, ita)
+			}
 			if len(ita.Outputs) <= int(input.Vout) {
 				glog.Warningf("rocksdb: height %d, tx %v, input tx %v vout %v is out of bounds of stored tx", block.Height, tx.Txid, input.Txid, input.Vout)
 				continue
@@ -819,6 +873,9 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 				balance.SentSat.Add(&balance.SentSat, &spentOutput.ValueSat)
 			}
 		}
+		if d.minedShieldedStats {
+			d.countMinedShieldedTx(tx, ta, inputTxs)
+		}
 	}
 	return nil
 }
@@ -892,8 +949,8 @@ func (d *RocksDB) cleanupBlockTxs(wb *gorocksdb.WriteBatch, block *bchain.Block)
 			if err != nil {
 				return err
 			}
-			// nil data means the key was not found in DB
-			if val.Data() == nil {
+			// the key was not found in DB, the empty record of the block without indexed transactions exists
+			if !val.Exists() {
 				break
 			}
 			val.Free()
@@ -907,7 +964,6 @@ func (d *RocksDB) storeAndCleanupBlockTxs(wb *gorocksdb.WriteBatch, block *bchai
 	pl := d.chainParser.PackedTxidLen()
 	buf := make([]byte, 0, pl*len(block.Txs))
 	varBuf := make([]byte, vlq.MaxLen64)
-	zeroTx := make([]byte, pl)
 	for i := range block.Txs {
 		tx := &block.Txs[i]
 		o := make([]outpoint, len(tx.Vin))
@@ -917,7 +973,7 @@ func (d *RocksDB) storeAndCleanupBlockTxs(wb *gorocksdb.WriteBatch, block *bchai
 			if err != nil {
 				// do not process inputs without input txid
 				if err == bchain.ErrTxidMissing {
-					btxID = zeroTx
+					btxID = noPrevoutTxid(d.chainParser, vin)
 				} else {
 					return err
 				}
@@ -939,6 +995,32 @@ func (d *RocksDB) storeAndCleanupBlockTxs(wb *gorocksdb.WriteBatch, block *bchai
 	return d.cleanupBlockTxs(wb, block)
 }
 
+// noPrevoutTxid returns the sentinel txid stored in the blockTxs column for the input without prevout,
+// the privacy spends are stored with the txid of all 0xff bytes, other inputs (coinbase) with the all-zero txid,
+// so that the spends are not mistaken for the coinbase inputs, which share the all-zero null prevout txid
+func noPrevoutTxid(parser bchain.BlockChainParser, vin *bchain.Vin) []byte {
+	btxID := make([]byte, parser.PackedTxidLen())
+	if parser.GetPrivacySpendAddrDesc(vin) != nil {
+		for i := range btxID {
+			btxID[i] = 0xff
+		}
+	}
+	return btxID
+}
+
+// isNoPrevoutTxid returns true if the txid stored in the blockTxs column is one of the sentinels of noPrevoutTxid
+func isNoPrevoutTxid(btxID []byte) bool {
+	if len(btxID) == 0 {
+		return false
+	}
+	for _, b := range btxID[1:] {
+		if b != btxID[0] {
+			return false
+		}
+	}
+	return btxID[0] == 0 || btxID[0] == 0xff
+}
+
 func (d *RocksDB) getBlockTxs(height uint32) ([]blockTxs, error) {
 	pl := d.chainParser.PackedTxidLen()
 	val, err := d.db.GetCF(d.ro, d.cfh[cfBlockTxs], packUint(height))
@@ -946,6 +1028,11 @@ func (d *RocksDB) getBlockTxs(height uint32) ([]blockTxs, error) {
 		return nil, err
 	}
 	defer val.Free()
+	// the missing record is returned as nil, the block without indexed transactions
+	// (privacy only index mode) is stored as an empty record and returned as an empty slice
+	if !val.Exists() {
+		return nil, nil
+	}
 	buf := val.Data()
 	bt := make([]blockTxs, 0, 8)
 	for i := 0; i < len(buf); {
@@ -1366,7 +1453,8 @@ func (d *RocksDB) disconnectTxAddressesInputs(wb *gorocksdb.WriteBatch, btxID []
 	var err error
 	var balance *AddrBalance
 	for i, t := range txa.Inputs {
-		if len(t.AddrDesc) > 0 {
+		// the inputs without prevout (coinbase, privacy spends) are not connected to any output
+		if len(t.AddrDesc) > 0 && !isNoPrevoutTxid(inputs[i].btxID) {
 			input := &inputs[i]
 			exist := addressFoundInTx(t.AddrDesc, btxID)
 			s := string(input.btxID)
@@ -1525,12 +1613,15 @@ func (d *RocksDB) disconnectBlock(height uint32, blockTxs []blockTxs) error {
 	key := packUint(height)
 	wb.DeleteCF(d.cfh[cfBlockTxs], key)
 	wb.DeleteCF(d.cfh[cfHeight], key)
+	wb.DeleteCF(d.cfh[cfShieldedSupply], key)
+	d.deleteMints(wb, height, txAddresses)
 	d.storeTxAddresses(wb, txAddressesToUpdate)
 	d.storeBalancesDisconnect(wb, balances)
 	for s := range txsToDelete {
 		b := []byte(s)
 		wb.DeleteCF(d.cfh[cfTransactions], b)
 		wb.DeleteCF(d.cfh[cfTxAddresses], b)
+		wb.DeleteCF(d.cfh[cfTxFlags], b)
 	}
 	return d.db.Write(d.wo, wb)
 }
@@ -1544,7 +1635,7 @@ func (d *RocksDB) DisconnectBlockRangeBitcoinType(lower uint32, higher uint32) e
 		if err != nil {
 			return err
 		}
-		if len(blockTxs) == 0 {
+		if blockTxs == nil {
 			return errors.Errorf("Cannot disconnect blocks with height %v and lower. It is necessary to rebuild index.", height)
 		}
 		blocks[height-lower] = blockTxs
@@ -5,10 +5,14 @@ package db
 import (
 	"blockbook/bchain"
 	"blockbook/bchain/coins/btc"
+	"blockbook/bchain/coins/xzc"
 	"blockbook/common"
 	"blockbook/tests/dbtestdata"
+	"bytes"
 	"encoding/binary"
+	"encoding/csv"
 	"encoding/hex"
+	"fmt"
 	"io/ioutil"
 	"math/big"
 	"os"
@@ -21,6 +25,7 @@ import (
 	vlq "github.com/bsm/go-vlq"
 	"github.com/juju/errors"
 	"github.com/martinboehm/btcutil/chaincfg"
+	"github.com/tecbot/gorocksdb"
 )
 
 // simplified explanation of signed varint packing, used in many index data structures
@@ -1483,6 +1488,735 @@ func Test_reorderUtxo(t *testing.T) {
 	}
 }
 
+func Test_isMinedShieldedTx(t *testing.T) {
+	parser := newTestZcoinParser(t)
+	p2pkh := hexToBytes("76a914a7f3e9e1e9a1d0fc43d84ee5dbd1e6e7c2306b0b88ac")
+	sigmaMint := hexToBytes("c3" + strings.Repeat("ab", 34))
+	coinbase := &TxAddresses{
+		Height:  1000,
+		Inputs:  []TxInput{{}},
+		Outputs: []TxOutput{{AddrDesc: p2pkh, ValueSat: *big.NewInt(2800000000)}},
+	}
+	coinstake := &TxAddresses{
+		Height:  1001,
+		Inputs:  []TxInput{{AddrDesc: p2pkh, ValueSat: *big.NewInt(100000000000)}},
+		Outputs: []TxOutput{{}, {AddrDesc: p2pkh, ValueSat: *big.NewInt(100500000000)}},
+	}
+	transparent := &TxAddresses{
+		Height:  1002,
+		Inputs:  []TxInput{{AddrDesc: p2pkh, ValueSat: *big.NewInt(300000000)}},
+		Outputs: []TxOutput{{AddrDesc: p2pkh, ValueSat: *big.NewInt(200000000)}},
+	}
+	// mint of 25 XZC funded by a coinbase output
+	mint := &TxAddresses{
+		Height:  1101,
+		Inputs:  []TxInput{{AddrDesc: p2pkh, ValueSat: *big.NewInt(2800000000)}},
+		Outputs: []TxOutput{{AddrDesc: sigmaMint, ValueSat: *big.NewInt(2500000000)}, {AddrDesc: p2pkh, ValueSat: *big.NewInt(299000000)}},
+	}
+	tests := []struct {
+		name     string
+		ta       *TxAddresses
+		inputTxs []*TxAddresses
+		want     bool
+	}{
+		{name: "mint from coinbase", ta: mint, inputTxs: []*TxAddresses{coinbase}, want: true},
+		{name: "mint from coinstake", ta: mint, inputTxs: []*TxAddresses{transparent, coinstake}, want: true},
+		{name: "mint from transparent", ta: mint, inputTxs: []*TxAddresses{transparent}, want: false},
+		{name: "mint from unknown input", ta: mint, inputTxs: []*TxAddresses{nil}, want: false},
+		{name: "transparent from coinbase", ta: transparent, inputTxs: []*TxAddresses{coinbase}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isMinedShieldedTx(parser, tt.ta, tt.inputTxs); got != tt.want {
+				t.Errorf("isMinedShieldedTx() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writeBlockMints(t *testing.T) {
+	parser := newTestZcoinParser(t)
+	block := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{Height: 1101},
+		Txs: []bchain.Tx{
+			{
+				Txid: "tx1",
+				Vout: []bchain.Vout{
+					{N: 0, ValueSat: *big.NewInt(2800000000), ScriptPubKey: bchain.ScriptPubKey{Hex: "76a914a7f3e9e1e9a1d0fc43d84ee5dbd1e6e7c2306b0b88ac"}},
+				},
+			},
+			{
+				Txid: "tx2",
+				Vout: []bchain.Vout{
+					{N: 0, ValueSat: *big.NewInt(2500000000), ScriptPubKey: bchain.ScriptPubKey{Hex: "c3" + strings.Repeat("ab", 34)}},
+					{N: 1, ValueSat: *big.NewInt(299000000), ScriptPubKey: bchain.ScriptPubKey{Hex: "76a914a7f3e9e1e9a1d0fc43d84ee5dbd1e6e7c2306b0b88ac"}},
+					{N: 2, ValueSat: *big.NewInt(1000000000), ScriptPubKey: bchain.ScriptPubKey{Hex: "c1" + strings.Repeat("cd", 34)}},
+				},
+			},
+		},
+	}
+	var b strings.Builder
+	cw := csv.NewWriter(&b)
+	n, err := writeBlockMints(parser, cw, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("writeBlockMints() = %v, want 2", n)
+	}
+	want := "1101,tx2,Sigmamint,2500000000\n1101,tx2,Zeromint,1000000000\n"
+	if b.String() != want {
+		t.Errorf("writeBlockMints() wrote %q, want %q", b.String(), want)
+	}
+}
+
+func Test_packMintKey_unpackMintKey(t *testing.T) {
+	d := big.NewInt(2500000000)
+	key := packMintKey(d, 215840)
+	if got, want := hex.EncodeToString(key), "049502f900"+"fffcb4df"; got != want {
+		t.Errorf("packMintKey() = %v, want %v", got, want)
+	}
+	gotD, gotHeight, err := unpackMintKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotD.Cmp(d) != 0 || gotHeight != 215840 {
+		t.Errorf("unpackMintKey() = %v, %v, want %v, 215840", gotD.String(), gotHeight, d)
+	}
+	// newer blocks are ordered first
+	if bytes.Compare(packMintKey(d, 215841), key) >= 0 {
+		t.Error("packMintKey() of newer block is not ordered before the older block")
+	}
+	if _, _, err := unpackMintKey(key[:len(key)-1]); err == nil {
+		t.Error("unpackMintKey() of invalid key, want error")
+	}
+}
+
+func TestRocksDB_Mints(t *testing.T) {
+	d := setupRocksDB(t, newTestZcoinParser(t))
+	defer closeAndDestroyRocksDB(t, d)
+	sigmaMint := "c3" + strings.Repeat("ab", 34)
+	zerocoinMint := "c1" + strings.Repeat("cd", 34)
+	p2pkh := "76a914a7f3e9e1e9a1d0fc43d84ee5dbd1e6e7c2306b0b88ac"
+	txid1, txid2, txid3 := strings.Repeat("11", 32), strings.Repeat("22", 32), strings.Repeat("33", 32)
+	blocks := []*bchain.Block{
+		{
+			BlockHeader: bchain.BlockHeader{Height: 100},
+			Txs: []bchain.Tx{{
+				Txid: txid1,
+				Vout: []bchain.Vout{
+					{N: 0, ValueSat: *big.NewInt(2500000000), ScriptPubKey: bchain.ScriptPubKey{Hex: sigmaMint}},
+					{N: 1, ValueSat: *big.NewInt(2500000000), ScriptPubKey: bchain.ScriptPubKey{Hex: p2pkh}},
+					{N: 2, ValueSat: *big.NewInt(1000000000), ScriptPubKey: bchain.ScriptPubKey{Hex: sigmaMint}},
+					{N: 200, ValueSat: *big.NewInt(2500000000), ScriptPubKey: bchain.ScriptPubKey{Hex: sigmaMint}},
+				},
+			}},
+		},
+		{
+			BlockHeader: bchain.BlockHeader{Height: 101},
+			Txs: []bchain.Tx{
+				{Txid: txid2, Vout: []bchain.Vout{{N: 0, ValueSat: *big.NewInt(2500000000), ScriptPubKey: bchain.ScriptPubKey{Hex: zerocoinMint}}}},
+				{Txid: txid3, Vout: []bchain.Vout{{N: 1, ValueSat: *big.NewInt(2500000000), ScriptPubKey: bchain.ScriptPubKey{Hex: sigmaMint}}}},
+			},
+		},
+		{
+			BlockHeader: bchain.BlockHeader{Height: 102},
+			Txs:         []bchain.Tx{{Txid: txid1, Vout: []bchain.Vout{{N: 0, ValueSat: *big.NewInt(2500000000), ScriptPubKey: bchain.ScriptPubKey{Hex: p2pkh}}}}},
+		},
+	}
+	for _, b := range blocks {
+		if err := d.storeMints(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	getMintsPage := func(denomination int64, lower, higher uint32, skip, limit int) []string {
+		var r []string
+		if err := d.GetMints(big.NewInt(denomination), lower, higher, skip, func(txid string, vout int32, height uint32) error {
+			r = append(r, fmt.Sprintf("%v:%v:%v", height, txid[:2], vout))
+			if len(r) == limit {
+				return &StopIteration{}
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	getMints := func(denomination int64, lower, higher uint32) []string {
+		return getMintsPage(denomination, lower, higher, 0, 0)
+	}
+	tests := []struct {
+		name         string
+		denomination int64
+		lower        uint32
+		higher       uint32
+		want         []string
+	}{
+		{name: "all", denomination: 2500000000, lower: 0, higher: 1000, want: []string{"101:22:0", "101:33:1", "100:11:0", "100:11:200"}},
+		{name: "lower bound", denomination: 2500000000, lower: 101, higher: 1000, want: []string{"101:22:0", "101:33:1"}},
+		{name: "higher bound", denomination: 2500000000, lower: 0, higher: 100, want: []string{"100:11:0", "100:11:200"}},
+		{name: "other denomination", denomination: 1000000000, lower: 0, higher: 1000, want: []string{"100:11:2"}},
+		{name: "no mints", denomination: 100000000, lower: 0, higher: 1000, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getMints(tt.denomination, tt.lower, tt.higher); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetMints() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	// the mints are skipped also across the blocks and the iteration is stopped by the callback
+	pages := []struct {
+		skip, limit int
+		want        []string
+	}{
+		{skip: 0, limit: 3, want: []string{"101:22:0", "101:33:1", "100:11:0"}},
+		{skip: 1, limit: 2, want: []string{"101:33:1", "100:11:0"}},
+		{skip: 3, limit: 2, want: []string{"100:11:200"}},
+		{skip: 4, limit: 2, want: nil},
+	}
+	for _, p := range pages {
+		if got := getMintsPage(2500000000, 0, 1000, p.skip, p.limit); !reflect.DeepEqual(got, p.want) {
+			t.Errorf("GetMints(skip %v, limit %v) = %v, want %v", p.skip, p.limit, got, p.want)
+		}
+	}
+	// disconnect the block 101
+	sigmaMintAddrDesc, _ := hex.DecodeString(sigmaMint)
+	zerocoinMintAddrDesc, _ := hex.DecodeString(zerocoinMint)
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	d.deleteMints(wb, 101, []*TxAddresses{
+		{Outputs: []TxOutput{{AddrDesc: zerocoinMintAddrDesc, ValueSat: *big.NewInt(2500000000)}}},
+		nil,
+		{Outputs: []TxOutput{{AddrDesc: sigmaMintAddrDesc, ValueSat: *big.NewInt(2500000000)}}},
+	})
+	if err := d.db.Write(d.wo, wb); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := getMints(2500000000, 0, 1000), []string{"100:11:0", "100:11:200"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetMints() after disconnect = %v, want %v", got, want)
+	}
+}
+
+// testZcoinBlock returns the Zcoin block at the height with the coinbase transaction and given transactions,
+// the variant distinguishes the hashes and txids of the blocks at the same height
+func testZcoinBlock(height uint32, variant byte, txs ...bchain.Tx) *bchain.Block {
+	p2pkh := "76a914a7f3e9e1e9a1d0fc43d84ee5dbd1e6e7c2306b0b88ac"
+	coinbase := bchain.Tx{
+		Txid: fmt.Sprintf("%02x%062x", variant, height),
+		Vin:  []bchain.Vin{{Coinbase: "03a0bb0d"}},
+		Vout: []bchain.Vout{{N: 0, ValueSat: *big.NewInt(5000000000), ScriptPubKey: bchain.ScriptPubKey{Hex: p2pkh}}},
+	}
+	return &bchain.Block{
+		BlockHeader: bchain.BlockHeader{
+			Height: height,
+			Hash:   fmt.Sprintf("%02x%062x", variant, height+1000000),
+			Time:   1560000000 + int64(height),
+		},
+		Txs: append([]bchain.Tx{coinbase}, txs...),
+	}
+}
+
+func TestRocksDB_ShieldedSupply(t *testing.T) {
+	params, err := xzc.GetChainParams("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// keep the blockTxs of the connected blocks so that they can be disconnected
+	parser := xzc.NewZcoinParser(params, &btc.Configuration{BlockAddressesToKeep: 10})
+	sigmaMint := "c3" + strings.Repeat("ab", 34)
+	// Sigma spend of the denomination 10 XZC
+	sigmaSpend := "c4010a00ca9a3b00000000" + strings.Repeat("ef", 32)
+	p2pkh := "76a914a7f3e9e1e9a1d0fc43d84ee5dbd1e6e7c2306b0b88ac"
+	// the mint is funded by a transparent input, the transaction without inputs would be fully shielded
+	mintTx := func(txid string, value int64) bchain.Tx {
+		return bchain.Tx{
+			Txid: txid,
+			Vin:  []bchain.Vin{{Txid: strings.Repeat("aa", 32), Vout: 0}},
+			Vout: []bchain.Vout{{N: 0, ValueSat: *big.NewInt(value), ScriptPubKey: bchain.ScriptPubKey{Hex: sigmaMint}}},
+		}
+	}
+	blocks := []*bchain.Block{
+		testZcoinBlock(100, 0, mintTx(strings.Repeat("11", 32), 2500000000)),
+		testZcoinBlock(101, 0),
+		testZcoinBlock(102, 0,
+			bchain.Tx{
+				Txid: strings.Repeat("22", 32),
+				Vin:  []bchain.Vin{{Coinbase: sigmaSpend, ScriptSig: bchain.ScriptSig{Hex: sigmaSpend}}},
+				Vout: []bchain.Vout{{N: 0, ValueSat: *big.NewInt(990000000), ScriptPubKey: bchain.ScriptPubKey{Hex: p2pkh}}},
+			},
+			mintTx(strings.Repeat("33", 32), 1000000000),
+		),
+	}
+	type supply struct {
+		height        uint32
+		minted, spent int64
+	}
+	verify := func(t *testing.T, d *RocksDB, name string, want []supply) {
+		for _, w := range want {
+			s, err := d.GetShieldedSupply(w.height)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Minted.Int64() != w.minted || s.Spent.Int64() != w.spent {
+				t.Errorf("%v: GetShieldedSupply(%v) = minted %v, spent %v, want minted %v, spent %v", name, w.height, s.Minted.String(), s.Spent.String(), w.minted, w.spent)
+			}
+		}
+	}
+	connected := []supply{
+		{height: 99, minted: 0, spent: 0},
+		{height: 100, minted: 2500000000, spent: 0},
+		{height: 101, minted: 2500000000, spent: 0},
+		{height: 102, minted: 3500000000, spent: 1000000000},
+		{height: 1000, minted: 3500000000, spent: 1000000000},
+	}
+
+	t.Run("connect", func(t *testing.T) {
+		d := setupRocksDB(t, parser)
+		defer closeAndDestroyRocksDB(t, d)
+		for _, b := range blocks {
+			if err := d.ConnectBlock(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		verify(t, d, "connect", connected)
+
+		if err := d.DisconnectBlockRangeBitcoinType(101, 102); err != nil {
+			t.Fatal(err)
+		}
+		verify(t, d, "disconnect", []supply{
+			{height: 100, minted: 2500000000, spent: 0},
+			{height: 102, minted: 2500000000, spent: 0},
+			{height: 1000, minted: 2500000000, spent: 0},
+		})
+
+		// reconnect other blocks, the running total continues from the block 100
+		for _, b := range []*bchain.Block{
+			testZcoinBlock(101, 1, mintTx(strings.Repeat("44", 32), 500000000)),
+			testZcoinBlock(102, 1),
+		} {
+			if err := d.ConnectBlock(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		verify(t, d, "reconnect", []supply{
+			{height: 100, minted: 2500000000, spent: 0},
+			{height: 101, minted: 3000000000, spent: 0},
+			{height: 102, minted: 3000000000, spent: 0},
+		})
+	})
+
+	t.Run("bulk connect", func(t *testing.T) {
+		d := setupRocksDB(t, parser)
+		defer closeAndDestroyRocksDB(t, d)
+		bc, err := d.InitBulkConnect()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, b := range blocks {
+			if err := bc.ConnectBlock(b, true); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// the supply is written immediately, before the bulk connect is closed
+		verify(t, d, "bulk connect", connected)
+		if err := bc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		verify(t, d, "bulk connect closed", connected)
+	})
+}
+
+// testBlocksChain is the backend returning the blocks by hash, other methods of the BlockChain are not implemented
+type testBlocksChain struct {
+	bchain.BlockChain
+	blocks map[string]*bchain.Block
+}
+
+func (c *testBlocksChain) GetBlock(hash string, height uint32) (*bchain.Block, error) {
+	b, found := c.blocks[hash]
+	if !found {
+		return nil, bchain.ErrBlockNotFound
+	}
+	return b, nil
+}
+
+// setupPrivacySpendsRocksDB connects the blocks 100 and 101 with Sigma spends and returns the db and the backend with the blocks
+func setupPrivacySpendsRocksDB(t *testing.T) (*RocksDB, *testBlocksChain, []*bchain.Block) {
+	d := setupRocksDB(t, newTestZcoinParser(t))
+	sigmaSpend := "c4010a00ca9a3b00000000" + strings.Repeat("ef", 32)
+	p2pkh := "76a914a7f3e9e1e9a1d0fc43d84ee5dbd1e6e7c2306b0b88ac"
+	spendTx := func(txid string) bchain.Tx {
+		return bchain.Tx{
+			Txid: txid,
+			Vin:  []bchain.Vin{{Coinbase: sigmaSpend, ScriptSig: bchain.ScriptSig{Hex: sigmaSpend}}},
+			Vout: []bchain.Vout{{N: 0, ValueSat: *big.NewInt(990000000), ScriptPubKey: bchain.ScriptPubKey{Hex: p2pkh}}},
+		}
+	}
+	blocks := []*bchain.Block{
+		testZcoinBlock(100, 0, spendTx(strings.Repeat("11", 32))),
+		testZcoinBlock(101, 0, spendTx(strings.Repeat("22", 32))),
+	}
+	chain := &testBlocksChain{blocks: make(map[string]*bchain.Block)}
+	for _, b := range blocks {
+		if err := d.ConnectBlock(b); err != nil {
+			t.Fatal(err)
+		}
+		chain.blocks[b.Hash] = b
+	}
+	return d, chain, blocks
+}
+
+// setInputAddrDesc overwrites the address descriptor of the input of the indexed transaction
+func setInputAddrDesc(t *testing.T, d *RocksDB, txid string, input int, ad bchain.AddressDescriptor) {
+	btxID, err := d.chainParser.PackTxid(txid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ta, err := d.getTxAddresses(btxID)
+	if err != nil || ta == nil {
+		t.Fatal("getTxAddresses ", txid, err)
+	}
+	ta.Inputs[input].AddrDesc = ad
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	if err := d.storeTxAddresses(wb, map[string]*TxAddresses{string(btxID): ta}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.db.Write(d.wo, wb); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// getInputAddrDesc returns the address descriptor of the input of the indexed transaction
+func getInputAddrDesc(t *testing.T, d *RocksDB, txid string, input int) bchain.AddressDescriptor {
+	btxID, err := d.chainParser.PackTxid(txid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ta, err := d.getTxAddresses(btxID)
+	if err != nil || ta == nil {
+		t.Fatal("getTxAddresses ", txid, err)
+	}
+	return ta.Inputs[input].AddrDesc
+}
+
+func TestRocksDB_FixPrivacySpends(t *testing.T) {
+	d, chain, blocks := setupPrivacySpendsRocksDB(t)
+	defer closeAndDestroyRocksDB(t, d)
+	spend, coinbase := blocks[0].Txs[1].Txid, blocks[1].Txs[0].Txid
+	spendAddrDesc := bchain.AddressDescriptor{xzc.OpSigmaSpend}
+	if got := getInputAddrDesc(t, d, spend, 0); !bytes.Equal(got, spendAddrDesc) {
+		t.Fatalf("spend input after connect = %x, want %x", got, spendAddrDesc)
+	}
+	// the spend indexed as the coinbase input and the coinbase input marked as the spend
+	setInputAddrDesc(t, d, spend, 0, nil)
+	setInputAddrDesc(t, d, coinbase, 0, spendAddrDesc)
+	stop := make(chan os.Signal)
+	for i, want := range []int{2, 0} {
+		fixed, err := d.FixPrivacySpends(chain, stop)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fixed != want {
+			t.Errorf("FixPrivacySpends() run %v = %v, want %v", i, fixed, want)
+		}
+		if got := getInputAddrDesc(t, d, spend, 0); !bytes.Equal(got, spendAddrDesc) {
+			t.Errorf("FixPrivacySpends() run %v: spend input = %x, want %x", i, got, spendAddrDesc)
+		}
+		if got := getInputAddrDesc(t, d, coinbase, 0); len(got) != 0 {
+			t.Errorf("FixPrivacySpends() run %v: coinbase input = %x, want empty", i, got)
+		}
+	}
+}
+
+func TestRocksDB_VerifyPrivacySpends(t *testing.T) {
+	d, chain, blocks := setupPrivacySpendsRocksDB(t)
+	defer closeAndDestroyRocksDB(t, d)
+	spend, coinbase := blocks[0].Txs[1].Txid, blocks[1].Txs[0].Txid
+	spendAddrDesc := bchain.AddressDescriptor{xzc.OpSigmaSpend}
+	// missing mark in the block 100 and extra mark in the block 101
+	setInputAddrDesc(t, d, spend, 0, nil)
+	setInputAddrDesc(t, d, coinbase, 0, spendAddrDesc)
+	stop := make(chan os.Signal)
+	tests := []struct {
+		name      string
+		blocks    int
+		want      int
+		wantSpend bchain.AddressDescriptor
+	}{
+		{name: "no blocks", blocks: 0, want: 0},
+		{name: "last block", blocks: 1, want: 1},
+		{name: "more blocks than indexed", blocks: 10, want: 1, wantSpend: spendAddrDesc},
+		{name: "verified", blocks: 10, want: 0, wantSpend: spendAddrDesc},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixed, err := d.VerifyPrivacySpends(chain, tt.blocks, 0, stop)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fixed != tt.want {
+				t.Errorf("VerifyPrivacySpends() = %v, want %v", fixed, tt.want)
+			}
+			if got := getInputAddrDesc(t, d, spend, 0); !bytes.Equal(got, tt.wantSpend) {
+				t.Errorf("VerifyPrivacySpends() spend input = %x, want %x", got, tt.wantSpend)
+			}
+			if tt.blocks > 0 {
+				if got := getInputAddrDesc(t, d, coinbase, 0); len(got) != 0 {
+					t.Errorf("VerifyPrivacySpends() coinbase input = %x, want empty", got)
+				}
+			}
+		})
+	}
+}
+
+func TestRocksDB_PrivacyOnlyIndex(t *testing.T) {
+	params, err := xzc.GetChainParams("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := setupRocksDB(t, xzc.NewZcoinParser(params, &btc.Configuration{BlockAddressesToKeep: 10}))
+	defer closeAndDestroyRocksDB(t, d)
+	d.is.PrivacyOnlyIndex = true
+	sigmaMint := "c3" + strings.Repeat("ab", 34)
+	sigmaSpend := "c4010a00ca9a3b00000000" + strings.Repeat("ef", 32)
+	spendOutput := "76a914a7f3e9e1e9a1d0fc43d84ee5dbd1e6e7c2306b0b88ac"
+	transparentOutput := "76a914" + strings.Repeat("12", 20) + "88ac"
+	transparent, spend, mint := strings.Repeat("11", 32), strings.Repeat("22", 32), strings.Repeat("33", 32)
+	blocks := []*bchain.Block{
+		testZcoinBlock(100, 0,
+			bchain.Tx{
+				Txid: transparent,
+				Vin:  []bchain.Vin{{Txid: strings.Repeat("aa", 32), Vout: 0}},
+				Vout: []bchain.Vout{{N: 0, ValueSat: *big.NewInt(100000000), ScriptPubKey: bchain.ScriptPubKey{Hex: transparentOutput}}},
+			},
+			bchain.Tx{
+				Txid: spend,
+				Vin:  []bchain.Vin{{Coinbase: sigmaSpend, ScriptSig: bchain.ScriptSig{Hex: sigmaSpend}}},
+				Vout: []bchain.Vout{{N: 0, ValueSat: *big.NewInt(990000000), ScriptPubKey: bchain.ScriptPubKey{Hex: spendOutput}}},
+			},
+			bchain.Tx{
+				Txid: mint,
+				Vin:  []bchain.Vin{{Txid: transparent, Vout: 0}},
+				Vout: []bchain.Vout{{N: 0, ValueSat: *big.NewInt(100000000), ScriptPubKey: bchain.ScriptPubKey{Hex: sigmaMint}}},
+			},
+		),
+		// the block without privacy transactions
+		testZcoinBlock(101, 0, bchain.Tx{
+			Txid: strings.Repeat("44", 32),
+			Vin:  []bchain.Vin{{Txid: strings.Repeat("bb", 32), Vout: 0}},
+			Vout: []bchain.Vout{{N: 0, ValueSat: *big.NewInt(100000000), ScriptPubKey: bchain.ScriptPubKey{Hex: transparentOutput}}},
+		}),
+	}
+	for _, b := range blocks {
+		if err := d.ConnectBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	indexed := func(txid string) bool {
+		btxID, err := d.chainParser.PackTxid(txid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ta, err := d.getTxAddresses(btxID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ta != nil
+	}
+	balanceTxs := func(script string) uint32 {
+		ab, err := d.GetAddrDescBalance(hexToBytes(script), AddressBalanceDetailNoUTXO)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ab == nil {
+			return 0
+		}
+		return ab.Txs
+	}
+	blockTxids := func(height uint32) []string {
+		bt, err := d.getBlockTxs(height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r []string
+		for _, b := range bt {
+			txid, err := d.chainParser.UnpackTxid(b.btxID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r = append(r, txid)
+		}
+		return r
+	}
+	for _, b := range blocks {
+		for _, tx := range b.Txs {
+			if got, want := indexed(tx.Txid), tx.Txid == spend || tx.Txid == mint; got != want {
+				t.Errorf("txAddresses of %v indexed %v, want %v", tx.Txid, got, want)
+			}
+		}
+	}
+	if got := balanceTxs(spendOutput); got != 1 {
+		t.Errorf("balance of the spend output Txs = %v, want 1", got)
+	}
+	if got := balanceTxs(transparentOutput); got != 0 {
+		t.Errorf("balance of the transparent output Txs = %v, want 0", got)
+	}
+	if got, want := blockTxids(100), []string{spend, mint}; !reflect.DeepEqual(got, want) {
+		t.Errorf("blockTxs(100) = %v, want %v", got, want)
+	}
+	if got := blockTxids(101); len(got) != 0 {
+		t.Errorf("blockTxs(101) = %v, want empty", got)
+	}
+
+	if err := d.DisconnectBlockRangeBitcoinType(100, 101); err != nil {
+		t.Fatal(err)
+	}
+	for _, txid := range []string{spend, mint} {
+		if indexed(txid) {
+			t.Errorf("txAddresses of %v indexed after disconnect", txid)
+		}
+	}
+	if got := balanceTxs(spendOutput); got != 0 {
+		t.Errorf("balance of the spend output Txs after disconnect = %v, want 0", got)
+	}
+	for _, height := range []uint32{100, 101} {
+		if hash, err := d.GetBlockHash(height); err != nil || hash != "" {
+			t.Errorf("GetBlockHash(%v) after disconnect = %v, %v, want empty", height, hash, err)
+		}
+	}
+}
+
+func Test_packOrphanBlock_unpackOrphanBlock(t *testing.T) {
+	d := &RocksDB{chainParser: bitcoinTestnetParser()}
+	hash := "00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6"
+	tests := []struct {
+		name string
+		ob   OrphanBlock
+	}{
+		{
+			name: "without staker",
+			ob:   OrphanBlock{Hash: hash, Height: 225494, Time: 1534858022, Txs: 2, Size: 1234, DisconnectTime: 1534858100},
+		},
+		{
+			name: "with staker",
+			ob:   OrphanBlock{Hash: hash, Height: 1, Time: 1534858022, Txs: 300, Size: 1234567, Staker: "mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti", DisconnectTime: 1534858100},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := d.packOrphanBlockKey(tt.ob.Height, tt.ob.Hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := d.unpackOrphanBlock(key, packOrphanBlock(&tt.ob))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, &tt.ob) {
+				t.Errorf("unpackOrphanBlock() = %+v, want %+v", got, tt.ob)
+			}
+		})
+	}
+	// the truncated staker is dropped, the record shorter than the fixed part is not unpacked
+	ob := tests[1].ob
+	key, _ := d.packOrphanBlockKey(ob.Height, ob.Hash)
+	buf := packOrphanBlock(&ob)
+	got, err := d.unpackOrphanBlock(key, buf[:len(buf)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ob.Staker = ""
+	if !reflect.DeepEqual(got, &ob) {
+		t.Errorf("unpackOrphanBlock(truncated staker) = %+v, want %+v", got, ob)
+	}
+	if got, err := d.unpackOrphanBlock(key, buf[:10]); got != nil || err != nil {
+		t.Errorf("unpackOrphanBlock(truncated) = %+v, %v, want nil", got, err)
+	}
+}
+
+func TestRocksDB_GetOrphanBlocks(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+	obs := []OrphanBlock{
+		{Hash: strings.Repeat("01", 32), Height: 100, Time: 1534858000, Txs: 1, Size: 200, DisconnectTime: 1534859000},
+		{Hash: strings.Repeat("03", 32), Height: 101, Time: 1534858100, Txs: 2, Size: 300, DisconnectTime: 1534859000},
+		{Hash: strings.Repeat("02", 32), Height: 101, Time: 1534858101, Txs: 3, Size: 400, Staker: "mfcWp7DB6NuaZsExybTTXpVgWz559Np4Ti", DisconnectTime: 1534859100},
+		{Hash: strings.Repeat("04", 32), Height: 99, Time: 1534857900, Txs: 4, Size: 500, DisconnectTime: 1534859100},
+	}
+	for i := range obs {
+		if err := d.StoreOrphanBlock(&obs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the truncated record is skipped
+	key, err := d.packOrphanBlockKey(102, strings.Repeat("05", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.db.PutCF(d.wo, d.cfh[cfOrphans], key, packOrphanBlock(&obs[0])[:10]); err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.GetOrphanBlocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ordered from the highest height, the blocks at the same height by the packed hash in descending order
+	want := []OrphanBlock{obs[1], obs[2], obs[0], obs[3]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetOrphanBlocks() = %+v, want %+v", got, want)
+	}
+}
+
+type testPrivacyEventPublisher struct {
+	events chan *bchain.PrivacyEvent
+}
+
+func (p *testPrivacyEventPublisher) Publish(event *bchain.PrivacyEvent) error {
+	p.events <- event
+	return errors.New("publish error")
+}
+
+func Test_queuePrivacyEvents(t *testing.T) {
+	parser := newTestZcoinParser(t)
+	block := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{Height: 1101},
+		Txs: []bchain.Tx{
+			{
+				Txid: "tx1",
+				Vout: []bchain.Vout{
+					{N: 0, ValueSat: *big.NewInt(2500000000), ScriptPubKey: bchain.ScriptPubKey{Hex: "c3" + strings.Repeat("ab", 34)}},
+					{N: 1, ValueSat: *big.NewInt(1000000000), ScriptPubKey: bchain.ScriptPubKey{Hex: "c3" + strings.Repeat("cd", 34)}},
+				},
+			},
+		},
+	}
+	// failing publisher does not stop the publishing
+	publisher := &testPrivacyEventPublisher{events: make(chan *bchain.PrivacyEvent, 2)}
+	d := &RocksDB{chainParser: parser}
+	d.SetPrivacyEventPublisher(publisher)
+	d.queuePrivacyEvents(block)
+	for _, want := range []string{"2500000000", "1000000000"} {
+		select {
+		case e := <-publisher.events:
+			if e.Txid != "tx1" || e.Height != 1101 || e.Type != "sigmamint" || e.Value != want {
+				t.Errorf("Publish() got %+v, want value %v", e, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Publish() not called")
+		}
+	}
+	// the events over the queue size are dropped without blocking
+	d = &RocksDB{chainParser: parser, privacyEvents: make(chan bchain.PrivacyEvent, 1)}
+	d.queuePrivacyEvents(block)
+	if len(d.privacyEvents) != 1 {
+		t.Errorf("queuePrivacyEvents() queued %v events, want 1", len(d.privacyEvents))
+	}
+}
+
 func TestRocksTickers(t *testing.T) {
 	d := setupRocksDB(t, &testBitcoinParser{
 		BitcoinParser: bitcoinTestnetParser(),
@@ -1558,3 +2292,261 @@ func TestRocksTickers(t *testing.T) {
 		t.Errorf("Ticker found, but the timestamp is older than the last ticker entry.")
 	}
 }
+
+func Test_blockTxsForAddrDesc(t *testing.T) {
+	parser := newTestZcoinParser(t)
+	scriptA := "76a914a7f3e9e1e9a1d0fc43d84ee5dbd1e6e7c2306b0b88ac"
+	scriptB := "76a914b7f3e9e1e9a1d0fc43d84ee5dbd1e6e7c2306b0b88ac"
+	txidA, txidB, txidStored := strings.Repeat("aa", 32), strings.Repeat("bb", 32), strings.Repeat("dd", 32)
+	block := &bchain.Block{
+		Txs: []bchain.Tx{
+			{
+				Txid: txidA,
+				Vin:  []bchain.Vin{{Coinbase: "03a0bb0d"}},
+				Vout: []bchain.Vout{{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: scriptA}}},
+			},
+			// spends the output of the first transaction of the block
+			{
+				Txid: txidB,
+				Vin:  []bchain.Vin{{Txid: txidA, Vout: 0}},
+				Vout: []bchain.Vout{{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: scriptB}}},
+			},
+			// spends the stored output
+			{
+				Txid: strings.Repeat("cc", 32),
+				Vin:  []bchain.Vin{{Txid: txidStored, Vout: 1}},
+				Vout: []bchain.Vout{{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: scriptB}}},
+			},
+			// privacy spend
+			{
+				Txid: strings.Repeat("ee", 32),
+				Vin:  []bchain.Vin{{Coinbase: "c2" + strings.Repeat("ab", 32)}},
+				Vout: []bchain.Vout{{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: scriptB}}},
+			},
+		},
+	}
+	addrDescA, _ := hex.DecodeString(scriptA)
+	addrDescB, _ := hex.DecodeString(scriptB)
+	getTxAddresses := func(btxID []byte) (*TxAddresses, error) {
+		if hex.EncodeToString(btxID) != txidStored {
+			return nil, nil
+		}
+		return &TxAddresses{Outputs: []TxOutput{{AddrDesc: addrDescB}, {AddrDesc: addrDescA}}}, nil
+	}
+	tests := []struct {
+		name     string
+		addrDesc bchain.AddressDescriptor
+		want     []int
+	}{
+		{name: "outputs and inputs", addrDesc: addrDescA, want: []int{0, 1, 2}},
+		{name: "outputs", addrDesc: addrDescB, want: []int{1, 2, 3}},
+		{name: "privacy spend", addrDesc: bchain.AddressDescriptor{xzc.OpZeroCoinSpend}, want: []int{3}},
+		{name: "not in block", addrDesc: bchain.AddressDescriptor{0x00}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := blockTxsForAddrDesc(parser, block, tt.addrDesc, getTxAddresses)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("blockTxsForAddrDesc() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_noPrevoutTxid(t *testing.T) {
+	parser := newTestZcoinParser(t)
+	d := &RocksDB{chainParser: parser}
+	coinbase := &bchain.Vin{Coinbase: "03a0bb0d"}
+	// the privacy spend is converted by the parser to the input without prevout, same as the coinbase
+	spend := &bchain.Vin{Coinbase: "c4" + strings.Repeat("ab", 32)}
+	cbTxid := noPrevoutTxid(parser, coinbase)
+	spendTxid := noPrevoutTxid(parser, spend)
+	if want := make([]byte, parser.PackedTxidLen()); !reflect.DeepEqual(cbTxid, want) {
+		t.Errorf("noPrevoutTxid(coinbase) = %x, want %x", cbTxid, want)
+	}
+	if reflect.DeepEqual(cbTxid, spendTxid) {
+		t.Errorf("noPrevoutTxid(spend) = %x collides with the coinbase", spendTxid)
+	}
+	// both inputs packed to the blockTxs column with the same index stay distinguishable
+	o := []outpoint{{btxID: cbTxid, index: 0}, {btxID: spendTxid, index: 0}}
+	buf := append([]byte{byte(len(o))}, d.packOutpoints(o)...)
+	got, _, err := d.unpackNOutpoints(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, o) {
+		t.Errorf("unpackNOutpoints() = %+v, want %+v", got, o)
+	}
+	for _, btxID := range [][]byte{cbTxid, spendTxid} {
+		if !isNoPrevoutTxid(btxID) {
+			t.Errorf("isNoPrevoutTxid(%x) = false, want true", btxID)
+		}
+	}
+	if isNoPrevoutTxid(hexToBytes(dbtestdata.TxidB1T1)) {
+		t.Errorf("isNoPrevoutTxid(%v) = true, want false", dbtestdata.TxidB1T1)
+	}
+}
+
+// newTestZcoinParser returns the Zcoin parser of the main network
+func newTestZcoinParser(t *testing.T) *xzc.ZcoinParser {
+	params, err := xzc.GetChainParams("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return xzc.NewZcoinParser(params, &btc.Configuration{})
+}
+
+func TestRocksDB_TxFlags(t *testing.T) {
+	d := setupRocksDB(t, newTestZcoinParser(t))
+	defer closeAndDestroyRocksDB(t, d)
+	sigmaMint := "c3" + strings.Repeat("ab", 34)
+	sigmaSpend := "c4" + strings.Repeat("ef", 34)
+	p2pkh := "76a914a7f3e9e1e9a1d0fc43d84ee5dbd1e6e7c2306b0b88ac"
+	txid1, txid2, txid3, txid4 := strings.Repeat("11", 32), strings.Repeat("22", 32), strings.Repeat("33", 32), strings.Repeat("44", 32)
+	block := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{Height: 100},
+		Txs: []bchain.Tx{
+			{Txid: txid1, Vout: []bchain.Vout{{N: 0, ValueSat: *big.NewInt(2500000000), ScriptPubKey: bchain.ScriptPubKey{Hex: sigmaMint}}}},
+			{
+				Txid: txid2,
+				Vin:  []bchain.Vin{{ScriptSig: bchain.ScriptSig{Hex: sigmaSpend}}},
+				Vout: []bchain.Vout{{N: 0, ValueSat: *big.NewInt(2500000000), ScriptPubKey: bchain.ScriptPubKey{Hex: p2pkh}}},
+			},
+			{Txid: txid3, Vout: []bchain.Vout{{N: 0, ValueSat: *big.NewInt(2500000000), ScriptPubKey: bchain.ScriptPubKey{Hex: p2pkh}}}},
+		},
+	}
+	if err := d.storeTxFlags(block); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		txid string
+		want bchain.TxFlags
+	}{
+		{name: "mint", txid: txid1, want: bchain.TxFlagPrivacyMint},
+		{name: "spend", txid: txid2, want: bchain.TxFlagPrivacySpend},
+		{name: "transparent", txid: txid3, want: 0},
+		{name: "unknown", txid: txid4, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.GetTxFlags(tt.txid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("GetTxFlags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRocksDB_indexedBlockBIP30(t *testing.T) {
+	d := &RocksDB{chainParser: btc.NewBitcoinParser(btc.GetChainParams("main"), &btc.Configuration{})}
+	duplicate := "d5d27987d2a3dfc724e359870c6644b40e497bdc0589a033220fe15429d88599"
+	other := strings.Repeat("11", 32)
+	tests := []struct {
+		name   string
+		height uint32
+		txids  []string
+		want   []string
+	}{
+		{name: "duplicate 91842", height: 91842, txids: []string{duplicate, other}, want: []string{other}},
+		{name: "original 91812", height: 91812, txids: []string{duplicate, other}, want: []string{duplicate, other}},
+		{name: "duplicate not coinbase", height: 91842, txids: []string{other, duplicate}, want: []string{other, duplicate}},
+		{name: "after BIP34", height: 227931, txids: []string{duplicate}, want: []string{duplicate}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := &bchain.Block{BlockHeader: bchain.BlockHeader{Height: tt.height}}
+			for _, txid := range tt.txids {
+				block.Txs = append(block.Txs, bchain.Tx{Txid: txid})
+			}
+			ib := d.indexedBlock(block)
+			got := make([]string, len(ib.Txs))
+			for i := range ib.Txs {
+				got[i] = ib.Txs[i].Txid
+			}
+			if !reflect.DeepEqual(got, tt.want) || ib.Height != tt.height {
+				t.Errorf("indexedBlock() = %v at %v, want %v", got, ib.Height, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddressClusters(t *testing.T) {
+	d := &RocksDB{chainParser: newTestZcoinParser(t)}
+	ad := func(b string) bchain.AddressDescriptor {
+		a, _ := hex.DecodeString("76a914" + strings.Repeat(b, 20) + "88ac")
+		return a
+	}
+	inputs := func(ads ...bchain.AddressDescriptor) *TxAddresses {
+		ta := &TxAddresses{}
+		for _, a := range ads {
+			ta.Inputs = append(ta.Inputs, TxInput{AddrDesc: a})
+		}
+		return ta
+	}
+	a, b, c, e, f, g, h := ad("0a"), ad("0b"), ad("0c"), ad("0e"), ad("0f"), ad("01"), ad("02")
+	spend := bchain.AddressDescriptor{xzc.OpSigmaSpend}
+	// the links stored in db by a previous computation, h was clustered under g
+	stored := map[string]string{string(h): string(g)}
+	clusters := newAddressClusters(func(addrDesc string) (string, error) { return stored[addrDesc], nil })
+	txs := []struct {
+		name string
+		ta   *TxAddresses
+		want bool
+	}{
+		{name: "c+b", ta: inputs(c, b, b), want: true},
+		{name: "a+b", ta: inputs(a, b), want: true},
+		{name: "single address", ta: inputs(e, e), want: false},
+		{name: "privacy spend", ta: inputs(e, spend, f), want: false},
+		{name: "coinbase", ta: inputs(nil), want: false},
+		{name: "unknown input", ta: inputs(e, nil), want: false},
+		{name: "f+h", ta: inputs(f, h), want: true},
+	}
+	for _, tt := range txs {
+		got, err := clusters.addTx(d, tt.ta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("addTx(%v) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	wantRoots := []struct {
+		addrDesc bchain.AddressDescriptor
+		root     bchain.AddressDescriptor
+	}{
+		{addrDesc: a, root: a},
+		{addrDesc: b, root: a},
+		{addrDesc: c, root: a},
+		{addrDesc: e, root: e},
+		{addrDesc: f, root: g},
+		{addrDesc: g, root: g},
+		{addrDesc: h, root: g},
+	}
+	for _, w := range wantRoots {
+		root, err := clusters.find(string(w.addrDesc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if root != string(w.root) {
+			t.Errorf("find(%x) = %x, want %x", w.addrDesc, root, w.root)
+		}
+	}
+	// only the changed links are written to db, the roots have no links
+	updated := make([]string, 0, len(clusters.updated))
+	for u := range clusters.updated {
+		updated = append(updated, u)
+	}
+	sort.Strings(updated)
+	want := []string{string(b), string(c), string(f)}
+	sort.Strings(want)
+	if !reflect.DeepEqual(updated, want) {
+		t.Errorf("updated = %x, want %x", updated, want)
+	}
+}
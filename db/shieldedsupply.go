@@ -0,0 +1,97 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"math/big"
+
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// ShieldedSupply contains cumulative values moved to and from the shielded pool up to the Height
+type ShieldedSupply struct {
+	Height uint32
+	Minted big.Int
+	Spent  big.Int
+}
+
+// Supply returns the net shielded supply, i.e. minted minus spent value
+func (s *ShieldedSupply) Supply() *big.Int {
+	var r big.Int
+	return r.Sub(&s.Minted, &s.Spent)
+}
+
+func packShieldedSupply(s *ShieldedSupply) []byte {
+	buf := make([]byte, 2*maxPackedBigintBytes)
+	l := packBigint(&s.Minted, buf)
+	l += packBigint(&s.Spent, buf[l:])
+	return buf[:l]
+}
+
+func unpackShieldedSupply(key, buf []byte) (*ShieldedSupply, error) {
+	if len(key) != packedHeightBytes || len(buf) < 2 {
+		return nil, errors.New("Invalid shielded supply data")
+	}
+	s := ShieldedSupply{Height: unpackUint(key)}
+	var l int
+	s.Minted, l = unpackBigint(buf)
+	if len(buf) <= l {
+		return nil, errors.New("Invalid shielded supply data")
+	}
+	s.Spent, _ = unpackBigint(buf[l:])
+	return &s, nil
+}
+
+// GetShieldedSupply returns cumulative shielded supply up to the given height
+// the values are stored only for blocks with privacy operations, zero supply is returned for heights before any privacy activity
+func (d *RocksDB) GetShieldedSupply(height uint32) (*ShieldedSupply, error) {
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfShieldedSupply])
+	defer it.Close()
+	// find the last record with height lower or equal to the requested height
+	it.Seek(packUint(height + 1))
+	if it.Valid() {
+		it.Prev()
+	} else {
+		it.SeekToLast()
+	}
+	if !it.Valid() {
+		return &ShieldedSupply{}, nil
+	}
+	return unpackShieldedSupply(it.Key().Data(), it.Value().Data())
+}
+
+// writeShieldedSupply adds the shielded flows of the block to the running total of the previous blocks
+// the record is written only if the block contains privacy operations
+func (d *RocksDB) writeShieldedSupply(wb *gorocksdb.WriteBatch, block *bchain.Block) error {
+	minted, spent := big.NewInt(0), big.NewInt(0)
+	for i := range block.Txs {
+		m, s := d.chainParser.GetShieldedFlows(&block.Txs[i])
+		minted.Add(minted, m)
+		spent.Add(spent, s)
+	}
+	if minted.Sign() == 0 && spent.Sign() == 0 {
+		return nil
+	}
+	if block.Height == 0 {
+		return errors.New("Shielded flows in the genesis block")
+	}
+	s, err := d.GetShieldedSupply(block.Height - 1)
+	if err != nil {
+		return err
+	}
+	s.Height = block.Height
+	s.Minted.Add(&s.Minted, minted)
+	s.Spent.Add(&s.Spent, spent)
+	wb.PutCF(d.cfh[cfShieldedSupply], packUint(block.Height), packShieldedSupply(s))
+	return nil
+}
+
+// storeShieldedSupply writes the shielded supply of the block directly to db
+func (d *RocksDB) storeShieldedSupply(block *bchain.Block) error {
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	if err := d.writeShieldedSupply(wb, block); err != nil {
+		return err
+	}
+	return d.db.Write(d.wo, wb)
+}
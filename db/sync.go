@@ -18,6 +18,7 @@ type SyncWorker struct {
 	chain                  bchain.BlockChain
 	syncWorkers, syncChunk int
 	dryRun                 bool
+	storeOrphans           bool
 	startHeight            uint32
 	startHash              string
 	chanOsSignal           chan os.Signal
@@ -26,7 +27,8 @@ type SyncWorker struct {
 }
 
 // NewSyncWorker creates new SyncWorker and returns its handle
-func NewSyncWorker(db *RocksDB, chain bchain.BlockChain, syncWorkers, syncChunk int, minStartHeight int, dryRun bool, chanOsSignal chan os.Signal, metrics *common.Metrics, is *common.InternalState) (*SyncWorker, error) {
+// if storeOrphans is set, blocks disconnected from the main chain during fork handling are stored in the orphans column
+func NewSyncWorker(db *RocksDB, chain bchain.BlockChain, syncWorkers, syncChunk int, minStartHeight int, dryRun bool, storeOrphans bool, chanOsSignal chan os.Signal, metrics *common.Metrics, is *common.InternalState) (*SyncWorker, error) {
 	if minStartHeight < 0 {
 		minStartHeight = 0
 	}
@@ -36,6 +38,7 @@ func NewSyncWorker(db *RocksDB, chain bchain.BlockChain, syncWorkers, syncChunk
 		syncWorkers:  syncWorkers,
 		syncChunk:    syncChunk,
 		dryRun:       dryRun,
+		storeOrphans: storeOrphans,
 		startHeight:  uint32(minStartHeight),
 		chanOsSignal: chanOsSignal,
 		metrics:      metrics,
@@ -166,12 +169,46 @@ func (w *SyncWorker) handleFork(localBestHeight uint32, localBestHash string, on
 		}
 		hashes = append(hashes, local)
 	}
+	if w.storeOrphans {
+		w.storeOrphanBlocks(localBestHeight, hashes)
+	}
 	if err := w.DisconnectBlocks(height+1, localBestHeight, hashes); err != nil {
 		return err
 	}
 	return w.resyncIndex(onNewBlock, initialSync)
 }
 
+// storeOrphanBlocks stores info about the blocks that are going to be disconnected, hashes are ordered from the highest block
+// the errors are only logged, storing of orphans must not affect the indexing of the main chain
+func (w *SyncWorker) storeOrphanBlocks(highestHeight uint32, hashes []string) {
+	disconnectTime := time.Now().Unix()
+	for i, hash := range hashes {
+		ob := OrphanBlock{
+			Hash:           hash,
+			Height:         highestHeight - uint32(i),
+			DisconnectTime: disconnectTime,
+		}
+		bi, err := w.db.GetBlockInfo(ob.Height)
+		if err != nil {
+			glog.Error("sync: orphan block ", ob.Height, " ", hash, ", GetBlockInfo error ", err)
+		} else if bi != nil {
+			ob.Time = bi.Time
+			ob.Txs = bi.Txs
+			ob.Size = bi.Size
+		}
+		// the backend usually keeps stale blocks, try to get the staker from it
+		block, err := w.chain.GetBlock(hash, 0)
+		if err != nil {
+			glog.Warning("sync: orphan block ", ob.Height, " ", hash, ", GetBlock error ", err)
+		} else {
+			ob.Staker = GetCoinstakeStaker(w.chain.GetChainParser(), block)
+		}
+		if err = w.db.StoreOrphanBlock(&ob); err != nil {
+			glog.Error("sync: orphan block ", ob.Height, " ", hash, ", StoreOrphanBlock error ", err)
+		}
+	}
+}
+
 func (w *SyncWorker) connectBlocks(onNewBlock bchain.OnNewBlockFunc, initialSync bool) error {
 	bch := make(chan blockResult, 8)
 	done := make(chan struct{})
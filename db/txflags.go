@@ -0,0 +1,58 @@
+package db
+
+import (
+	"blockbook/bchain"
+
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// writeTxFlags stores the privacy flags of the transactions of the block with privacy operations,
+// the transparent transactions are not stored
+func (d *RocksDB) writeTxFlags(wb *gorocksdb.WriteBatch, block *bchain.Block) error {
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		f := d.chainParser.TxPrivacyFlags(tx)
+		if f == 0 {
+			continue
+		}
+		btxID, err := d.chainParser.PackTxid(tx.Txid)
+		if err != nil {
+			return err
+		}
+		wb.PutCF(d.cfh[cfTxFlags], btxID, []byte{byte(f)})
+	}
+	return nil
+}
+
+// storeTxFlags writes the privacy flags of the transactions of the block directly to db
+func (d *RocksDB) storeTxFlags(block *bchain.Block) error {
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	if err := d.writeTxFlags(wb, block); err != nil {
+		return err
+	}
+	return d.db.Write(d.wo, wb)
+}
+
+// GetTxFlags returns the privacy flags of the confirmed transaction, 0 for transparent transactions
+// and for transactions which are not in db
+func (d *RocksDB) GetTxFlags(txid string) (bchain.TxFlags, error) {
+	btxID, err := d.chainParser.PackTxid(txid)
+	if err != nil {
+		return 0, err
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cfTxFlags], btxID)
+	if err != nil {
+		return 0, err
+	}
+	defer val.Free()
+	buf := val.Data()
+	switch len(buf) {
+	case 0:
+		return 0, nil
+	case 1:
+		return bchain.TxFlags(buf[0]), nil
+	}
+	return 0, errors.Errorf("Invalid tx flags of %v", txid)
+}
@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"io/ioutil"
 	"math/big"
 	"net/http"
@@ -28,6 +29,7 @@ const txsOnPage = 25
 const blocksOnPage = 50
 const mempoolTxsOnPage = 50
 const txsInAPI = 1000
+const txInputsInAPI = 100
 
 const (
 	_ = iota
@@ -190,6 +192,18 @@ func (s *PublicServer) ConnectFullPublicInterface() {
 	serveMux.HandleFunc(path+"api/v2/balancehistory/", s.jsonHandler(s.apiBalanceHistory, apiDefault))
 	serveMux.HandleFunc(path+"api/v2/tickers/", s.jsonHandler(s.apiTickers, apiV2))
 	serveMux.HandleFunc(path+"api/v2/tickers-list/", s.jsonHandler(s.apiTickersList, apiV2))
+	serveMux.HandleFunc(path+"api/v2/orphans/", s.jsonHandler(s.apiOrphans, apiV2))
+	serveMux.HandleFunc(path+"api/v2/shielded-supply/", s.jsonHandler(s.apiShieldedSupply, apiV2))
+	serveMux.HandleFunc(path+"api/v2/mints/", s.jsonHandler(s.apiMints, apiV2))
+	serveMux.HandleFunc(path+"api/v2/tx-flags/", s.jsonHandler(s.apiTxFlags, apiV2))
+	serveMux.HandleFunc(path+"api/v2/address-cluster/", s.jsonHandler(s.apiAddressCluster, apiV2))
+	serveMux.HandleFunc(path+"api/v2/serial/", s.jsonHandler(s.apiPrivacySerial, apiV2))
+	serveMux.HandleFunc(path+"api/v2/mempool/privacy", s.jsonHandler(s.apiMempoolPrivacy, apiV2))
+	serveMux.HandleFunc(path+"api/v2/block-privacy/", s.jsonHandler(s.apiBlockPrivacy, apiV2))
+	// the parsing of user supplied blocks is available only in debug mode
+	if s.debug {
+		serveMux.HandleFunc(path+"api/v2/debug/parse-block", s.jsonHandler(s.apiDebugParseBlock, apiV2))
+	}
 	// socket.io interface
 	serveMux.Handle(path+"socket.io/", s.socketio.GetHandler())
 	// websocket interface
@@ -644,12 +658,14 @@ func (s *PublicServer) getAddressQueryParams(r *http.Request, accountDetails api
 		gap = 0
 	}
 	contract := r.URL.Query().Get("contract")
+	mints, _ := strconv.ParseBool(r.URL.Query().Get("mints"))
 	return page, pageSize, accountDetails, &api.AddressFilter{
 		Vout:           voutFilter,
 		TokensToReturn: tokensToReturn,
 		FromHeight:     uint32(from),
 		ToHeight:       uint32(to),
 		Contract:       contract,
+		Mints:          mints,
 	}, filterParam, gap
 }
 
@@ -952,10 +968,18 @@ func (s *PublicServer) apiTx(r *http.Request, apiVersion int) (interface{}, erro
 		}
 	}
 	tx, err = s.api.GetTransaction(txid, spendingTxs, false)
-	if err == nil && apiVersion == apiV1 {
+	if err != nil {
+		return nil, err
+	}
+	vinPage, ec := strconv.Atoi(r.URL.Query().Get("vinPage"))
+	if ec != nil {
+		vinPage = 0
+	}
+	api.PageTxInputs(tx, vinPage, txInputsInAPI)
+	if apiVersion == apiV1 {
 		return s.api.TxToV1(tx), nil
 	}
-	return tx, err
+	return tx, nil
 }
 
 func (s *PublicServer) apiTxSpecific(r *http.Request, apiVersion int) (interface{}, error) {
@@ -1113,11 +1137,116 @@ func (s *PublicServer) apiFeeStats(r *http.Request, apiVersion int) (interface{}
 	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-feestats"}).Inc()
 	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
-		feeStats, err = s.api.GetFeeStats(r.URL.Path[i+1:])
+		feeStats, err = s.api.GetFeeStats(r.URL.Path[i+1:], r.URL.Query().Get("privacy") == "include")
 	}
 	return feeStats, err
 }
 
+func (s *PublicServer) apiShieldedSupply(r *http.Request, apiVersion int) (interface{}, error) {
+	var supply *api.ShieldedSupply
+	var err error
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-shielded-supply"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		supply, err = s.api.GetShieldedSupply(r.URL.Path[i+1:])
+	}
+	return supply, err
+}
+
+func (s *PublicServer) apiMints(r *http.Request, apiVersion int) (interface{}, error) {
+	var mints *api.Mints
+	var err error
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-mints"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		page, ec := strconv.Atoi(r.URL.Query().Get("page"))
+		if ec != nil {
+			page = 0
+		}
+		pageSize, ec := strconv.Atoi(r.URL.Query().Get("pageSize"))
+		if ec != nil || pageSize <= 0 || pageSize > txsInAPI {
+			pageSize = txsInAPI
+		}
+		from, ec := strconv.ParseUint(r.URL.Query().Get("from"), 10, 32)
+		if ec != nil {
+			from = 0
+		}
+		to, ec := strconv.ParseUint(r.URL.Query().Get("to"), 10, 32)
+		if ec != nil {
+			to = 0
+		}
+		mints, err = s.api.GetMints(r.URL.Path[i+1:], uint32(from), uint32(to), page, pageSize)
+	}
+	return mints, err
+}
+
+func (s *PublicServer) apiTxFlags(r *http.Request, apiVersion int) (interface{}, error) {
+	var flags *api.TxFlags
+	var err error
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-tx-flags"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		flags, err = s.api.GetTxFlags(r.URL.Path[i+1:])
+	}
+	return flags, err
+}
+
+func (s *PublicServer) apiAddressCluster(r *http.Request, apiVersion int) (interface{}, error) {
+	var cluster *api.AddressCluster
+	var err error
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-address-cluster"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		cluster, err = s.api.GetAddressCluster(r.URL.Path[i+1:])
+	}
+	return cluster, err
+}
+
+func (s *PublicServer) apiPrivacySerial(r *http.Request, apiVersion int) (interface{}, error) {
+	var serial *api.PrivacySerial
+	var err error
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-serial"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		serial, err = s.api.GetPrivacySerial(r.URL.Path[i+1:])
+	}
+	return serial, err
+}
+
+func (s *PublicServer) apiMempoolPrivacy(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-mempool-privacy"}).Inc()
+	return s.api.GetMempoolPrivacy(), nil
+}
+
+func (s *PublicServer) apiBlockPrivacy(r *http.Request, apiVersion int) (interface{}, error) {
+	var privacy *api.BlockPrivacy
+	var err error
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-block-privacy"}).Inc()
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		privacy, err = s.api.GetBlockPrivacy(r.URL.Path[i+1:])
+	}
+	return privacy, err
+}
+
+// maxDebugBlockHexSize is the maximum size of the hex encoded block accepted by the debug parse-block endpoint,
+// it corresponds to the default maximum block size of the bitcoin type parsers (32MB)
+const maxDebugBlockHexSize = 64 * 1024 * 1024
+
+func (s *PublicServer) apiDebugParseBlock(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-debug-parse-block"}).Inc()
+	if r.Method != http.MethodPost {
+		return nil, api.NewAPIError("Block hex must be sent by POST", true)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, maxDebugBlockHexSize+1))
+	if err != nil {
+		return nil, api.NewAPIError("Missing block hex", true)
+	}
+	if len(data) > maxDebugBlockHexSize {
+		return nil, api.NewAPIError("Block hex too large", true)
+	}
+	return s.api.ParseRawBlock(string(data))
+}
+
+func (s *PublicServer) apiOrphans(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-orphans"}).Inc()
+	return s.api.GetOrphanStats(txsInAPI)
+}
+
 type resultSendTransaction struct {
 	Result string `json:"result"`
 }
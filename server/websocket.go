@@ -68,7 +68,7 @@ type WebsocketServer struct {
 	is                         *common.InternalState
 	api                        *api.Worker
 	block0hash                 string
-	newBlockSubscriptions      map[*websocketChannel]string
+	newBlockSubscriptions      map[*websocketChannel]newBlockSubscription
 	newBlockSubscriptionsLock  sync.Mutex
 	addressSubscriptions       map[string]map[*websocketChannel]string
 	addressSubscriptionsLock   sync.Mutex
@@ -101,7 +101,7 @@ func NewWebsocketServer(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.
 		is:                     is,
 		api:                    api,
 		block0hash:             b0,
-		newBlockSubscriptions:  make(map[*websocketChannel]string),
+		newBlockSubscriptions:  make(map[*websocketChannel]newBlockSubscription),
 		addressSubscriptions:   make(map[string]map[*websocketChannel]string),
 		fiatRatesSubscriptions: make(map[string]map[*websocketChannel]string),
 	}
@@ -630,10 +630,25 @@ type subscriptionResponse struct {
 	Subscribed bool `json:"subscribed"`
 }
 
+// newBlockSubscription contains the id of the subscription request and the option
+// to include the privacy summary of the block in the notifications
+type newBlockSubscription struct {
+	id      string
+	privacy bool
+}
+
 func (s *WebsocketServer) subscribeNewBlock(c *websocketChannel, req *websocketReq) (res interface{}, err error) {
+	r := struct {
+		Privacy bool `json:"privacy"`
+	}{}
+	if len(req.Params) > 0 {
+		if err = json.Unmarshal(req.Params, &r); err != nil {
+			return nil, err
+		}
+	}
 	s.newBlockSubscriptionsLock.Lock()
 	defer s.newBlockSubscriptionsLock.Unlock()
-	s.newBlockSubscriptions[c] = req.ID
+	s.newBlockSubscriptions[c] = newBlockSubscription{id: req.ID, privacy: r.Privacy}
 	return &subscriptionResponse{true}, nil
 }
 
@@ -733,22 +748,49 @@ func (s *WebsocketServer) unsubscribeFiatRates(c *websocketChannel) (res interfa
 	return &subscriptionResponse{false}, nil
 }
 
+type newBlockNotification struct {
+	Height  uint32            `json:"height"`
+	Hash    string            `json:"hash"`
+	Privacy *api.BlockPrivacy `json:"privacy,omitempty"`
+}
+
 // OnNewBlock is a callback that broadcasts info about new block to subscribed clients
+// the privacy summary of the block is computed only if some client subscribed for it
 func (s *WebsocketServer) OnNewBlock(hash string, height uint32) {
+	// check if there is any privacy subscription but release the lock immediately, GetBlockPrivacy may take some time
 	s.newBlockSubscriptionsLock.Lock()
-	defer s.newBlockSubscriptionsLock.Unlock()
-	data := struct {
-		Height uint32 `json:"height"`
-		Hash   string `json:"hash"`
-	}{
+	withPrivacy := false
+	for _, sub := range s.newBlockSubscriptions {
+		if sub.privacy {
+			withPrivacy = true
+			break
+		}
+	}
+	s.newBlockSubscriptionsLock.Unlock()
+	data := newBlockNotification{
 		Height: height,
 		Hash:   hash,
 	}
-	for c, id := range s.newBlockSubscriptions {
+	dataWithPrivacy := data
+	if withPrivacy {
+		privacy, err := s.api.GetBlockPrivacy(hash)
+		if err != nil {
+			glog.Error("GetBlockPrivacy error ", err, " for ", height, " ", hash)
+		} else {
+			dataWithPrivacy.Privacy = privacy
+		}
+	}
+	s.newBlockSubscriptionsLock.Lock()
+	defer s.newBlockSubscriptionsLock.Unlock()
+	for c, sub := range s.newBlockSubscriptions {
 		if c.IsAlive() {
+			d := &data
+			if sub.privacy {
+				d = &dataWithPrivacy
+			}
 			c.out <- &websocketRes{
-				ID:   id,
-				Data: &data,
+				ID:   sub.id,
+				Data: d,
 			}
 		}
 	}
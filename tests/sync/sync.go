@@ -189,7 +189,7 @@ func withRocksDBAndSyncWorker(t *testing.T, h *TestHandler, startHeight uint32,
 
 	ch := make(chan os.Signal)
 
-	sw, err := db.NewSyncWorker(d, h.Chain, 8, 0, int(startHeight), false, ch, m, is)
+	sw, err := db.NewSyncWorker(d, h.Chain, 8, 0, int(startHeight), false, false, ch, m, is)
 	if err != nil {
 		t.Fatal(err)
 	}